@@ -2,6 +2,7 @@ package assets
 
 import (
 	"embed"
+	"io/fs"
 )
 
 //go:embed *
@@ -11,3 +12,8 @@ var f embed.FS
 func ReadFile(name string) ([]byte, error) {
 	return f.ReadFile(name)
 }
+
+// FS returns the embedded assets.
+func FS() fs.FS {
+	return f
+}
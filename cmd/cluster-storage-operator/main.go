@@ -16,6 +16,7 @@ import (
 
 var (
 	guestKubeConfig *string
+	dryRun          *bool
 )
 
 func main() {
@@ -34,20 +35,112 @@ func NewOperatorCommand() *cobra.Command {
 		},
 	}
 
-	ctrlCmd := controllercmd.NewControllerCommandConfig(
+	ctrlCmdConfig := controllercmd.NewControllerCommandConfig(
 		"cluster-storage-operator",
 		version.Get(),
 		runOperatorWithGuestKubeconfig,
-	).NewCommand()
+	)
+	ctrlCmd := ctrlCmdConfig.NewCommand()
 	ctrlCmd.Use = "start"
 	ctrlCmd.Short = "Start the Cluster Storage Operator"
+	ctrlCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		// Do not take the lease from the operator that applies the changes.
+		ctrlCmdConfig.DisableLeaderElection = *dryRun
+	}
 	guestKubeConfig = ctrlCmd.Flags().String("guest-kubeconfig", "", "Path to guest kubeconfig file. This flag enables hypershift integration")
+	dryRun = ctrlCmd.Flags().Bool("dry-run", false, "Send all changes to the API server in dry-run mode and report them in events and in ConfigMap cluster-storage-operator-dry-run instead of applying them")
 
 	cmd.AddCommand(ctrlCmd)
+	cmd.AddCommand(NewRenderCommand())
+	cmd.AddCommand(NewExplainDriversCommand())
+	cmd.AddCommand(NewDiagnoseCommand())
+	cmd.AddCommand(NewVerifyAssetsCommand())
 
 	return cmd
 }
 
+func NewRenderCommand() *cobra.Command {
+	opts := operator.RenderOptions{}
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render objects the Cluster Storage Operator would create, without an API server",
+		Long: `Render objects the Cluster Storage Operator would create, without an API server.
+
+Rendered are CSI driver operators with their objects, vsphere-problem-detector,
+NetworkPolicies, the PrometheusRule with storage alerts and the default StorageClass
+from default-storage-class-templates ConfigMap. Alert overrides and StorageClass
+templates are read from --config-maps.
+
+Not rendered are:
+- VolumeSnapshotClasses, CSI driver operators create them.
+- Changes that depend on objects in a live cluster: default StorageClass and
+  VolumeSnapshotClass annotations, pod security labels of namespaces and removal
+  of objects of stopped CSI driver operators.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return operator.RunRender(opts, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&opts.InfrastructureFile, "infrastructure", "", "Path to YAML file with Infrastructure \"cluster\" (required)")
+	cmd.Flags().StringVar(&opts.FeatureGateFile, "feature-gate", "", "Path to YAML file with FeatureGate \"cluster\" with populated status. All feature gates are disabled when not set")
+	cmd.Flags().StringVar(&opts.ReleaseVersion, "release-version", "", "Release version to select from FeatureGate status. Optional when the status has a single version")
+	cmd.Flags().StringVar(&opts.StorageFile, "storage", "", "Path to YAML file with Storage \"cluster\". A Managed Storage CR is used when not set")
+	cmd.Flags().StringVar(&opts.ImagesEnvFile, "images-env", "", "Path to file with KEY=VALUE lines with image env. vars of the operator Deployment")
+	cmd.Flags().StringVar(&opts.ConfigMapsFile, "config-maps", "", "Path to YAML file with a ConfigMap or a List of ConfigMaps in the operator namespace")
+	cmd.Flags().BoolVar(&opts.HyperShift, "hypershift", false, "Render objects as in a HyperShift hosted cluster")
+	cmd.Flags().StringVar(&opts.ControlNamespace, "control-plane-namespace", "clusters-hosted", "HyperShift control plane namespace")
+	cmd.Flags().StringVar(&opts.OutputDir, "output-dir", "", "Directory where rendered objects are written (required)")
+	cmd.MarkFlagRequired("infrastructure")
+	cmd.MarkFlagRequired("output-dir")
+	return cmd
+}
+
+func NewExplainDriversCommand() *cobra.Command {
+	opts := operator.ExplainOptions{}
+	cmd := &cobra.Command{
+		Use:   "explain-drivers",
+		Short: "Explain which CSI driver operators the Cluster Storage Operator would run and why",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return operator.RunExplainDrivers(cmd.Context(), opts, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "Path to kubeconfig of a cluster to read objects from. In HyperShift, kubeconfig of the guest cluster")
+	cmd.Flags().StringVar(&opts.InfrastructureFile, "infrastructure", "", "Path to YAML file with Infrastructure \"cluster\" (required without --kubeconfig)")
+	cmd.Flags().StringVar(&opts.FeatureGateFile, "feature-gate", "", "Path to YAML file with FeatureGate \"cluster\" with populated status. All feature gates are disabled when not set")
+	cmd.Flags().StringVar(&opts.ReleaseVersion, "release-version", "", "Release version to select from FeatureGate status. Defaults to the desired version of a live cluster")
+	cmd.Flags().StringVar(&opts.CSIDriversFile, "csi-drivers", "", "Path to YAML file with a list of CSIDrivers")
+	cmd.Flags().StringVar(&opts.ClusterCSIDriversFile, "cluster-csi-drivers", "", "Path to YAML file with a list of ClusterCSIDrivers")
+	cmd.Flags().BoolVar(&opts.HyperShift, "hypershift", false, "Explain CSI driver operators of a HyperShift hosted cluster")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", operator.ExplainOutputText, "Output format, text or json")
+	cmd.MarkFlagsMutuallyExclusive("kubeconfig", "infrastructure")
+	return cmd
+}
+
+func NewDiagnoseCommand() *cobra.Command {
+	opts := operator.DiagnoseOptions{}
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Gather storage diagnostic data of a cluster into a tarball",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return operator.RunDiagnose(cmd.Context(), opts, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "Path to kubeconfig of the cluster. In-cluster config is used when not set. In HyperShift, kubeconfig of the guest cluster")
+	cmd.Flags().StringVar(&opts.OutputFile, "output-file", "", "Path to the gzipped tarball to write. Defaults to storage-diagnostics-<timestamp>.tar.gz")
+	cmd.Flags().Int64Var(&opts.LogLines, "log-lines", 1000, "Number of the most recent log lines to gather from each container, 0 for all")
+	cmd.Flags().BoolVar(&opts.HyperShift, "hypershift", false, "Gather CSI driver operators of a HyperShift hosted cluster")
+	return cmd
+}
+
+func NewVerifyAssetsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-assets",
+		Short: "Verify that all assets referenced by the Cluster Storage Operator exist and are consistent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return operator.RunVerifyAssets(cmd.OutOrStdout())
+		},
+	}
+}
+
 func runOperatorWithGuestKubeconfig(ctx context.Context, controllerConfig *controllercmd.ControllerContext) error {
-	return operator.RunOperator(ctx, controllerConfig, guestKubeConfig)
+	return operator.RunOperator(ctx, controllerConfig, guestKubeConfig, *dryRun)
 }
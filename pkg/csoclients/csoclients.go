@@ -15,6 +15,7 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/transport"
 
 	cfgclientset "github.com/openshift/client-go/config/clientset/versioned"
 	cfginformers "github.com/openshift/client-go/config/informers/externalversions"
@@ -176,16 +177,22 @@ func NewHypershiftMgmtClients(controllerConfig *controllercmd.ControllerContext,
 	return c, nil
 }
 
+// NewHypershiftGuestClients creates clients of the guest cluster. When not nil,
+// wrapTransport wraps transports of all the clients.
 func NewHypershiftGuestClients(
 	controllerConfig *controllercmd.ControllerContext,
 	guestKubeConfig string,
-	controllerName string, resync time.Duration) (*Clients, error) {
+	controllerName string, resync time.Duration,
+	wrapTransport transport.WrapperFunc) (*Clients, error) {
 	c := &Clients{}
 	var err error
 	kubeRestConfig, err := client.GetKubeConfigOrInClusterConfig(guestKubeConfig, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to use guest kubeconfig %s: %s", guestKubeConfig, err)
 	}
+	if wrapTransport != nil {
+		kubeRestConfig.Wrap(wrapTransport)
+	}
 	// TODO set user agent name here
 	guestKubeClient := kubernetes.NewForConfigOrDie(rest.AddUserAgent(kubeRestConfig, controllerName))
 	// Kubernetes client, used to manipulate StorageClasses
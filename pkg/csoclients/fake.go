@@ -14,6 +14,7 @@ import (
 	apiextinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/dynamic/fake"
@@ -25,6 +26,11 @@ type FakeTestObjects struct {
 	CoreObjects, ExtensionObjects, OperatorObjects, ConfigObjects, DynamicObjects, MonitoringObjects []runtime.Object
 }
 
+// List kinds of resources listed through the fake dynamic client.
+var dynamicListKinds = map[schema.GroupVersionResource]string{
+	{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotclasses"}: "VolumeSnapshotClassList",
+}
+
 func WaitForSync(clients *Clients, stopCh <-chan struct{}) {
 	clients.OperatorInformers.WaitForCacheSync(stopCh)
 	clients.ExtensionInformer.WaitForCacheSync(stopCh)
@@ -67,7 +73,7 @@ func NewFakeClients(initialObjects *FakeTestObjects) *Clients {
 	monitoringInformer := prominformer.NewSharedInformerFactory(monitoringClient, 0)
 
 	scheme := runtime.NewScheme()
-	dynamicClient := fake.NewSimpleDynamicClient(scheme)
+	dynamicClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, dynamicListKinds, initialObjects.DynamicObjects...)
 	dynamicInformer := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
 
 	categoryExpander := restmapper.NewDiscoveryCategoryExpander(kubeClient.Discovery())
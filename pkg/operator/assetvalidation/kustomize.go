@@ -0,0 +1,231 @@
+package assetvalidation
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kustomizationFile = "kustomization.yaml"
+	// generatedDir is a directory next to kustomization.yaml with output of
+	// "oc kustomize", see hack/generate-manifests.sh.
+	generatedDir = "generated"
+)
+
+// kustomizeClusterScopedKinds are cluster scoped kinds known to kustomize.
+// Kustomize sets namespace of all other objects, including cluster scoped
+// custom resources.
+var kustomizeClusterScopedKinds = sets.New[schema.GroupKind](
+	schema.GroupKind{Kind: "Namespace"},
+	schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+	schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+	schema.GroupKind{Group: "storage.k8s.io", Kind: "CSIDriver"},
+	schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"},
+)
+
+// kustomization is the subset of kustomization.yaml used in the assets.
+type kustomization struct {
+	Resources []string `json:"resources"`
+	Namespace string   `json:"namespace"`
+	Patches   []struct {
+		Path   string `json:"path"`
+		Patch  string `json:"patch"`
+		Target struct {
+			AnnotationSelector string `json:"annotationSelector"`
+		} `json:"target"`
+	} `json:"patches"`
+}
+
+func readKustomization(fsys fs.FS, dir string) (*kustomization, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, kustomizationFile))
+	if err != nil {
+		return nil, err
+	}
+	k := &kustomization{}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path.Join(dir, kustomizationFile), err)
+	}
+	return k, nil
+}
+
+// findKustomizations returns all directories with kustomization.yaml.
+func findKustomizations(fsys fs.FS) ([]string, error) {
+	var dirs []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path.Base(name) == kustomizationFile {
+			dirs = append(dirs, path.Dir(name))
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// files returns files used by the kustomization, i.e. resource files and
+// patches. Resource directories have their own kustomization.yaml.
+func (k *kustomization) files(dir string) []string {
+	var files []string
+	for _, r := range k.Resources {
+		if isManifest(r) {
+			files = append(files, path.Join(dir, r))
+		}
+	}
+	for _, p := range k.Patches {
+		if p.Path != "" {
+			files = append(files, path.Join(dir, p.Path))
+		}
+	}
+	return files
+}
+
+// build returns objects that "oc kustomize" produces from the kustomization,
+// with their namespace and without deleted objects. Other patches are not
+// applied, they do not change names nor namespaces in the assets.
+func (k *kustomization) build(fsys fs.FS, dir string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, r := range k.Resources {
+		resource := path.Join(dir, r)
+		info, err := fs.Stat(fsys, resource)
+		if err != nil {
+			return nil, fmt.Errorf("%s: resource %s: %w", path.Join(dir, kustomizationFile), r, err)
+		}
+		var resourceObjs []*unstructured.Unstructured
+		if info.IsDir() {
+			sub, err := readKustomization(fsys, resource)
+			if err != nil {
+				return nil, err
+			}
+			resourceObjs, err = sub.build(fsys, resource)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			resourceObjs, err = readObjects(fsys, resource)
+			if err != nil {
+				return nil, err
+			}
+		}
+		objs = append(objs, resourceObjs...)
+	}
+
+	if k.Namespace != "" {
+		for _, obj := range objs {
+			if !kustomizeClusterScopedKinds.Has(obj.GroupVersionKind().GroupKind()) {
+				obj.SetNamespace(k.Namespace)
+			}
+		}
+	}
+
+	for _, p := range k.Patches {
+		if p.Target.AnnotationSelector == "" {
+			continue
+		}
+		patch := p.Patch
+		if p.Path != "" {
+			data, err := fs.ReadFile(fsys, path.Join(dir, p.Path))
+			if err != nil {
+				return nil, err
+			}
+			patch = string(data)
+		}
+		if !strings.Contains(patch, "$patch: delete") {
+			continue
+		}
+		selector, err := labels.Parse(p.Target.AnnotationSelector)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid annotationSelector: %w", path.Join(dir, kustomizationFile), err)
+		}
+		var kept []*unstructured.Unstructured
+		for _, obj := range objs {
+			if !selector.Matches(labels.Set(obj.GetAnnotations())) {
+				kept = append(kept, obj)
+			}
+		}
+		objs = kept
+	}
+	return objs, nil
+}
+
+// generatedFileName returns name of the file that "oc kustomize -o <dir>"
+// writes the object to.
+func generatedFileName(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	prefix := gvk.Version
+	if gvk.Group != "" {
+		prefix = gvk.Group + "_" + gvk.Version
+	}
+	return strings.ToLower(prefix+"_"+gvk.Kind) + "_" + obj.GetName() + ".yaml"
+}
+
+// ValidateKustomizations checks that generated output of each kustomization
+// has the same objects in the same namespaces as the kustomization sources.
+// Content of the objects is not compared, it requires running kustomize,
+// see hack/verify-manifest.sh.
+func ValidateKustomizations(fsys fs.FS) []error {
+	dirs, err := findKustomizations(fsys)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, dir := range dirs {
+		generated := path.Join(dir, generatedDir)
+		if _, err := fs.Stat(fsys, generated); err != nil {
+			// Base kustomizations are not generated.
+			continue
+		}
+		k, err := readKustomization(fsys, dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		objs, err := k.build(fsys, dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		expected := map[string]*unstructured.Unstructured{}
+		for _, obj := range objs {
+			expected[generatedFileName(obj)] = obj
+		}
+
+		entries, err := fs.ReadDir(fsys, generated)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		found := sets.New[string]()
+		for _, entry := range entries {
+			name := path.Join(generated, entry.Name())
+			found.Insert(entry.Name())
+			source, ok := expected[entry.Name()]
+			if !ok {
+				errs = append(errs, fmt.Errorf("asset %s is not produced by %s", name, path.Join(dir, kustomizationFile)))
+				continue
+			}
+			obj, err := readObject(fsys, name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if obj.GetNamespace() != source.GetNamespace() {
+				errs = append(errs, fmt.Errorf("asset %s: expected namespace %q from %s, got %q", name, source.GetNamespace(), path.Join(dir, kustomizationFile), obj.GetNamespace()))
+			}
+		}
+		for _, name := range sets.List(sets.KeySet(expected)) {
+			if !found.Has(name) {
+				errs = append(errs, fmt.Errorf("asset %s produced by %s is missing, run \"make update\"", path.Join(generated, name), path.Join(dir, kustomizationFile)))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,219 @@
+package assetvalidation
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// Reference is an asset referenced by CSO code.
+type Reference struct {
+	// Owner is a human readable name of the code that references the asset,
+	// such as "AWSEBS" or "AWSEBS (HyperShift)". Assets of the same owner are
+	// applied together and must be consistent with each other.
+	Owner string
+	// Asset is name of the asset.
+	Asset string
+	// Kind is the expected kind of the asset. Any kind is accepted when empty.
+	Kind schema.GroupVersionKind
+	// Namespace is the expected namespace of a namespaced asset. When empty,
+	// a namespaced asset can be in any namespace, but it must have one.
+	Namespace string
+}
+
+// clusterScopedKinds are kinds of cluster scoped objects used in the assets.
+var clusterScopedKinds = sets.New[schema.GroupKind](
+	schema.GroupKind{Kind: "Namespace"},
+	schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+	schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+	schema.GroupKind{Group: "storage.k8s.io", Kind: "CSIDriver"},
+	schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"},
+	schema.GroupKind{Group: "snapshot.storage.k8s.io", Kind: "VolumeSnapshotClass"},
+	schema.GroupKind{Group: "operator.openshift.io", Kind: "ClusterCSIDriver"},
+)
+
+// Validate runs all checks of the assets and returns all found problems.
+func Validate(fsys fs.FS, refs []Reference) []error {
+	var errs []error
+	errs = append(errs, ValidateReferences(fsys, refs)...)
+	errs = append(errs, ValidateKustomizations(fsys)...)
+	errs = append(errs, ValidateAllReferenced(fsys, refs)...)
+	return errs
+}
+
+// ValidateReferences checks that all referenced assets exist, decode to the
+// expected kind and are in the expected namespace. ServiceAccounts used by
+// Deployments and bindings of an owner must be in the same namespace as the
+// ServiceAccounts in the owner's assets.
+func ValidateReferences(fsys fs.FS, refs []Reference) []error {
+	var errs []error
+	objects := map[string][]*unstructured.Unstructured{}
+	var owners []string
+	for _, ref := range refs {
+		obj, err := readObject(fsys, ref.Asset)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ref.Owner, err))
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if !ref.Kind.Empty() && gvk != ref.Kind {
+			errs = append(errs, fmt.Errorf("%s: asset %s: expected %s, got %s", ref.Owner, ref.Asset, kindString(ref.Kind), kindString(gvk)))
+		}
+		if !clusterScopedKinds.Has(gvk.GroupKind()) {
+			switch {
+			case ref.Namespace != "" && obj.GetNamespace() != ref.Namespace:
+				errs = append(errs, fmt.Errorf("%s: asset %s: expected namespace %q, got %q", ref.Owner, ref.Asset, ref.Namespace, obj.GetNamespace()))
+			case obj.GetNamespace() == "":
+				errs = append(errs, fmt.Errorf("%s: asset %s: namespaced %s has no namespace", ref.Owner, ref.Asset, gvk.Kind))
+			}
+		}
+		if _, found := objects[ref.Owner]; !found {
+			owners = append(owners, ref.Owner)
+		}
+		objects[ref.Owner] = append(objects[ref.Owner], obj)
+	}
+
+	for _, owner := range owners {
+		errs = append(errs, validateServiceAccounts(owner, objects[owner])...)
+	}
+	return errs
+}
+
+// validateServiceAccounts checks that ServiceAccounts referenced by
+// Deployments and bindings exist in the expected namespace, when the owner
+// has a ServiceAccount with the same name.
+func validateServiceAccounts(owner string, objects []*unstructured.Unstructured) []error {
+	namespaces := map[string]sets.Set[string]{}
+	for _, obj := range objects {
+		if obj.GroupVersionKind().GroupKind() == (schema.GroupKind{Kind: "ServiceAccount"}) {
+			if namespaces[obj.GetName()] == nil {
+				namespaces[obj.GetName()] = sets.New[string]()
+			}
+			namespaces[obj.GetName()].Insert(obj.GetNamespace())
+		}
+	}
+	check := func(obj *unstructured.Unstructured, name, namespace string) error {
+		saNamespaces, found := namespaces[name]
+		if !found || saNamespaces.Has(namespace) {
+			return nil
+		}
+		return fmt.Errorf("%s: %s %s uses ServiceAccount %s/%s, but the ServiceAccount is in namespace(s) %s",
+			owner, obj.GetKind(), obj.GetName(), namespace, name, strings.Join(sets.List(saNamespaces), ", "))
+	}
+
+	var errs []error
+	for _, obj := range objects {
+		switch obj.GroupVersionKind().GroupKind() {
+		case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+			name, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "serviceAccountName")
+			if err := check(obj, name, obj.GetNamespace()); err != nil {
+				errs = append(errs, err)
+			}
+		case schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"},
+			schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:
+			subjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+			for _, s := range subjects {
+				subject, ok := s.(map[string]interface{})
+				if !ok || subject["kind"] != "ServiceAccount" {
+					continue
+				}
+				name, _ := subject["name"].(string)
+				namespace, _ := subject["namespace"].(string)
+				if err := check(obj, name, namespace); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// readObject reads an asset with a single object.
+func readObject(fsys fs.FS, name string) (*unstructured.Unstructured, error) {
+	objs, err := readObjects(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) != 1 {
+		return nil, fmt.Errorf("asset %s: expected a single object, got %d", name, len(objs))
+	}
+	return objs[0], nil
+}
+
+// readObjects reads all objects in an asset with one or more YAML documents.
+func readObjects(fsys fs.FS, name string) ([]*unstructured.Unstructured, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s: %w", name, err)
+	}
+	var objs []*unstructured.Unstructured
+	for _, doc := range bytes.Split(data, []byte("\n---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &obj.Object); err != nil {
+			return nil, fmt.Errorf("asset %s: failed to decode: %w", name, err)
+		}
+		if obj.Object == nil {
+			continue
+		}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("asset %s: apiVersion, kind and metadata.name are required", name)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func kindString(gvk schema.GroupVersionKind) string {
+	return gvk.Kind + "." + gvk.GroupVersion().String()
+}
+
+// isManifest returns true for assets that contain Kubernetes objects.
+func isManifest(name string) bool {
+	return path.Ext(name) == ".yaml"
+}
+
+// ValidateAllReferenced checks that each manifest in the assets is either
+// referenced or used by a kustomization.
+func ValidateAllReferenced(fsys fs.FS, refs []Reference) []error {
+	used := sets.New[string]()
+	for _, ref := range refs {
+		used.Insert(ref.Asset)
+	}
+	kustomizations, err := findKustomizations(fsys)
+	if err != nil {
+		return []error{err}
+	}
+	for _, dir := range kustomizations {
+		k, err := readKustomization(fsys, dir)
+		if err != nil {
+			return []error{err}
+		}
+		used.Insert(path.Join(dir, kustomizationFile))
+		used.Insert(k.files(dir)...)
+	}
+
+	var errs []error
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isManifest(name) && !used.Has(name) {
+			errs = append(errs, fmt.Errorf("asset %s is not referenced", name))
+		}
+		return nil
+	})
+	if err != nil {
+		return []error{err}
+	}
+	return errs
+}
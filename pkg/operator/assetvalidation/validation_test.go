@@ -0,0 +1,236 @@
+package assetvalidation
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	testSA = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: test-operator
+  namespace: test
+`
+	testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-operator
+  namespace: test
+spec:
+  template:
+    spec:
+      serviceAccountName: test-operator
+`
+	testClusterRoleBinding = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: test-operator
+subjects:
+- kind: ServiceAccount
+  name: test-operator
+  namespace: other
+`
+	testCR = `apiVersion: operator.openshift.io/v1
+kind: ClusterCSIDriver
+metadata:
+  name: test.csi.openshift.io
+  annotations:
+    storage.openshift.io/remove-from: mgmt
+`
+	testKustomization = `resources:
+  - 01_sa.yaml
+  - 02_deployment.yaml
+  - 03_cr.yaml
+namespace: test
+patches:
+  - path: deployment.patch.yaml
+    target:
+      kind: Deployment
+  - patch: |
+      $patch: delete
+      kind: Kustomization
+      metadata:
+        name: PLACEHOLDER
+    target:
+      annotationSelector: "storage.openshift.io/remove-from=mgmt"
+`
+)
+
+var deploymentKind = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+func TestValidateReferences(t *testing.T) {
+	fsys := fstest.MapFS{
+		"test/01_sa.yaml":                 {Data: []byte(testSA)},
+		"test/02_deployment.yaml":         {Data: []byte(testDeployment)},
+		"test/03_clusterrolebinding.yaml": {Data: []byte(testClusterRoleBinding)},
+		"test/04_no_namespace.yaml":       {Data: []byte(strings.Replace(testSA, "  namespace: test\n", "", 1))},
+		"test/05_invalid.yaml":            {Data: []byte("kind: ServiceAccount\n")},
+	}
+
+	tests := []struct {
+		name           string
+		refs           []Reference
+		expectedErrors []string
+	}{
+		{
+			name: "valid references",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/01_sa.yaml"},
+				{Owner: "Test", Asset: "test/02_deployment.yaml", Kind: deploymentKind, Namespace: "test"},
+			},
+		},
+		{
+			name: "missing asset",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/99_typo.yaml"},
+			},
+			expectedErrors: []string{"Test: asset test/99_typo.yaml: open test/99_typo.yaml: file does not exist"},
+		},
+		{
+			name: "wrong kind",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/01_sa.yaml", Kind: deploymentKind},
+			},
+			expectedErrors: []string{"Test: asset test/01_sa.yaml: expected Deployment.apps/v1, got ServiceAccount.v1"},
+		},
+		{
+			name: "wrong namespace",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/02_deployment.yaml", Kind: deploymentKind, Namespace: "${CONTROLPLANE_NAMESPACE}"},
+			},
+			expectedErrors: []string{`Test: asset test/02_deployment.yaml: expected namespace "${CONTROLPLANE_NAMESPACE}", got "test"`},
+		},
+		{
+			name: "missing namespace",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/04_no_namespace.yaml"},
+			},
+			expectedErrors: []string{"Test: asset test/04_no_namespace.yaml: namespaced ServiceAccount has no namespace"},
+		},
+		{
+			name: "invalid asset",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/05_invalid.yaml"},
+			},
+			expectedErrors: []string{"Test: asset test/05_invalid.yaml: apiVersion, kind and metadata.name are required"},
+		},
+		{
+			name: "ServiceAccount in a different namespace",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/01_sa.yaml"},
+				{Owner: "Test", Asset: "test/03_clusterrolebinding.yaml"},
+			},
+			expectedErrors: []string{"Test: ClusterRoleBinding test-operator uses ServiceAccount other/test-operator, but the ServiceAccount is in namespace(s) test"},
+		},
+		{
+			name: "ServiceAccount of a different owner",
+			refs: []Reference{
+				{Owner: "Test", Asset: "test/01_sa.yaml"},
+				{Owner: "Other", Asset: "test/03_clusterrolebinding.yaml"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := ValidateReferences(fsys, test.refs)
+			checkErrors(t, errs, test.expectedErrors)
+		})
+	}
+}
+
+func TestValidateKustomizations(t *testing.T) {
+	sources := func() fstest.MapFS {
+		return fstest.MapFS{
+			"test/base/01_sa.yaml":                                      {Data: []byte(strings.Replace(testSA, "namespace: test", "namespace: default", 1))},
+			"test/base/02_deployment.yaml":                              {Data: []byte(strings.Replace(testDeployment, "namespace: test", "namespace: default", 1))},
+			"test/base/03_cr.yaml":                                      {Data: []byte(testCR)},
+			"test/base/deployment.patch.yaml":                           {Data: []byte("spec:\n  replicas: 2\n")},
+			"test/base/kustomization.yaml":                              {Data: []byte(testKustomization)},
+			"test/mgmt/kustomization.yaml":                              {Data: []byte("resources:\n  - ../base\nnamespace: ${CONTROLPLANE_NAMESPACE}\n")},
+			"test/mgmt/generated/v1_serviceaccount_test-operator.yaml":  {Data: []byte(strings.Replace(testSA, "namespace: test", "namespace: ${CONTROLPLANE_NAMESPACE}", 1))},
+			"test/mgmt/generated/apps_v1_deployment_test-operator.yaml": {Data: []byte(strings.Replace(testDeployment, "namespace: test", "namespace: ${CONTROLPLANE_NAMESPACE}", 1))},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		modify         func(fstest.MapFS)
+		expectedErrors []string
+	}{
+		{
+			name:   "generated output matches",
+			modify: func(fsys fstest.MapFS) {},
+		},
+		{
+			name: "missing generated file",
+			modify: func(fsys fstest.MapFS) {
+				delete(fsys, "test/mgmt/generated/v1_serviceaccount_test-operator.yaml")
+			},
+			expectedErrors: []string{`asset test/mgmt/generated/v1_serviceaccount_test-operator.yaml produced by test/mgmt/kustomization.yaml is missing, run "make update"`},
+		},
+		{
+			name: "stale generated file",
+			modify: func(fsys fstest.MapFS) {
+				fsys["test/mgmt/generated/v1_serviceaccount_removed.yaml"] = &fstest.MapFile{Data: []byte(testSA)}
+			},
+			expectedErrors: []string{"asset test/mgmt/generated/v1_serviceaccount_removed.yaml is not produced by test/mgmt/kustomization.yaml"},
+		},
+		{
+			name: "generated file in a wrong namespace",
+			modify: func(fsys fstest.MapFS) {
+				fsys["test/mgmt/generated/apps_v1_deployment_test-operator.yaml"] = &fstest.MapFile{Data: []byte(testDeployment)}
+			},
+			expectedErrors: []string{`asset test/mgmt/generated/apps_v1_deployment_test-operator.yaml: expected namespace "${CONTROLPLANE_NAMESPACE}" from test/mgmt/kustomization.yaml, got "test"`},
+		},
+		{
+			name: "missing resource",
+			modify: func(fsys fstest.MapFS) {
+				delete(fsys, "test/base/03_cr.yaml")
+			},
+			expectedErrors: []string{"test/base/kustomization.yaml: resource 03_cr.yaml: open test/base/03_cr.yaml: file does not exist"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := sources()
+			test.modify(fsys)
+			errs := ValidateKustomizations(fsys)
+			checkErrors(t, errs, test.expectedErrors)
+		})
+	}
+}
+
+func TestValidateAllReferenced(t *testing.T) {
+	fsys := fstest.MapFS{
+		"test/base/01_sa.yaml":            {Data: []byte(testSA)},
+		"test/base/02_deployment.yaml":    {Data: []byte(testDeployment)},
+		"test/base/03_cr.yaml":            {Data: []byte(testCR)},
+		"test/base/deployment.patch.yaml": {Data: []byte("spec:\n  replicas: 2\n")},
+		"test/base/kustomization.yaml":    {Data: []byte(testKustomization)},
+		"test/03_clusterrolebinding.yaml": {Data: []byte(testClusterRoleBinding)},
+		"test/04_unused.yaml":             {Data: []byte(testSA)},
+		"test/README.md":                  {Data: []byte("# Test assets\n")},
+	}
+	refs := []Reference{
+		{Owner: "Test", Asset: "test/03_clusterrolebinding.yaml"},
+	}
+	errs := ValidateAllReferenced(fsys, refs)
+	checkErrors(t, errs, []string{"asset test/04_unused.yaml is not referenced"})
+}
+
+func checkErrors(t *testing.T, errs []error, expected []string) {
+	t.Helper()
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
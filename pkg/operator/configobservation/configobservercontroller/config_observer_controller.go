@@ -5,6 +5,7 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/configobserver"
+	"github.com/openshift/library-go/pkg/operator/configobserver/apiserver"
 	"github.com/openshift/library-go/pkg/operator/configobserver/proxy"
 	"github.com/openshift/library-go/pkg/operator/events"
 
@@ -27,6 +28,7 @@ func NewConfigObserverController(
 	informers := []factory.Informer{
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Proxies().Informer(),
+		clients.ConfigInformers.Config().V1().APIServers().Informer(),
 	}
 
 	c := &ConfigObserverController{
@@ -34,16 +36,25 @@ func NewConfigObserverController(
 			clients.OperatorClient,
 			eventRecorder.WithComponentSuffix("config-observer-controller-"),
 			configobservation.Listers{
-				ProxyLister_: clients.ConfigInformers.Config().V1().Proxies().Lister(),
+				ProxyLister_:     clients.ConfigInformers.Config().V1().Proxies().Lister(),
+				APIServerLister_: clients.ConfigInformers.Config().V1().APIServers().Lister(),
 				PreRunCachesSynced: append([]cache.InformerSynced{},
 					clients.OperatorClient.Informer().HasSynced,
 					clients.ConfigInformers.Config().V1().Proxies().Informer().HasSynced,
+					clients.ConfigInformers.Config().V1().APIServers().Informer().HasSynced,
 				),
 			},
 			informers,
 			proxy.NewProxyObserveFunc(util.ProxyConfigPath()),
+			observeTLSSecurityProfile,
 		),
 	}
 
 	return c
 }
+
+// observeTLSSecurityProfile observes the TLS security profile of the cluster
+// API server, CSI driver operators use the same profile.
+func observeTLSSecurityProfile(genericListers configobserver.Listers, recorder events.Recorder, existingConfig map[string]interface{}) (map[string]interface{}, []error) {
+	return apiserver.ObserveTLSSecurityProfileWithPaths(genericListers, recorder, existingConfig, util.TLSMinVersionConfigPath(), util.TLSCipherSuitesConfigPath())
+}
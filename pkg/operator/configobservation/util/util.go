@@ -1,14 +1,34 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
 
 	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 )
 
+const (
+	// Key in the TLS security profile ConfigMap.
+	tlsProfileConfigKey  = "config.yaml"
+	tlsProfileVolumeName = "tls-profile-config"
+	tlsProfileMountPath  = "/var/run/configmaps/tls-profile-config"
+	// Pod template annotation with a hash of the observed TLS security profile.
+	tlsProfileHashAnnotation = "storage.openshift.io/tls-profile-hash"
+
+	kubeRBACProxyContainerPrefix = "kube-rbac-proxy"
+)
+
 // InjectObservedProxyInDeploymentContainers takes an observed proxy config and adds it to the containers in the Deployment.
 func InjectObservedProxyInDeploymentContainers(deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) error {
 	containerNamesString := deployment.Annotations["config.openshift.io/inject-proxy"]
@@ -29,3 +49,168 @@ func InjectObservedProxyInDeploymentContainers(deployment *appsv1.Deployment, op
 func ProxyConfigPath() []string {
 	return []string{"targetconfig", "proxy"}
 }
+
+// TLSMinVersionConfigPath returns the path for the observed minimal TLS version.
+func TLSMinVersionConfigPath() []string {
+	return []string{"targetconfig", "servingInfo", "minTLSVersion"}
+}
+
+// TLSCipherSuitesConfigPath returns the path for the observed TLS cipher suites.
+func TLSCipherSuitesConfigPath() []string {
+	return []string{"targetconfig", "servingInfo", "cipherSuites"}
+}
+
+// ObservedTLSProfile returns the observed minimal TLS version and cipher
+// suites. Both are empty when no TLS security profile was observed.
+func ObservedTLSProfile(opSpec *operatorapi.OperatorSpec) (string, []string, error) {
+	if len(opSpec.ObservedConfig.Raw) == 0 {
+		return "", nil, nil
+	}
+	observedConfig := map[string]interface{}{}
+	if err := json.Unmarshal(opSpec.ObservedConfig.Raw, &observedConfig); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal the observedConfig: %w", err)
+	}
+	minTLSVersion, _, err := unstructured.NestedString(observedConfig, TLSMinVersionConfigPath()...)
+	if err != nil {
+		return "", nil, err
+	}
+	cipherSuites, _, err := unstructured.NestedStringSlice(observedConfig, TLSCipherSuitesConfigPath()...)
+	if err != nil {
+		return "", nil, err
+	}
+	return minTLSVersion, cipherSuites, nil
+}
+
+// TLSProfileConfigMapName returns name of the ConfigMap with the TLS security
+// profile of an operator Deployment.
+func TLSProfileConfigMapName(deploymentName string) string {
+	return deploymentName + "-tls-profile"
+}
+
+// GetTLSProfileConfigMap returns a ConfigMap with the observed TLS security
+// profile as GenericOperatorConfig, in the form CSI driver operators read with
+// --config. It returns nil when no profile was observed.
+func GetTLSProfileConfigMap(deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) (*corev1.ConfigMap, error) {
+	minTLSVersion, cipherSuites, err := ObservedTLSProfile(opSpec)
+	if err != nil {
+		return nil, err
+	}
+	if minTLSVersion == "" && len(cipherSuites) == 0 {
+		return nil, nil
+	}
+	servingInfo := map[string]interface{}{}
+	if minTLSVersion != "" {
+		servingInfo["minTLSVersion"] = minTLSVersion
+	}
+	if len(cipherSuites) > 0 {
+		servingInfo["cipherSuites"] = cipherSuites
+	}
+	config, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "operator.openshift.io/v1alpha1",
+		"kind":        "GenericOperatorConfig",
+		"servingInfo": servingInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TLSProfileConfigMapName(deployment.Name),
+			Namespace: deployment.Namespace,
+		},
+		Data: map[string]string{
+			tlsProfileConfigKey: string(config),
+		},
+	}, nil
+}
+
+// InjectObservedTLSProfileInDeploymentContainers takes an observed TLS security
+// profile and passes it to containers of the Deployment, in the form they read it:
+//   - kube-rbac-proxy containers get --tls-min-version and --tls-cipher-suites args.
+//   - Other containers are operators based on library-go controllercmd, they get
+//     --config with GenericOperatorConfig from ConfigMap returned by GetTLSProfileConfigMap.
+//
+// A hash of the profile is added to the pod template, a changed profile rolls out
+// the Deployment.
+func InjectObservedTLSProfileInDeploymentContainers(deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) error {
+	minTLSVersion, cipherSuites, err := ObservedTLSProfile(opSpec)
+	if err != nil {
+		return err
+	}
+	if minTLSVersion == "" && len(cipherSuites) == 0 {
+		return nil
+	}
+
+	podSpec := &deployment.Spec.Template.Spec
+	operatorConfigUsed := false
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if strings.HasPrefix(container.Name, kubeRBACProxyContainerPrefix) {
+			if minTLSVersion != "" {
+				container.Args = setArg(container.Args, "--tls-min-version", minTLSVersion)
+			}
+			if len(cipherSuites) > 0 {
+				container.Args = setArg(container.Args, "--tls-cipher-suites", strings.Join(cipherSuites, ","))
+			}
+			continue
+		}
+		container.Args = setArg(container.Args, "--config", path.Join(tlsProfileMountPath, tlsProfileConfigKey))
+		container.VolumeMounts = setVolumeMount(container.VolumeMounts, corev1.VolumeMount{
+			Name:      tlsProfileVolumeName,
+			MountPath: tlsProfileMountPath,
+			ReadOnly:  true,
+		})
+		operatorConfigUsed = true
+	}
+	if operatorConfigUsed {
+		podSpec.Volumes = setVolume(podSpec.Volumes, corev1.Volume{
+			Name: tlsProfileVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: TLSProfileConfigMapName(deployment.Name)},
+				},
+			},
+		})
+	}
+
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	hash := sha256.Sum256([]byte(minTLSVersion + "/" + strings.Join(cipherSuites, ",")))
+	deployment.Spec.Template.Annotations[tlsProfileHashAnnotation] = hex.EncodeToString(hash[:])
+	return nil
+}
+
+// setArg replaces value of a "--name=value" arg or appends a new one.
+func setArg(args []string, name, value string) []string {
+	arg := name + "=" + value
+	for i := range args {
+		if strings.HasPrefix(args[i], name+"=") {
+			args[i] = arg
+			return args
+		}
+	}
+	return append(args, arg)
+}
+
+// setVolumeMount replaces a volume mount with the same name or appends a new one.
+func setVolumeMount(mounts []corev1.VolumeMount, mount corev1.VolumeMount) []corev1.VolumeMount {
+	for i := range mounts {
+		if mounts[i].Name == mount.Name {
+			mounts[i] = mount
+			return mounts
+		}
+	}
+	return append(mounts, mount)
+}
+
+// setVolume replaces a volume with the same name or appends a new one.
+func setVolume(volumes []corev1.Volume, volume corev1.Volume) []corev1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == volume.Name {
+			volumes[i] = volume
+			return volumes
+		}
+	}
+	return append(volumes, volume)
+}
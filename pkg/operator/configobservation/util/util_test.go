@@ -0,0 +1,200 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	tls12Profile = `{"targetconfig":{"servingInfo":{"minTLSVersion":"VersionTLS12","cipherSuites":["TLS_AES_128_GCM_SHA256","TLS_AES_256_GCM_SHA384"]}}}`
+	tls13Profile = `{"targetconfig":{"servingInfo":{"minTLSVersion":"VersionTLS13","cipherSuites":["TLS_AES_256_GCM_SHA384"]}}}`
+)
+
+func getTestDeployment(operatorArgs, proxyArgs []string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-operator", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "operator", Args: operatorArgs},
+						{Name: "kube-rbac-proxy-8443", Args: proxyArgs},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestInjectObservedTLSProfileInDeploymentContainers(t *testing.T) {
+	tests := []struct {
+		name               string
+		observedConfig     string
+		deployment         *appsv1.Deployment
+		expectedOperator   []string
+		expectedProxy      []string
+		expectConfigVolume bool
+		expectError        bool
+	}{
+		{
+			name:             "no observed config",
+			observedConfig:   "",
+			deployment:       getTestDeployment([]string{"start"}, []string{"--secure-listen-address=0.0.0.0:8443"}),
+			expectedOperator: []string{"start"},
+			expectedProxy:    []string{"--secure-listen-address=0.0.0.0:8443"},
+		},
+		{
+			name:             "observed proxy only",
+			observedConfig:   `{"targetconfig":{"proxy":{"HTTP_PROXY":"http://proxy"}}}`,
+			deployment:       getTestDeployment([]string{"start"}, []string{"--secure-listen-address=0.0.0.0:8443"}),
+			expectedOperator: []string{"start"},
+			expectedProxy:    []string{"--secure-listen-address=0.0.0.0:8443"},
+		},
+		{
+			name:             "observed TLS profile",
+			observedConfig:   tls12Profile,
+			deployment:       getTestDeployment([]string{"start"}, []string{"--secure-listen-address=0.0.0.0:8443"}),
+			expectedOperator: []string{"start", "--config=/var/run/configmaps/tls-profile-config/config.yaml"},
+			expectedProxy: []string{
+				"--secure-listen-address=0.0.0.0:8443",
+				"--tls-min-version=VersionTLS12",
+				"--tls-cipher-suites=TLS_AES_128_GCM_SHA256,TLS_AES_256_GCM_SHA384",
+			},
+			expectConfigVolume: true,
+		},
+		{
+			name:           "changed TLS profile replaces args",
+			observedConfig: tls13Profile,
+			deployment: getTestDeployment(
+				[]string{"start", "--config=/var/run/configmaps/tls-profile-config/config.yaml"},
+				[]string{"--tls-min-version=VersionTLS12", "--tls-cipher-suites=TLS_AES_128_GCM_SHA256"},
+			),
+			expectedOperator:   []string{"start", "--config=/var/run/configmaps/tls-profile-config/config.yaml"},
+			expectedProxy:      []string{"--tls-min-version=VersionTLS13", "--tls-cipher-suites=TLS_AES_256_GCM_SHA384"},
+			expectConfigVolume: true,
+		},
+		{
+			name:           "invalid observed config",
+			observedConfig: `{"targetconfig":{"servingInfo":{"minTLSVersion":12}}}`,
+			deployment:     getTestDeployment(nil, nil),
+			expectError:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := test.deployment
+			opSpec := &operatorapi.OperatorSpec{
+				ObservedConfig: runtime.RawExtension{Raw: []byte(test.observedConfig)},
+			}
+
+			err := InjectObservedTLSProfileInDeploymentContainers(deployment, opSpec)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			podSpec := deployment.Spec.Template.Spec
+			if args := podSpec.Containers[0].Args; !reflect.DeepEqual(args, test.expectedOperator) {
+				t.Errorf("expected operator args:\n%+v\ngot:\n%+v", test.expectedOperator, args)
+			}
+			if args := podSpec.Containers[1].Args; !reflect.DeepEqual(args, test.expectedProxy) {
+				t.Errorf("expected kube-rbac-proxy args:\n%+v\ngot:\n%+v", test.expectedProxy, args)
+			}
+			if len(podSpec.Containers[1].VolumeMounts) != 0 {
+				t.Errorf("expected no volume mounts in kube-rbac-proxy, got %+v", podSpec.Containers[1].VolumeMounts)
+			}
+
+			hasVolume := len(podSpec.Volumes) == 1 && podSpec.Volumes[0].ConfigMap != nil &&
+				podSpec.Volumes[0].ConfigMap.Name == "test-operator-tls-profile"
+			hasMount := len(podSpec.Containers[0].VolumeMounts) == 1 &&
+				podSpec.Containers[0].VolumeMounts[0].MountPath == tlsProfileMountPath
+			if hasVolume != test.expectConfigVolume || hasMount != test.expectConfigVolume {
+				t.Errorf("expected TLS profile volume %t, got volumes %+v, mounts %+v", test.expectConfigVolume, podSpec.Volumes, podSpec.Containers[0].VolumeMounts)
+			}
+			_, hasHash := deployment.Spec.Template.Annotations[tlsProfileHashAnnotation]
+			if hasHash != test.expectConfigVolume {
+				t.Errorf("expected TLS profile hash annotation %t, got annotations %+v", test.expectConfigVolume, deployment.Spec.Template.Annotations)
+			}
+		})
+	}
+}
+
+func TestTLSProfileHashChanges(t *testing.T) {
+	hashes := map[string]bool{}
+	for _, profile := range []string{tls12Profile, tls13Profile} {
+		deployment := getTestDeployment(nil, nil)
+		opSpec := &operatorapi.OperatorSpec{ObservedConfig: runtime.RawExtension{Raw: []byte(profile)}}
+		if err := InjectObservedTLSProfileInDeploymentContainers(deployment, opSpec); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hashes[deployment.Spec.Template.Annotations[tlsProfileHashAnnotation]] = true
+	}
+	if len(hashes) != 2 {
+		t.Errorf("expected different hashes for different profiles, got %v", hashes)
+	}
+}
+
+func TestGetTLSProfileConfigMap(t *testing.T) {
+	tests := []struct {
+		name           string
+		observedConfig string
+		expectedData   map[string]string
+	}{
+		{
+			name:           "no observed TLS profile",
+			observedConfig: `{"targetconfig":{"proxy":{"HTTP_PROXY":"http://proxy"}}}`,
+		},
+		{
+			name:           "observed TLS profile",
+			observedConfig: tls12Profile,
+			expectedData: map[string]string{
+				"config.yaml": `apiVersion: operator.openshift.io/v1alpha1
+kind: GenericOperatorConfig
+servingInfo:
+  cipherSuites:
+  - TLS_AES_128_GCM_SHA256
+  - TLS_AES_256_GCM_SHA384
+  minTLSVersion: VersionTLS12
+`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opSpec := &operatorapi.OperatorSpec{
+				ObservedConfig: runtime.RawExtension{Raw: []byte(test.observedConfig)},
+			}
+			cm, err := GetTLSProfileConfigMap(getTestDeployment(nil, nil), opSpec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expectedData == nil {
+				if cm != nil {
+					t.Errorf("expected no ConfigMap, got %+v", cm)
+				}
+				return
+			}
+			if cm == nil {
+				t.Fatalf("expected ConfigMap, got nil")
+			}
+			if cm.Name != "test-operator-tls-profile" || cm.Namespace != "test" {
+				t.Errorf("expected ConfigMap test/test-operator-tls-profile, got %s/%s", cm.Namespace, cm.Name)
+			}
+			if !reflect.DeepEqual(cm.Data, test.expectedData) {
+				t.Errorf("expected data:\n%+v\ngot:\n%+v", test.expectedData, cm.Data)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
@@ -143,10 +144,14 @@ func (c *CSIDriverOperatorCRController) Sync(ctx context.Context, syncCtx factor
 }
 
 func (c *CSIDriverOperatorCRController) getRequestedClusterCSIDriver(logLevel operatorapi.LogLevel) *operatorapi.ClusterCSIDriver {
+	return getRequestedClusterCSIDriver(c.csiDriverAsset, logLevel)
+}
+
+func getRequestedClusterCSIDriver(csiDriverAsset string, logLevel operatorapi.LogLevel) *operatorapi.ClusterCSIDriver {
 	if logLevel == "" {
 		logLevel = operatorapi.Normal
 	}
-	assetBytes, err := assets.ReadFile(c.csiDriverAsset)
+	assetBytes, err := assets.ReadFile(csiDriverAsset)
 	if err != nil {
 		panic(err)
 	}
@@ -159,7 +164,7 @@ func (c *CSIDriverOperatorCRController) getRequestedClusterCSIDriver(logLevel op
 
 func (c *CSIDriverOperatorCRController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.Name(), c.eventRecorder)
+	ctrl := c.factory.WithSync(operatormetrics.InstrumentSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
@@ -203,6 +208,11 @@ func (c *CSIDriverOperatorCRController) syncConditions(ctx context.Context, cond
 		}
 	}
 	availableCnd.Type = c.crConditionName(operatorapi.OperatorStatusTypeAvailable)
+	if availableCnd.Reason == "WaitForOperator" {
+		operatormetrics.WaitForOperatorStarted(c.csiDriverName)
+	} else {
+		operatormetrics.WaitForOperatorFinished(c.csiDriverName)
+	}
 
 	progressingCnd := status.UnionCondition(operatorapi.OperatorStatusTypeProgressing, operatorapi.ConditionFalse, nil, conditions...)
 	progressingCnd.Type = c.crConditionName(operatorapi.OperatorStatusTypeProgressing)
@@ -23,11 +23,12 @@ func GetAWSEBSCSIOperatorConfig(isHypershift bool) CSIOperatorConfig {
 	}
 
 	csiDriverConfig := CSIOperatorConfig{
-		CSIDriverName:   AWSEBSCSIDriverName,
-		ConditionPrefix: "AWSEBS",
-		Platform:        configv1.AWSPlatformType,
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CSIDriverName:            AWSEBSCSIDriverName,
+		ConditionPrefix:          "AWSEBS",
+		Platform:                 configv1.AWSPlatformType,
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/aws-ebs.yaml",
 	}
 
 	if !isHypershift {
@@ -54,8 +55,13 @@ func GetAWSEBSCSIOperatorConfig(isHypershift bool) CSIOperatorConfig {
 			"csidriveroperators/aws-ebs/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_role_aws-ebs-csi-driver-operator-role.yaml",
 			"csidriveroperators/aws-ebs/hypershift/mgmt/generated/v1_serviceaccount_aws-ebs-csi-driver-operator.yaml",
 			"csidriveroperators/aws-ebs/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_rolebinding_aws-ebs-csi-driver-operator-rolebinding.yaml",
+			"csidriveroperators/aws-ebs/hypershift/mgmt/11_metrics_service.yaml",
+			"csidriveroperators/aws-ebs/hypershift/mgmt/13_prometheus_role.yaml",
+			"csidriveroperators/aws-ebs/hypershift/mgmt/14_prometheus_rolebinding.yaml",
+			"csidriveroperators/aws-ebs/hypershift/mgmt/16_networkpolicy_controller.yaml",
 		}
 		csiDriverConfig.DeploymentAsset = "csidriveroperators/aws-ebs/hypershift/mgmt/generated/apps_v1_deployment_aws-ebs-csi-driver-operator.yaml"
+		csiDriverConfig.MgmtServiceMonitorAsset = "csidriveroperators/aws-ebs/hypershift/mgmt/12_servicemonitor.yaml"
 		csiDriverConfig.CRAsset = "csidriveroperators/aws-ebs/hypershift/guest/generated/operator.openshift.io_v1_clustercsidriver_ebs.csi.aws.com.yaml"
 	}
 
@@ -26,11 +26,12 @@ func GetAzureDiskCSIOperatorConfig(isHyperShift bool) CSIOperatorConfig {
 	}
 
 	csiDriverConfig := CSIOperatorConfig{
-		CSIDriverName:   AzureDiskDriverName,
-		ConditionPrefix: "AzureDisk",
-		Platform:        configv1.AzurePlatformType,
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CSIDriverName:            AzureDiskDriverName,
+		ConditionPrefix:          "AzureDisk",
+		Platform:                 configv1.AzurePlatformType,
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/azure-disk.yaml",
 	}
 
 	if !isHyperShift {
@@ -55,8 +56,13 @@ func GetAzureDiskCSIOperatorConfig(isHyperShift bool) CSIOperatorConfig {
 			"csidriveroperators/azure-disk/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_role_azure-disk-csi-driver-operator-role.yaml",
 			"csidriveroperators/azure-disk/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_rolebinding_azure-disk-csi-driver-operator-rolebinding.yaml",
 			"csidriveroperators/azure-disk/hypershift/mgmt/generated/v1_serviceaccount_azure-disk-csi-driver-operator.yaml",
+			"csidriveroperators/azure-disk/hypershift/mgmt/11_metrics_service.yaml",
+			"csidriveroperators/azure-disk/hypershift/mgmt/13_prometheus_role.yaml",
+			"csidriveroperators/azure-disk/hypershift/mgmt/14_prometheus_rolebinding.yaml",
+			"csidriveroperators/azure-disk/hypershift/mgmt/16_networkpolicy_controller.yaml",
 		}
 		csiDriverConfig.DeploymentAsset = "csidriveroperators/azure-disk/hypershift/mgmt/generated/apps_v1_deployment_azure-disk-csi-driver-operator.yaml"
+		csiDriverConfig.MgmtServiceMonitorAsset = "csidriveroperators/azure-disk/hypershift/mgmt/12_servicemonitor.yaml"
 		csiDriverConfig.CRAsset = "csidriveroperators/azure-disk/hypershift/guest/generated/operator.openshift.io_v1_clustercsidriver_disk.csi.azure.com.yaml"
 	}
 	return csiDriverConfig
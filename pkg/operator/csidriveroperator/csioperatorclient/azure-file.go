@@ -37,12 +37,14 @@ func GetAzureFileCSIOperatorConfig(isHyperShift bool) CSIOperatorConfig {
 	}
 
 	csiDriverConfig := CSIOperatorConfig{
-		CSIDriverName:   AzureFileDriverName,
-		ConditionPrefix: "AzureFile",
-		Platform:        configv1.AzurePlatformType,
-		StatusFilter:    IsNotAzueStackCloud,
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CSIDriverName:            AzureFileDriverName,
+		ConditionPrefix:          "AzureFile",
+		Platform:                 configv1.AzurePlatformType,
+		StatusFilter:             IsNotAzueStackCloud,
+		StatusFilterDescription:  "Azure File is not supported on Azure Stack Hub",
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/azure-file.yaml",
 	}
 
 	if !isHyperShift {
@@ -67,8 +69,13 @@ func GetAzureFileCSIOperatorConfig(isHyperShift bool) CSIOperatorConfig {
 			"csidriveroperators/azure-file/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_role_azure-file-csi-driver-operator-role.yaml",
 			"csidriveroperators/azure-file/hypershift/mgmt/generated/rbac.authorization.k8s.io_v1_rolebinding_azure-file-csi-driver-operator-rolebinding.yaml",
 			"csidriveroperators/azure-file/hypershift/mgmt/generated/v1_serviceaccount_azure-file-csi-driver-operator.yaml",
+			"csidriveroperators/azure-file/hypershift/mgmt/11_metrics_service.yaml",
+			"csidriveroperators/azure-file/hypershift/mgmt/13_prometheus_role.yaml",
+			"csidriveroperators/azure-file/hypershift/mgmt/14_prometheus_rolebinding.yaml",
+			"csidriveroperators/azure-file/hypershift/mgmt/16_networkpolicy_controller.yaml",
 		}
 		csiDriverConfig.DeploymentAsset = "csidriveroperators/azure-file/hypershift/mgmt/generated/apps_v1_deployment_azure-file-csi-driver-operator.yaml"
+		csiDriverConfig.MgmtServiceMonitorAsset = "csidriveroperators/azure-file/hypershift/mgmt/12_servicemonitor.yaml"
 		csiDriverConfig.CRAsset = "csidriveroperators/azure-file/hypershift/guest/generated/operator.openshift.io_v1_clustercsidriver_file.csi.azure.com.yaml"
 	}
 	return csiDriverConfig
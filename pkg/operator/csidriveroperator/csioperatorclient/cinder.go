@@ -32,9 +32,10 @@ func GetOpenStackCinderCSIOperatorConfig(clients *csoclients.Clients, recorder e
 			"csidriveroperators/openstack-cinder/05_clusterrole.yaml",
 			"csidriveroperators/openstack-cinder/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/openstack-cinder/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/openstack-cinder/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CRAsset:                  "csidriveroperators/openstack-cinder/08_cr.yaml",
+		DeploymentAsset:          "csidriveroperators/openstack-cinder/07_deployment.yaml",
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/openstack-cinder.yaml",
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
 	}
 }
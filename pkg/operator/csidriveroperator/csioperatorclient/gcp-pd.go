@@ -30,9 +30,10 @@ func GetGCPPDCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/gcp-pd/05_clusterrole.yaml",
 			"csidriveroperators/gcp-pd/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/gcp-pd/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/gcp-pd/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CRAsset:                  "csidriveroperators/gcp-pd/08_cr.yaml",
+		DeploymentAsset:          "csidriveroperators/gcp-pd/07_deployment.yaml",
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/gcp-pd.yaml",
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
 	}
 }
@@ -35,10 +35,11 @@ func GetIBMVPCBlockCSIOperatorConfig() CSIOperatorConfig {
 	}
 
 	return CSIOperatorConfig{
-		CSIDriverName:   IBMVPCBlockCSIDriverName,
-		ConditionPrefix: "IBMVPCBlock",
-		Platform:        configv1.IBMCloudPlatformType,
-		StatusFilter:    isNotExternalTopologyMode,
+		CSIDriverName:           IBMVPCBlockCSIDriverName,
+		ConditionPrefix:         "IBMVPCBlock",
+		Platform:                configv1.IBMCloudPlatformType,
+		StatusFilter:            isNotExternalTopologyMode,
+		StatusFilterDescription: "IBM ROKS clusters with External control plane topology do not need CSO to install the driver",
 		StaticAssets: []string{
 			"csidriveroperators/ibm-vpc-block/03_sa.yaml",
 			"csidriveroperators/ibm-vpc-block/04_role.yaml",
@@ -46,9 +47,10 @@ func GetIBMVPCBlockCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/ibm-vpc-block/06_clusterrole.yaml",
 			"csidriveroperators/ibm-vpc-block/07_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/ibm-vpc-block/09_cr.yaml",
-		DeploymentAsset: "csidriveroperators/ibm-vpc-block/08_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CRAsset:                  "csidriveroperators/ibm-vpc-block/09_cr.yaml",
+		DeploymentAsset:          "csidriveroperators/ibm-vpc-block/08_deployment.yaml",
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/ibm-vpc-block.yaml",
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            false,
 	}
 }
@@ -15,6 +15,8 @@ import (
 const (
 	CloudConfigName = "cloud-provider-config"
 
+	ManilaOperandNamespace = "openshift-manila-csi-driver"
+
 	envManilaDriverOperatorImage = "MANILA_DRIVER_OPERATOR_IMAGE"
 	envManilaDriverImage         = "MANILA_DRIVER_IMAGE"
 	envNFSDriverImage            = "MANILA_NFS_DRIVER_IMAGE"
@@ -38,6 +40,8 @@ func GetManilaOperatorConfig(clients *csoclients.Clients, recorder events.Record
 			"csidriveroperators/manila/04_rolebinding.yaml",
 			"csidriveroperators/manila/05_clusterrole.yaml",
 			"csidriveroperators/manila/06_clusterrolebinding.yaml",
+			"csidriveroperators/manila/10_networkpolicy_default_deny.yaml",
+			"csidriveroperators/manila/12_networkpolicy_allow_metrics.yaml",
 		},
 		CRAsset:         "csidriveroperators/manila/08_cr.yaml",
 		DeploymentAsset: "csidriveroperators/manila/07_deployment.yaml",
@@ -45,7 +49,8 @@ func GetManilaOperatorConfig(clients *csoclients.Clients, recorder events.Record
 		ExtraControllers: []factory.Controller{
 			newCertificateSyncerOrDie(clients, recorder),
 		},
-		AllowDisabled: true,
+		AllowDisabled:    true,
+		OperandNamespace: ManilaOperandNamespace,
 	}
 }
 
@@ -49,8 +49,13 @@ func GetPowerVSBlockCSIOperatorConfig(isHypershift bool) CSIOperatorConfig {
 			"csidriveroperators/powervs-block/hypershift/mgmt/01_operator_role.yaml",
 			"csidriveroperators/powervs-block/hypershift/mgmt/01_sa.yaml",
 			"csidriveroperators/powervs-block/hypershift/mgmt/03_rolebinding.yaml",
+			"csidriveroperators/powervs-block/hypershift/mgmt/11_metrics_service.yaml",
+			"csidriveroperators/powervs-block/hypershift/mgmt/13_prometheus_role.yaml",
+			"csidriveroperators/powervs-block/hypershift/mgmt/14_prometheus_rolebinding.yaml",
+			"csidriveroperators/powervs-block/hypershift/mgmt/16_networkpolicy_controller.yaml",
 		}
 		csiDriverConfig.DeploymentAsset = "csidriveroperators/powervs-block/hypershift/mgmt/06_deployment.yaml"
+		csiDriverConfig.MgmtServiceMonitorAsset = "csidriveroperators/powervs-block/hypershift/mgmt/12_servicemonitor.yaml"
 		csiDriverConfig.CRAsset = "csidriveroperators/powervs-block/hypershift/guest/07_cr.yaml"
 	}
 
@@ -11,6 +11,11 @@ const (
 	// AllPlatforms is a special PlatformType that indicates a CSI driver is installable on any cloud provider.
 	// It is only meant to be used by the CSIOperatorConfig, and does not represent a real OpenShift platform type.
 	AllPlatforms configv1.PlatformType = "AllPlatforms"
+
+	// Pod Security Admission levels, see k8s.io/pod-security-admission/api.
+	PodSecurityLevelPrivileged = "privileged"
+	PodSecurityLevelBaseline   = "baseline"
+	PodSecurityLevelRestricted = "restricted"
 )
 
 // CSIOperatorConfig is configuration of a CSI driver operator.
@@ -29,12 +34,18 @@ type CSIOperatorConfig struct {
 	// If StatusFilter is nil, it is assumed to be true and will proceed like
 	// normal and run the usual checks.
 	StatusFilter func(*configv1.InfrastructureStatus, bool) bool
+	// StatusFilterDescription is a human readable explanation of when
+	// StatusFilter returns false.
+	StatusFilterDescription string
 	// StaticAssets is list of bindata assets to create when starting the CSI
-	// driver operator in standalone OCP clusters
+	// driver operator in standalone OCP clusters.
+	// NetworkPolicies in the list are applied by a separate controller, a driver
+	// can use them to allow its specific traffic (e.g. NFS).
 	StaticAssets []string
 
 	// MgmtStaticAssets returns a list of bindata assets to create in mgmt cluster
-	// when starting the driver in hypershift clusters
+	// when starting the driver in hypershift clusters.
+	// NetworkPolicies in the list are applied by a separate controller.
 	MgmtStaticAssets []string
 
 	// CRAsset is name of the bindata asset with ClusterCSIDriver of the
@@ -42,6 +53,16 @@ type CSIOperatorConfig struct {
 	CRAsset string
 	// ServiceMonitorAsset is the name of the bindata asset with the ServiceMonitor
 	ServiceMonitorAsset string
+	// MgmtServiceMonitorAsset is the name of the bindata asset with the ServiceMonitor
+	// to create in mgmt cluster when running the driver in hypershift clusters.
+	// ${CONTROLPLANE_NAMESPACE} in the asset is replaced by the control plane namespace.
+	MgmtServiceMonitorAsset string
+	// VolumeSnapshotClassAsset is name of the bindata asset with the default
+	// VolumeSnapshotClass of the driver. When the driver has no default
+	// VolumeSnapshotClass, CSO marks the existing class with the same name as the
+	// default or creates it from the asset. Empty when the driver does not support
+	// snapshots.
+	VolumeSnapshotClassAsset string
 	// DeploymentAsset is name of the bindata asset with Deployment of the
 	// operator. It will get updated by OCS in this way:
 	// - ImageReplacer this CSIOperatorConfig is run.
@@ -54,8 +75,19 @@ type CSIOperatorConfig struct {
 	// In this case, the CSO's overall Available / Progressing conditions will not be affected by Disabled
 	// ClusterCSIDriver.
 	AllowDisabled bool
-	// Extra controllers to start with the CSI driver operator
+	// Extra controllers to start with the CSI driver operator.
+	// They cannot be restarted, don't use them together with RequireFeatureGate.
 	ExtraControllers []factory.Controller
-	// Run the CSI driver operator only when given FeatureGate is enabled
+	// Run the CSI driver operator only when given FeatureGate is enabled.
+	// CSO stops the CSI driver operator when the FeatureGate gets disabled and
+	// deletes its Deployment and static assets. The ClusterCSIDriver and objects
+	// created by the CSI driver operator itself are left in the cluster.
 	RequireFeatureGate configv1.FeatureGateName
+	// OperandNamespace is the namespace where the CSI driver runs.
+	// openshift-cluster-csi-drivers is used when empty.
+	OperandNamespace string
+	// PodSecurityLevel is the Pod Security Admission level required by the CSI
+	// driver in its OperandNamespace. PodSecurityLevelPrivileged is used when empty,
+	// CSI driver node DaemonSets usually need it.
+	PodSecurityLevel string
 }
@@ -36,11 +36,13 @@ func GetVMwareVSphereCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/vsphere/13_prometheus_role.yaml",
 			"csidriveroperators/vsphere/14_prometheus_rolebinding.yaml",
 			"csidriveroperators/vsphere/15_prometheusrules.yaml",
+			"csidriveroperators/vsphere/16_networkpolicy_allow_webhook.yaml",
 		},
-		ServiceMonitorAsset: "csidriveroperators/vsphere/12_servicemonitor.yaml",
-		CRAsset:             "csidriveroperators/vsphere/09_cr.yaml",
-		DeploymentAsset:     "csidriveroperators/vsphere/08_deployment.yaml",
-		ImageReplacer:       strings.NewReplacer(pairs...),
-		AllowDisabled:       true,
+		ServiceMonitorAsset:      "csidriveroperators/vsphere/12_servicemonitor.yaml",
+		CRAsset:                  "csidriveroperators/vsphere/09_cr.yaml",
+		DeploymentAsset:          "csidriveroperators/vsphere/08_deployment.yaml",
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/vsphere.yaml",
+		ImageReplacer:            strings.NewReplacer(pairs...),
+		AllowDisabled:            true,
 	}
 }
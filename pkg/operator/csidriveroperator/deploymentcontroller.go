@@ -23,6 +23,7 @@ import (
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
 	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 )
 
@@ -173,29 +174,18 @@ func (c *CSIDriverOperatorDeploymentController) Sync(ctx context.Context, syncCt
 		return nil
 	}
 
-	replacers := []*strings.Replacer{sidecarReplacer}
-	// Replace images
-	if c.csiOperatorConfig.ImageReplacer != nil {
-		replacers = append(replacers, c.csiOperatorConfig.ImageReplacer)
-	}
-
-	required, err := csoutils.GetRequiredDeployment(c.csiOperatorConfig.DeploymentAsset, opSpec, nil, nil, replacers...)
+	infra, err := c.infraLister.Get(infraConfigName)
 	if err != nil {
-		return fmt.Errorf("failed to generate required Deployment: %s", err)
+		return fmt.Errorf("failed to get infrastructure resource: %w", err)
 	}
 
-	requiredCopy := required.DeepCopy()
-	err = util.InjectObservedProxyInDeploymentContainers(requiredCopy, opSpec)
+	requiredCopy, err := getRequiredStandaloneDeployment(c.csiOperatorConfig, opSpec, infra)
 	if err != nil {
-		return fmt.Errorf("failed to inject proxy data into deployment: %w", err)
+		return err
 	}
 
-	infra, err := c.infraLister.Get(infraConfigName)
-	if err != nil {
-		return fmt.Errorf("failed to get infrastructure resource: %w", err)
-	}
-	if infra.Status.ControlPlaneTopology == configv1.ExternalTopologyMode {
-		requiredCopy.Spec.Template.Spec.NodeSelector = map[string]string{}
+	if err := applyTLSProfileConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, requiredCopy, opSpec); err != nil {
+		return err
 	}
 
 	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(requiredCopy, opStatus.Generations)
@@ -211,9 +201,39 @@ func (c *CSIDriverOperatorDeploymentController) Sync(ctx context.Context, syncCt
 	return checkDeploymentHealth(ctx, c.kubeClient.AppsV1(), deployment)
 }
 
+// getRequiredStandaloneDeployment returns Deployment of a CSI driver operator
+// in a standalone cluster.
+func getRequiredStandaloneDeployment(cfg csioperatorclient.CSIOperatorConfig, opSpec *operatorv1.OperatorSpec, infra *configv1.Infrastructure) (*appsv1.Deployment, error) {
+	replacers := []*strings.Replacer{getSidecarReplacer()}
+	// Replace images
+	if cfg.ImageReplacer != nil {
+		replacers = append(replacers, cfg.ImageReplacer)
+	}
+
+	required, err := csoutils.GetRequiredDeployment(cfg.DeploymentAsset, opSpec, nil, nil, replacers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate required Deployment: %s", err)
+	}
+
+	requiredCopy := required.DeepCopy()
+	err = util.InjectObservedProxyInDeploymentContainers(requiredCopy, opSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject proxy data into deployment: %w", err)
+	}
+	err = util.InjectObservedTLSProfileInDeploymentContainers(requiredCopy, opSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject TLS security profile into deployment: %w", err)
+	}
+
+	if infra.Status.ControlPlaneTopology == configv1.ExternalTopologyMode {
+		requiredCopy.Spec.Template.Spec.NodeSelector = map[string]string{}
+	}
+	return requiredCopy, nil
+}
+
 func (c *CSIDriverOperatorDeploymentController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.Name(), c.eventRecorder)
+	ctrl := c.factory.WithSync(operatormetrics.InstrumentSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
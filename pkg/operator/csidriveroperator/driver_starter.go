@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	storagev1 "k8s.io/api/storage/v1"
@@ -16,6 +18,8 @@ import (
 	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/networkpolicy"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/controller/manager"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
@@ -37,6 +41,8 @@ const (
 	featureGateConfigName = "cluster"
 
 	annOpenShiftManaged = "csi.openshift.io/managed"
+
+	csiDriverStarterControllerName = "CSIDriverStarter"
 )
 
 var (
@@ -46,6 +52,9 @@ var (
 type driverInterface interface {
 	initController([]csioperatorclient.CSIOperatorConfig, driverInterface) factory.Controller
 	addExtraControllersToManager(manager.ControllerManager, csioperatorclient.CSIOperatorConfig)
+	// deleteCSIDriverOperator deletes the Deployment and static assets of a
+	// stopped CSI driver operator.
+	deleteCSIDriverOperator(context.Context, csioperatorclient.CSIOperatorConfig) error
 	sync(ctx context.Context, syncCtx factory.SyncContext) error
 }
 
@@ -53,7 +62,6 @@ type driverStarterCommon struct {
 	commonClients     *csoclients.Clients
 	resyncInterval    time.Duration
 	infraLister       openshiftv1.InfrastructureLister
-	featureGates      featuregates.FeatureGate
 	csiDriverLister   storagelister.CSIDriverLister
 	restMapper        *restmapper.DeferredDiscoveryRESTMapper
 	versionGetter     status.VersionGetter
@@ -61,6 +69,16 @@ type driverStarterCommon struct {
 	eventRecorder     events.Recorder
 	controllers       []csiDriverControllerManager
 	controllerStarted bool // true if at least one controller has started
+	// protects controllers[*].running and synced, they are read by other controllers via RunningDrivers()
+	runningLock *sync.RWMutex
+	synced      bool
+	// protects featureGates, they are updated by SetFeatureGates()
+	featureGateLock *sync.Mutex
+	featureGates    featuregates.FeatureGate
+	// driverStarter adds controllers specific to standalone or HyperShift
+	// clusters when a ControllerManager is (re)created.
+	driverStarter driverInterface
+	syncCtx       factory.SyncContext
 }
 
 type standAloneDriverStarter struct {
@@ -74,6 +92,14 @@ type hypershiftDriverStarter struct {
 	controllerNamespace string
 }
 
+// RunningDriversGetter provides configs of CSI driver operators started by CSO.
+type RunningDriversGetter interface {
+	// RunningDrivers returns configs of the running CSI driver operators.
+	// synced is false until all CSI driver operators were evaluated at least once,
+	// e.g. right after CSO start.
+	RunningDrivers() (configs []csioperatorclient.CSIOperatorConfig, synced bool)
+}
+
 type RelatedObjectGetter interface {
 	RelatedObjects() ([]configv1.ObjectReference, error)
 }
@@ -85,6 +111,12 @@ type csiDriverControllerManager struct {
 	mgr                manager.ControllerManager
 	running            bool
 	ctrlRelatedObjects RelatedObjectGetter
+	// stops the running ControllerManager
+	cancel context.CancelFunc
+	// closed when the running ControllerManager exits
+	done chan struct{}
+	// true if related objects of the CSI driver operator were added to relatedObjects
+	relatedObjectsAdded bool
 }
 
 func initCommonStarterParams(
@@ -102,6 +134,8 @@ func initCommonStarterParams(
 		featureGates:      featureGates,
 		eventRecorder:     eventRecorder.WithComponentSuffix("CSIDriverStarter"),
 		controllerStarted: false,
+		runningLock:       &sync.RWMutex{},
+		featureGateLock:   &sync.Mutex{},
 	}
 	return c
 }
@@ -115,6 +149,8 @@ func (dsrc *driverStarterCommon) createInformers() {
 func (dsrc *driverStarterCommon) initController(
 	driverConfigs []csioperatorclient.CSIOperatorConfig, vStarter driverInterface) factory.Controller {
 	dsrc.createInformers()
+	dsrc.driverStarter = vStarter
+	dsrc.syncCtx = factory.NewSyncContext(csiDriverStarterControllerName, dsrc.eventRecorder)
 	relatedObjects = []configv1.ObjectReference{}
 
 	// Populating all CSI driver operator ControllerManagers here simplifies
@@ -125,32 +161,55 @@ func (dsrc *driverStarterCommon) initController(
 	// started in sync() when their platform is detected.
 	dsrc.controllers = []csiDriverControllerManager{}
 	for _, cfg := range driverConfigs {
-		mgr, ctrlRelatedObjects := dsrc.createCSIControllerManager(cfg)
-		vStarter.addExtraControllersToManager(mgr, cfg)
-		dsrc.controllers = append(dsrc.controllers, csiDriverControllerManager{
-			operatorConfig:     cfg,
-			mgr:                mgr,
-			running:            false,
-			ctrlRelatedObjects: ctrlRelatedObjects,
-		})
+		ctrl := csiDriverControllerManager{
+			operatorConfig: cfg,
+			running:        false,
+		}
+		dsrc.resetControllerManager(&ctrl)
+		dsrc.controllers = append(dsrc.controllers, ctrl)
 	}
 
-	return factory.New().WithSync(dsrc.sync).WithSyncDegradedOnError(dsrc.commonClients.OperatorClient).WithInformers(
+	return factory.New().WithSyncContext(dsrc.syncCtx).WithSync(operatormetrics.InstrumentSync(csiDriverStarterControllerName, dsrc.sync)).WithSyncDegradedOnError(dsrc.commonClients.OperatorClient).WithInformers(
 		dsrc.commonClients.OperatorClient.Informer(),
 		dsrc.commonClients.ConfigInformers.Config().V1().Infrastructures().Informer(),
 		dsrc.commonClients.ConfigInformers.Config().V1().FeatureGates().Informer(),
 		dsrc.commonClients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Informer(),
-	).ToController("CSIDriverStarter", dsrc.eventRecorder)
+	).ToController(csiDriverStarterControllerName, dsrc.eventRecorder)
+}
+
+// resetControllerManager creates a new ControllerManager of the CSI driver
+// operator. Controllers cannot be started again after they were stopped.
+func (dsrc *driverStarterCommon) resetControllerManager(ctrl *csiDriverControllerManager) {
+	mgr, ctrlRelatedObjects := dsrc.createCSIControllerManager(ctrl.operatorConfig)
+	dsrc.driverStarter.addExtraControllersToManager(mgr, ctrl.operatorConfig)
+	ctrl.mgr = mgr
+	ctrl.ctrlRelatedObjects = ctrlRelatedObjects
+}
+
+// SetFeatureGates updates the current FeatureGates and re-evaluates which CSI
+// driver operators should run.
+func (dsrc *driverStarterCommon) SetFeatureGates(featureGates featuregates.FeatureGate) {
+	dsrc.featureGateLock.Lock()
+	dsrc.featureGates = featureGates
+	dsrc.featureGateLock.Unlock()
+	dsrc.syncCtx.Queue().Add(factory.DefaultQueueKey)
+}
+
+func (dsrc *driverStarterCommon) currentFeatureGates() featuregates.FeatureGate {
+	dsrc.featureGateLock.Lock()
+	defer dsrc.featureGateLock.Unlock()
+	return dsrc.featureGates
 }
 
 func (dsrc *driverStarterCommon) createCSIControllerManager(cfg csioperatorclient.CSIOperatorConfig) (manager.ControllerManager, RelatedObjectGetter) {
 	manager := manager.NewControllerManager()
 	clients := dsrc.commonClients
 
+	networkPolicyAssets, staticAssets := networkpolicy.SplitAssets(assets.ReadFile, cfg.StaticAssets)
 	staticResourceClients := resourceapply.NewKubeClientHolder(clients.KubeClient).WithDynamicClient(clients.DynamicClient)
 	src := staticresourcecontroller.NewStaticResourceController(
 		cfg.ConditionPrefix+"CSIDriverOperatorStaticController",
-		assets.ReadFile, cfg.StaticAssets, staticResourceClients, dsrc.commonClients.OperatorClient, dsrc.eventRecorder).
+		assets.ReadFile, staticAssets, staticResourceClients, dsrc.commonClients.OperatorClient, dsrc.eventRecorder).
 		AddKubeInformers(clients.KubeInformers).
 		AddRESTMapper(clients.RestMapper).
 		AddCategoryExpander(clients.CategoryExpander)
@@ -158,6 +217,19 @@ func (dsrc *driverStarterCommon) createCSIControllerManager(cfg csioperatorclien
 	manager = manager.WithController(src, 1)
 	ctrlRelatedObjects := src
 
+	if len(networkPolicyAssets) > 0 {
+		manager = manager.WithController(networkpolicy.NewNetworkPolicyController(
+			cfg.ConditionPrefix+"CSIDriverOperatorNetworkPolicyController",
+			assets.ReadFile,
+			networkPolicyAssets,
+			clients.KubeClient,
+			clients.KubeInformers,
+			clients.OperatorClient,
+			dsrc.eventRecorder,
+			dsrc.resyncInterval,
+		), 1)
+	}
+
 	crController := NewCSIDriverOperatorCRController(
 		cfg.ConditionPrefix,
 		clients,
@@ -210,6 +282,15 @@ func (dsrc *driverStarterCommon) sync(ctx context.Context, syncCtx factory.SyncC
 	if err != nil {
 		return err
 	}
+	featureGates := dsrc.currentFeatureGates()
+	var started, stopped []string
+	defer func() {
+		// Report the changes also when a later driver fails.
+		if len(started) > 0 || len(stopped) > 0 {
+			dsrc.eventRecorder.Eventf("CSIDriverOperatorsChanged", "CSI driver operators changed after FeatureGate change: started: [%s], stopped: [%s]",
+				strings.Join(started, ", "), strings.Join(stopped, ", "))
+		}
+	}()
 	// Start controller managers for this platform
 	for i := range dsrc.controllers {
 		ctrl := &dsrc.controllers[i]
@@ -223,24 +304,40 @@ func (dsrc *driverStarterCommon) sync(ctx context.Context, syncCtx factory.SyncC
 			return err
 		}
 
-		if !ctrl.running {
-			isInstalled, err := dsrc.isOperatorInstalled(ctrl.operatorConfig.CSIDriverName)
-			if err != nil {
-				return err
+		if ctrl.running {
+			// Only a FeatureGate change can stop a running CSI driver operator.
+			if ctrl.operatorConfig.RequireFeatureGate == "" || isFeatureGateEnabled(ctrl.operatorConfig, featureGates) {
+				continue
 			}
-			shouldRun, err := shouldRunController(ctrl.operatorConfig, infrastructure, dsrc.featureGates, csiDriver, isInstalled)
-			if err != nil {
+			klog.V(2).Infof("Stopping ControllerManager for %s: feature %s is not enabled", ctrl.operatorConfig.ConditionPrefix, ctrl.operatorConfig.RequireFeatureGate)
+			if err := dsrc.stopControllerManager(ctx, ctrl); err != nil {
 				return err
 			}
-			if !shouldRun {
-				continue
-			}
+			operatormetrics.SetCSIDriverOperatorEnabled(ctrl.operatorConfig.CSIDriverName, false, runReasonFeatureGateDisabled)
+			stopped = append(stopped, ctrl.operatorConfig.ConditionPrefix)
+			continue
+		}
+
+		isInstalled, err := dsrc.isOperatorInstalled(ctrl.operatorConfig.CSIDriverName)
+		if err != nil {
+			return err
+		}
+		shouldRun, reason, err := shouldRunController(ctrl.operatorConfig, infrastructure, featureGates, csiDriver, isInstalled)
+		operatormetrics.SetCSIDriverOperatorEnabled(ctrl.operatorConfig.CSIDriverName, shouldRun, reason)
+		if err != nil {
+			return err
+		}
+		if !shouldRun {
+			continue
+		}
+		if !ctrl.relatedObjectsAdded {
 			// add static assets
 			objs, err := ctrl.ctrlRelatedObjects.RelatedObjects()
 			if err != nil {
 				if isNoMatchError(err) {
 					// RESTMapper NoResourceMatch / NoKindMatch errors are cached. Reset the cache to get fresh results on the next sync.
 					dsrc.restMapper.Reset()
+					operatormetrics.RESTMapperReset(csiDriverStarterControllerName)
 				}
 				return err
 			}
@@ -250,13 +347,32 @@ func (dsrc *driverStarterCommon) sync(ctx context.Context, syncCtx factory.SyncC
 				Resource: "clustercsidrivers",
 				Name:     ctrl.operatorConfig.CSIDriverName,
 			})
-			klog.V(2).Infof("Starting ControllerManager for %s", ctrl.operatorConfig.ConditionPrefix)
-			go ctrl.mgr.Start(ctx)
-			ctrl.running = true
-			dsrc.controllerStarted = true
+			// Related objects are added only once, also when the CSI driver operator is started again.
+			ctrl.relatedObjectsAdded = true
+		}
+		klog.V(2).Infof("Starting ControllerManager for %s", ctrl.operatorConfig.ConditionPrefix)
+		mgrCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		mgr := ctrl.mgr
+		go func() {
+			defer close(done)
+			mgr.Start(mgrCtx)
+		}()
+		ctrl.cancel = cancel
+		ctrl.done = done
+		dsrc.runningLock.Lock()
+		ctrl.running = true
+		dsrc.runningLock.Unlock()
+		dsrc.controllerStarted = true
+		if dsrc.synced && reason == runReasonFeatureGateEnabled {
+			started = append(started, ctrl.operatorConfig.ConditionPrefix)
 		}
 	}
 
+	dsrc.runningLock.Lock()
+	dsrc.synced = true
+	dsrc.runningLock.Unlock()
+
 	// If no controller has started, then CSIDriverOperatorCRController
 	// will not run and we have to set Upgradeable=true right now.
 	if !dsrc.controllerStarted {
@@ -268,6 +384,67 @@ func (dsrc *driverStarterCommon) sync(ctx context.Context, syncCtx factory.SyncC
 	return nil
 }
 
+// stopControllerManager stops a running ControllerManager of the CSI driver
+// operator, deletes the CSI driver operator and removes its conditions and metrics. A new ControllerManager is
+// created, so the CSI driver operator can be started again.
+func (dsrc *driverStarterCommon) stopControllerManager(ctx context.Context, ctrl *csiDriverControllerManager) error {
+	ctrl.cancel()
+	// Wait for the controllers, so they don't update conditions removed below
+	// or run together with the new ControllerManager.
+	select {
+	case <-ctrl.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	ctrl.cancel = nil
+	ctrl.done = nil
+	dsrc.runningLock.Lock()
+	ctrl.running = false
+	dsrc.runningLock.Unlock()
+	dsrc.resetControllerManager(ctrl)
+	operatormetrics.WaitForOperatorCancelled(ctrl.operatorConfig.CSIDriverName)
+
+	if err := dsrc.driverStarter.deleteCSIDriverOperator(ctx, ctrl.operatorConfig); err != nil {
+		return fmt.Errorf("failed to delete CSI driver operator %s: %w", ctrl.operatorConfig.ConditionPrefix, err)
+	}
+
+	_, _, err := v1helpers.UpdateStatus(ctx, dsrc.commonClients.OperatorClient, removeConditionsFn(ctrl.operatorConfig.ConditionPrefix+csiDriverControllerName))
+	return err
+}
+
+// removeConditionsFn removes conditions of all controllers of a stopped CSI
+// driver operator, so e.g. its Degraded condition does not stay forever.
+// All the controllers use <ConditionPrefix>CSIDriverOperator prefix,
+// ConditionPrefix alone would match also other controllers, e.g. "VSphere"
+// and "VSphereProblemDetector".
+func removeConditionsFn(prefix string) v1helpers.UpdateStatusFunc {
+	return func(oldStatus *operatorapi.OperatorStatus) error {
+		var conditions []operatorapi.OperatorCondition
+		for _, cnd := range oldStatus.Conditions {
+			if strings.HasPrefix(cnd.Type, prefix) {
+				continue
+			}
+			conditions = append(conditions, cnd)
+		}
+		oldStatus.Conditions = conditions
+		return nil
+	}
+}
+
+// RunningDrivers returns configs of all CSI driver operators that have been started.
+func (dsrc *driverStarterCommon) RunningDrivers() ([]csioperatorclient.CSIOperatorConfig, bool) {
+	dsrc.runningLock.RLock()
+	defer dsrc.runningLock.RUnlock()
+
+	var configs []csioperatorclient.CSIOperatorConfig
+	for i := range dsrc.controllers {
+		if dsrc.controllers[i].running {
+			configs = append(configs, dsrc.controllers[i].operatorConfig)
+		}
+	}
+	return configs, dsrc.synced
+}
+
 func NewStandaloneDriverStarter(
 	clients *csoclients.Clients,
 	featureGates featuregates.FeatureGate,
@@ -310,6 +487,18 @@ func (s *standAloneDriverStarter) addExtraControllersToManager(manager manager.C
 	}
 }
 
+func (s *standAloneDriverStarter) deleteCSIDriverOperator(ctx context.Context, cfg csioperatorclient.CSIOperatorConfig) error {
+	clients := s.commonClients
+	if err := deleteOperatorDeployment(ctx, clients.KubeClient, s.eventRecorder, assets.ReadFile, cfg.DeploymentAsset); err != nil {
+		return err
+	}
+	files := append([]string{}, cfg.StaticAssets...)
+	if cfg.ServiceMonitorAsset != "" {
+		files = append(files, cfg.ServiceMonitorAsset)
+	}
+	return deleteAssets(ctx, clients.KubeClient, clients.DynamicClient, s.eventRecorder, assets.ReadFile, files)
+}
+
 func NewHypershiftDriverStarter(
 	clients *csoclients.Clients,
 	mgmtClients *csoclients.Clients,
@@ -335,16 +524,30 @@ func NewHypershiftDriverStarter(
 func (h *hypershiftDriverStarter) addExtraControllersToManager(manager manager.ControllerManager, cfg csioperatorclient.CSIOperatorConfig) {
 	mgmtStaticResourceClient := resourceapply.NewKubeClientHolder(h.mgmtClient.KubeClient).WithDynamicClient(h.mgmtClient.DynamicClient)
 	namespacedAssetFunc := namespaceReplacer(assets.ReadFile, "${CONTROLPLANE_NAMESPACE}", h.controllerNamespace)
+	mgmtNetworkPolicyAssets, mgmtStaticAssets := networkpolicy.SplitAssets(namespacedAssetFunc, cfg.MgmtStaticAssets)
 
 	mgmtStaticResourceController := staticresourcecontroller.NewStaticResourceController(
 		cfg.ConditionPrefix+"CSIDriverOperatorMgmtStaticController",
-		namespacedAssetFunc, cfg.MgmtStaticAssets, mgmtStaticResourceClient, h.commonClients.OperatorClient, h.eventRecorder).
+		namespacedAssetFunc, mgmtStaticAssets, mgmtStaticResourceClient, h.commonClients.OperatorClient, h.eventRecorder).
 		AddKubeInformers(h.mgmtClient.KubeInformers).
 		AddRESTMapper(h.mgmtClient.RestMapper).
 		AddCategoryExpander(h.mgmtClient.CategoryExpander)
 
 	manager = manager.WithController(mgmtStaticResourceController, 1)
 
+	if len(mgmtNetworkPolicyAssets) > 0 {
+		manager = manager.WithController(networkpolicy.NewNetworkPolicyController(
+			cfg.ConditionPrefix+"CSIDriverOperatorMgmtNetworkPolicyController",
+			namespacedAssetFunc,
+			mgmtNetworkPolicyAssets,
+			h.mgmtClient.KubeClient,
+			h.mgmtClient.KubeInformers,
+			h.commonClients.OperatorClient,
+			h.eventRecorder,
+			h.resyncInterval,
+		), 1)
+	}
+
 	manager.WithController(NewHyperShiftControllerDeployment(
 		h.mgmtClient,
 		h.commonClients,
@@ -355,6 +558,32 @@ func (h *hypershiftDriverStarter) addExtraControllersToManager(manager manager.C
 		h.eventRecorder,
 		h.resyncInterval,
 	), 1)
+
+	if cfg.MgmtServiceMonitorAsset != "" {
+		manager.WithController(NewHyperShiftMonitoringController(
+			h.mgmtClient,
+			h.commonClients,
+			h.controllerNamespace,
+			cfg,
+			h.eventRecorder,
+			h.resyncInterval,
+		), 1)
+	}
+}
+
+func (h *hypershiftDriverStarter) deleteCSIDriverOperator(ctx context.Context, cfg csioperatorclient.CSIOperatorConfig) error {
+	namespacedAssetFunc := namespaceReplacer(assets.ReadFile, "${CONTROLPLANE_NAMESPACE}", h.controllerNamespace)
+	if err := deleteOperatorDeployment(ctx, h.mgmtClient.KubeClient, h.eventRecorder, namespacedAssetFunc, cfg.DeploymentAsset); err != nil {
+		return err
+	}
+	mgmtFiles := append([]string{}, cfg.MgmtStaticAssets...)
+	if cfg.MgmtServiceMonitorAsset != "" {
+		mgmtFiles = append(mgmtFiles, cfg.MgmtServiceMonitorAsset)
+	}
+	if err := deleteAssets(ctx, h.mgmtClient.KubeClient, h.mgmtClient.DynamicClient, h.eventRecorder, namespacedAssetFunc, mgmtFiles); err != nil {
+		return err
+	}
+	return deleteAssets(ctx, h.commonClients.KubeClient, h.commonClients.DynamicClient, h.eventRecorder, assets.ReadFile, cfg.StaticAssets)
 }
 
 func namespaceReplacer(assetFunc resourceapply.AssetFunc, placeholder, namespace string) resourceapply.AssetFunc {
@@ -368,8 +597,19 @@ func namespaceReplacer(assetFunc resourceapply.AssetFunc, placeholder, namespace
 	}
 }
 
-// shouldRunController returns true, if given CSI driver controller should run.
-func shouldRunController(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg featuregates.FeatureGate, csiDriver *storagev1.CSIDriver, isInstalled bool) (bool, error) {
+// Reasons of shouldRunController decisions, reported in cso_csi_driver_operator_enabled metric.
+const (
+	runReasonWrongPlatform            = "WrongPlatform"
+	runReasonStatusFilter             = "StatusFilter"
+	runReasonGA                       = "GA"
+	runReasonFeatureGateDisabled      = "FeatureGateDisabled"
+	runReasonFeatureGateEnabled       = "FeatureGateEnabled"
+	runReasonUnsupportedDriverRunning = "UnsupportedDriverRunning"
+)
+
+// shouldRunController returns true, if given CSI driver controller should run,
+// together with the reason of the decision.
+func shouldRunController(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg featuregates.FeatureGate, csiDriver *storagev1.CSIDriver, isInstalled bool) (bool, string, error) {
 	// Check the correct platform first, it will filter out most CSI driver operators
 	var platform configv1.PlatformType
 	if infrastructure.Status.PlatformStatus != nil {
@@ -377,34 +617,40 @@ func shouldRunController(cfg csioperatorclient.CSIOperatorConfig, infrastructure
 	}
 	if cfg.Platform != csioperatorclient.AllPlatforms && cfg.Platform != platform {
 		klog.V(5).Infof("Not starting %s: wrong platform %s", cfg.CSIDriverName, platform)
-		return false, nil
+		return false, runReasonWrongPlatform, nil
 	}
 
 	if cfg.StatusFilter != nil && !cfg.StatusFilter(&infrastructure.Status, isInstalled) {
 		klog.V(5).Infof("Not starting %s: StatusFilter returned false", cfg.CSIDriverName)
-		return false, nil
+		return false, runReasonStatusFilter, nil
 	}
 
 	if cfg.RequireFeatureGate == "" {
 		// This is GA / always enabled operator, always run
 		klog.V(5).Infof("Starting %s: it's GA", cfg.CSIDriverName)
-		return true, nil
+		return true, runReasonGA, nil
 	}
 
-	knownFeatures := sets.New[configv1.FeatureGateName](fg.KnownFeatures()...)
-	if !knownFeatures.Has(cfg.RequireFeatureGate) || !fg.Enabled(cfg.RequireFeatureGate) {
+	if !isFeatureGateEnabled(cfg, fg) {
 		klog.V(4).Infof("Not starting %s: feature %s is not enabled", cfg.CSIDriverName, cfg.RequireFeatureGate)
-		return false, nil
+		return false, runReasonFeatureGateDisabled, nil
 	}
 
 	if isUnsupportedCSIDriverRunning(cfg, csiDriver) {
 		// Some other version of the CSI driver is running, degrade the whole cluster
-		return false, fmt.Errorf("detected CSI driver %s that is not provided by OpenShift - please remove it before enabling the OpenShift one", cfg.CSIDriverName)
+		return false, runReasonUnsupportedDriverRunning, fmt.Errorf("detected CSI driver %s that is not provided by OpenShift - please remove it before enabling the OpenShift one", cfg.CSIDriverName)
 	}
 
 	// Tech preview operator and tech preview is enabled
 	klog.V(5).Infof("Starting %s: feature %s is enabled", cfg.CSIDriverName, cfg.RequireFeatureGate)
-	return true, nil
+	return true, runReasonFeatureGateEnabled, nil
+}
+
+// isFeatureGateEnabled returns true if the feature gate required by the CSI
+// driver operator is known and enabled.
+func isFeatureGateEnabled(cfg csioperatorclient.CSIOperatorConfig, fg featuregates.FeatureGate) bool {
+	knownFeatures := sets.New[configv1.FeatureGateName](fg.KnownFeatures()...)
+	return knownFeatures.Has(cfg.RequireFeatureGate) && fg.Enabled(cfg.RequireFeatureGate)
 }
 
 func RelatedObjectFunc() func() (isset bool, objs []configv1.ObjectReference) {
@@ -9,18 +9,24 @@ import (
 
 	v1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/api/features"
+	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/status"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type RunControllerTest struct {
@@ -253,7 +259,7 @@ func TestShouldRunController(t *testing.T) {
 
 			infra := NewTestInfra().WithStatus(test.platformStatus)
 
-			res, err := shouldRunController(test.config, infra, test.featureGate, test.csiDriver, test.isInstalled)
+			res, _, err := shouldRunController(test.config, infra, test.featureGate, test.csiDriver, test.isInstalled)
 			if res != test.expectRun {
 				t.Errorf("Expected run %t, got %t", test.expectRun, res)
 			}
@@ -433,3 +439,112 @@ func TestStandAloneStarter(t *testing.T) {
 	assert.Equal(t, csiController.operatorConfig.DeploymentAsset, "csidriveroperators/aws-ebs/standalone/generated/apps_v1_deployment_aws-ebs-csi-driver-operator.yaml")
 	assert.NotEmpty(t, csiController.operatorConfig.StaticAssets)
 }
+
+func TestFeatureGateChange(t *testing.T) {
+	initialObjects := &csoclients.FakeTestObjects{}
+	initialObjects.OperatorObjects = append(initialObjects.OperatorObjects, csoclients.GetCR())
+	initialObjects.ConfigObjects = append(initialObjects.ConfigObjects, getInfrastructure(v1.AWSPlatformType))
+	initialObjects.ConfigObjects = append(initialObjects.ConfigObjects, getDefaultFeatureGate())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	clients := csoclients.NewFakeClients(initialObjects)
+	clients.OperatorInformers.Operator().V1().Storages().Informer().GetStore().Add(csoclients.GetCR())
+	clients.ConfigInformers.Config().V1().Infrastructures().Informer().GetStore().Add(getInfrastructure(v1.AWSPlatformType))
+	csoclients.StartInformers(clients, ctx.Done())
+	csoclients.WaitForSync(clients, ctx.Done())
+
+	disabled := featuregates.NewFeatureGate(nil, []v1.FeatureGateName{features.FeatureGateExample})
+	enabled := featuregates.NewFeatureGate([]v1.FeatureGateName{features.FeatureGateExample}, nil)
+
+	awsConfig := csioperatorclient.GetAWSEBSCSIOperatorConfig(false)
+	awsConfig.RequireFeatureGate = features.FeatureGateExample
+	// The fake RESTMapper does not know the static assets.
+	awsConfig.StaticAssets = nil
+	recorder := events.NewInMemoryRecorder(csiDriverControllerName)
+	_, starter := NewStandaloneDriverStarter(clients,
+		disabled,
+		20*time.Minute,
+		status.NewVersionGetter(),
+		"",
+		recorder,
+		[]csioperatorclient.CSIOperatorConfig{awsConfig})
+
+	expectRunning := func(expected bool) {
+		t.Helper()
+		if err := starter.sync(ctx, starter.syncCtx); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		drivers, synced := starter.RunningDrivers()
+		if !synced {
+			t.Errorf("expected synced drivers")
+		}
+		if running := len(drivers) == 1; running != expected {
+			t.Errorf("expected running %t, got %t", expected, running)
+		}
+	}
+
+	expectRunning(false)
+	starter.SetFeatureGates(enabled)
+	expectRunning(true)
+	_, _, err := v1helpers.UpdateStatus(ctx, clients.OperatorClient,
+		v1helpers.UpdateConditionFn(operatorapi.OperatorCondition{Type: "AWSEBSCSIDriverOperatorDeploymentDegraded", Status: operatorapi.ConditionTrue}),
+		v1helpers.UpdateConditionFn(operatorapi.OperatorCondition{Type: "AWSEBSOtherControllerDegraded", Status: operatorapi.ConditionFalse}),
+	)
+	if err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	// OperatorClient reads the status from an informer.
+	hasCondition := func(cndType string) func(context.Context) (bool, error) {
+		return func(context.Context) (bool, error) {
+			_, status, _, err := clients.OperatorClient.GetOperatorState()
+			return err == nil && v1helpers.FindOperatorCondition(status.Conditions, cndType) != nil, err
+		}
+	}
+	if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, wait.ForeverTestTimeout, true, hasCondition("AWSEBSCSIDriverOperatorDeploymentDegraded")); err != nil {
+		t.Fatalf("failed to wait for the condition: %v", err)
+	}
+	// The Deployment of the CSI driver operator, as created by its DeploymentController.
+	operatorDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "aws-ebs-csi-driver-operator", Namespace: csoclients.CSIOperatorNamespace}}
+	if _, err := clients.KubeClient.AppsV1().Deployments(operatorDeployment.Namespace).Create(ctx, operatorDeployment, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("failed to create Deployment: %v", err)
+	}
+	starter.SetFeatureGates(disabled)
+	expectRunning(false)
+	_, err = clients.KubeClient.AppsV1().Deployments(operatorDeployment.Namespace).Get(ctx, operatorDeployment.Name, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected Deployment of the stopped CSI driver operator to be deleted, got error %v", err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, wait.ForeverTestTimeout, true, func(ctx context.Context) (bool, error) {
+		found, err := hasCondition("AWSEBSCSIDriverOperatorDeploymentDegraded")(ctx)
+		return !found, err
+	})
+	if err != nil {
+		t.Errorf("expected conditions of the stopped CSI driver operator to be removed: %v", err)
+	}
+	if found, _ := hasCondition("AWSEBSOtherControllerDegraded")(ctx); !found {
+		t.Errorf("expected conditions of other controllers to be kept")
+	}
+	if starter.controllers[0].cancel != nil || starter.controllers[0].done != nil {
+		t.Errorf("expected the stopped ControllerManager to be cleared")
+	}
+	starter.SetFeatureGates(enabled)
+	expectRunning(true)
+
+	var messages []string
+	for _, e := range recorder.Events() {
+		if e.Reason == "CSIDriverOperatorsChanged" {
+			messages = append(messages, e.Message)
+		}
+	}
+	expectedMessages := []string{
+		"CSI driver operators changed after FeatureGate change: started: [AWSEBS], stopped: []",
+		"CSI driver operators changed after FeatureGate change: started: [], stopped: [AWSEBS]",
+		"CSI driver operators changed after FeatureGate change: started: [AWSEBS], stopped: []",
+	}
+	assert.Equal(t, expectedMessages, messages)
+	if len(relatedObjects) == 0 || len(relatedObjects) != len(sets.New(relatedObjects...)) {
+		t.Errorf("expected unique related objects, got %v", relatedObjects)
+	}
+}
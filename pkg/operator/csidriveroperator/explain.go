@@ -0,0 +1,70 @@
+package csidriveroperator
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+// DriverExplanation is a decision of the driver starter about a CSI driver
+// operator, with a human readable reason.
+type DriverExplanation struct {
+	Driver             string `json:"driver"`
+	CSIDriverName      string `json:"csiDriverName"`
+	Platform           string `json:"platform"`
+	RequireFeatureGate string `json:"requireFeatureGate,omitempty"`
+	// Installed is true when ClusterCSIDriver of the driver exists.
+	Installed bool `json:"installed"`
+	Run       bool `json:"run"`
+	// Reason is the same reason as reported in cso_csi_driver_operator_enabled metric.
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// ExplainDriverOperator evaluates whether the driver starter would run a CSI
+// driver operator in a cluster with the given objects and explains why.
+// csiDriver is nil when the CSIDriver does not exist.
+func ExplainDriverOperator(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg featuregates.FeatureGate, csiDriver *storagev1.CSIDriver, isInstalled bool) DriverExplanation {
+	shouldRun, reason, err := shouldRunController(cfg, infrastructure, fg, csiDriver, isInstalled)
+	explanation := DriverExplanation{
+		Driver:             cfg.ConditionPrefix,
+		CSIDriverName:      cfg.CSIDriverName,
+		Platform:           string(cfg.Platform),
+		RequireFeatureGate: string(cfg.RequireFeatureGate),
+		Installed:          isInstalled,
+		Run:                shouldRun,
+		Reason:             reason,
+	}
+
+	var platform configv1.PlatformType
+	if infrastructure.Status.PlatformStatus != nil {
+		platform = infrastructure.Status.PlatformStatus.Type
+	}
+	switch reason {
+	case runReasonWrongPlatform:
+		explanation.Message = fmt.Sprintf("The driver runs on platform %s, the cluster platform is %q", cfg.Platform, platform)
+	case runReasonStatusFilter:
+		explanation.Message = fmt.Sprintf("Infrastructure status does not allow the driver: %s", cfg.StatusFilterDescription)
+		if cfg.StatusFilterDescription == "" {
+			explanation.Message = "Infrastructure status does not allow the driver"
+		}
+	case runReasonGA:
+		explanation.Message = "The driver is generally available on the platform"
+	case runReasonFeatureGateDisabled:
+		explanation.Message = fmt.Sprintf("Feature gate %s is not enabled", cfg.RequireFeatureGate)
+	case runReasonFeatureGateEnabled:
+		explanation.Message = fmt.Sprintf("Feature gate %s is enabled", cfg.RequireFeatureGate)
+	case runReasonUnsupportedDriverRunning:
+		explanation.Message = err.Error()
+	}
+	return explanation
+}
+
+// IsUnsupportedCSIDriver returns true when the CSIDriver of a CSI driver
+// operator exists and it was not installed by OpenShift.
+func IsUnsupportedCSIDriver(cfg csioperatorclient.CSIOperatorConfig, csiDriver *storagev1.CSIDriver) bool {
+	return isUnsupportedCSIDriverRunning(cfg, csiDriver)
+}
@@ -0,0 +1,129 @@
+package csidriveroperator
+
+import (
+	"testing"
+
+	v1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExplainDriverOperator(t *testing.T) {
+	const gate v1.FeatureGateName = "TestDriver"
+	azureStack := &v1.Infrastructure{
+		Status: v1.InfrastructureStatus{
+			PlatformStatus: &v1.PlatformStatus{
+				Type:  v1.AzurePlatformType,
+				Azure: &v1.AzurePlatformStatus{CloudName: v1.AzureStackCloud},
+			},
+		},
+	}
+	aws := &v1.Infrastructure{
+		Status: v1.InfrastructureStatus{PlatformStatus: &v1.PlatformStatus{Type: v1.AWSPlatformType}},
+	}
+	techPreviewConfig := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:      "test.csi.openshift.io",
+		ConditionPrefix:    "Test",
+		Platform:           v1.AWSPlatformType,
+		RequireFeatureGate: gate,
+	}
+	foreignDriver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "test.csi.openshift.io"}}
+
+	tests := []struct {
+		name           string
+		config         csioperatorclient.CSIOperatorConfig
+		infrastructure *v1.Infrastructure
+		featureGates   featuregates.FeatureGate
+		csiDriver      *storagev1.CSIDriver
+		isInstalled    bool
+		expected       DriverExplanation
+	}{
+		{
+			name:           "wrong platform",
+			config:         csioperatorclient.GetAWSEBSCSIOperatorConfig(false),
+			infrastructure: azureStack,
+			featureGates:   featuregates.NewFeatureGate(nil, nil),
+			expected: DriverExplanation{
+				Run:     false,
+				Reason:  runReasonWrongPlatform,
+				Message: `The driver runs on platform AWS, the cluster platform is "Azure"`,
+			},
+		},
+		{
+			name:           "Azure File on Azure Stack Hub",
+			config:         csioperatorclient.GetAzureFileCSIOperatorConfig(false),
+			infrastructure: azureStack,
+			featureGates:   featuregates.NewFeatureGate(nil, nil),
+			expected: DriverExplanation{
+				Run:     false,
+				Reason:  runReasonStatusFilter,
+				Message: "Infrastructure status does not allow the driver: Azure File is not supported on Azure Stack Hub",
+			},
+		},
+		{
+			name:           "installed Azure File on Azure Stack Hub",
+			config:         csioperatorclient.GetAzureFileCSIOperatorConfig(false),
+			infrastructure: azureStack,
+			featureGates:   featuregates.NewFeatureGate(nil, nil),
+			isInstalled:    true,
+			expected: DriverExplanation{
+				Installed: true,
+				Run:       true,
+				Reason:    runReasonGA,
+				Message:   "The driver is generally available on the platform",
+			},
+		},
+		{
+			name:           "feature gate disabled",
+			config:         techPreviewConfig,
+			infrastructure: aws,
+			featureGates:   featuregates.NewFeatureGate(nil, []v1.FeatureGateName{gate}),
+			expected: DriverExplanation{
+				Run:     false,
+				Reason:  runReasonFeatureGateDisabled,
+				Message: "Feature gate TestDriver is not enabled",
+			},
+		},
+		{
+			name:           "feature gate enabled",
+			config:         techPreviewConfig,
+			infrastructure: aws,
+			featureGates:   featuregates.NewFeatureGate([]v1.FeatureGateName{gate}, nil),
+			expected: DriverExplanation{
+				Run:     true,
+				Reason:  runReasonFeatureGateEnabled,
+				Message: "Feature gate TestDriver is enabled",
+			},
+		},
+		{
+			name:           "unsupported driver running",
+			config:         techPreviewConfig,
+			infrastructure: aws,
+			featureGates:   featuregates.NewFeatureGate([]v1.FeatureGateName{gate}, nil),
+			csiDriver:      foreignDriver,
+			expected: DriverExplanation{
+				Run:     false,
+				Reason:  runReasonUnsupportedDriverRunning,
+				Message: "detected CSI driver test.csi.openshift.io that is not provided by OpenShift - please remove it before enabling the OpenShift one",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			explanation := ExplainDriverOperator(test.config, test.infrastructure, test.featureGates, test.csiDriver, test.isInstalled)
+			if explanation.Driver != test.config.ConditionPrefix || explanation.CSIDriverName != test.config.CSIDriverName {
+				t.Errorf("expected driver %s (%s), got %s (%s)", test.config.ConditionPrefix, test.config.CSIDriverName, explanation.Driver, explanation.CSIDriverName)
+			}
+			if explanation.Installed != test.expected.Installed || explanation.Run != test.expected.Run || explanation.Reason != test.expected.Reason {
+				t.Errorf("expected installed=%t run=%t (%s), got installed=%t run=%t (%s)",
+					test.expected.Installed, test.expected.Run, test.expected.Reason, explanation.Installed, explanation.Run, explanation.Reason)
+			}
+			if explanation.Message != test.expected.Message {
+				t.Errorf("expected message %q, got %q", test.expected.Message, explanation.Message)
+			}
+		})
+	}
+}
@@ -7,15 +7,18 @@ import (
 	"strings"
 	"time"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
 	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/status"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -26,9 +29,11 @@ import (
 
 var _ factory.Controller = &HyperShiftDeploymentController{}
 
-var (
-	envHyperShiftImage = os.Getenv("HYPERSHIFT_IMAGE")
+const (
+	envHyperShiftImage = "HYPERSHIFT_IMAGE"
+)
 
+var (
 	hostedControlPlaneGVR = schema.GroupVersionResource{
 		Group:    "hypershift.openshift.io",
 		Version:  "v1beta1",
@@ -100,36 +105,71 @@ func (c *HyperShiftDeploymentController) Sync(ctx context.Context, syncCtx facto
 		return nil
 	}
 
-	replacers := []*strings.Replacer{sidecarReplacer}
-	// Replace images
-	if c.csiOperatorConfig.ImageReplacer != nil {
-		replacers = append(replacers, c.csiOperatorConfig.ImageReplacer)
+	nodeSelector, err := c.getHostedControlPlaneNodeSelector()
+	if err != nil {
+		return err
 	}
 
-	namespaceReplacer := strings.NewReplacer("${CONTROLPLANE_NAMESPACE}", c.controlNamespace)
-	hyperShiftImageReplacer := strings.NewReplacer("${HYPERSHIFT_IMAGE}", envHyperShiftImage)
-	replacers = append(replacers, namespaceReplacer)
-	replacers = append(replacers, hyperShiftImageReplacer)
+	tolerations, err := c.getHostedControlPlaneCustomTolerations()
+	if err != nil {
+		return err
+	}
 
-	nodeSelector, err := c.getHostedControlPlaneNodeSelector()
+	requiredCopy, err := getRequiredHyperShiftDeployment(c.csiOperatorConfig, opSpec, c.controlNamespace, nodeSelector, tolerations)
 	if err != nil {
 		return err
 	}
 
-	tolerations, err := c.getHostedControlPlaneCustomTolerations()
+	if err := applyTLSProfileConfigMap(ctx, c.mgmtClient.KubeClient.CoreV1(), c.eventRecorder, requiredCopy, opSpec); err != nil {
+		return err
+	}
+
+	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(requiredCopy, opStatus.Generations)
+	deployment, _, err := resourceapply.ApplyDeployment(ctx, c.mgmtClient.KubeClient.AppsV1(), c.eventRecorder, requiredCopy, lastGeneration)
+	if err != nil {
+		return err
+	}
+	err = c.postSync(ctx, deployment)
 	if err != nil {
 		return err
 	}
 
-	required, err := csoutils.GetRequiredDeployment(c.csiOperatorConfig.DeploymentAsset, opSpec, nodeSelector, tolerations, replacers...)
+	return checkDeploymentHealth(ctx, c.mgmtClient.KubeClient.AppsV1(), deployment)
+}
+
+// getRequiredHyperShiftDeployment returns Deployment of a CSI driver operator
+// in the HyperShift control plane namespace.
+func getRequiredHyperShiftDeployment(
+	cfg csioperatorclient.CSIOperatorConfig,
+	opSpec *operatorv1.OperatorSpec,
+	controlNamespace string,
+	nodeSelector map[string]string,
+	tolerations []corev1.Toleration) (*appsv1.Deployment, error) {
+
+	replacers := []*strings.Replacer{getSidecarReplacer()}
+	// Replace images
+	if cfg.ImageReplacer != nil {
+		replacers = append(replacers, cfg.ImageReplacer)
+	}
+
+	namespaceReplacer := strings.NewReplacer("${CONTROLPLANE_NAMESPACE}", controlNamespace)
+	hyperShiftImageReplacer := strings.NewReplacer("${HYPERSHIFT_IMAGE}", os.Getenv(envHyperShiftImage))
+	replacers = append(replacers, namespaceReplacer)
+	replacers = append(replacers, hyperShiftImageReplacer)
+
+	required, err := csoutils.GetRequiredDeployment(cfg.DeploymentAsset, opSpec, nodeSelector, tolerations, replacers...)
 	if err != nil {
-		return fmt.Errorf("failed to generate required Deployment: %s", err)
+		return nil, fmt.Errorf("failed to generate required Deployment: %s", err)
 	}
 
 	requiredCopy := required.DeepCopy()
 	err = util.InjectObservedProxyInDeploymentContainers(requiredCopy, opSpec)
 	if err != nil {
-		return fmt.Errorf("failed to inject proxy data into deployment: %w", err)
+		return nil, fmt.Errorf("failed to inject proxy data into deployment: %w", err)
+	}
+	err = util.InjectObservedTLSProfileInDeploymentContainers(requiredCopy, opSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject TLS security profile into deployment: %w", err)
 	}
 
 	// The existence of the environment variable, ARO_HCP_SECRET_PROVIDER_CLASS_FOR_FILE, means this is an ARO HCP
@@ -152,23 +192,12 @@ func (c *HyperShiftDeploymentController) Sync(ctx context.Context, syncCtx facto
 	if len(envVars) > 0 {
 		requiredCopy.Spec.Template.Spec.Containers[0].Env = append(requiredCopy.Spec.Template.Spec.Containers[0].Env, envVars...)
 	}
-
-	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(requiredCopy, opStatus.Generations)
-	deployment, _, err := resourceapply.ApplyDeployment(ctx, c.mgmtClient.KubeClient.AppsV1(), c.eventRecorder, requiredCopy, lastGeneration)
-	if err != nil {
-		return err
-	}
-	err = c.postSync(ctx, deployment)
-	if err != nil {
-		return err
-	}
-
-	return checkDeploymentHealth(ctx, c.mgmtClient.KubeClient.AppsV1(), deployment)
+	return requiredCopy, nil
 }
 
 func (c *HyperShiftDeploymentController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.Name(), c.eventRecorder)
+	ctrl := c.factory.WithSync(operatormetrics.InstrumentSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
@@ -0,0 +1,106 @@
+package csidriveroperator
+
+import (
+	"reflect"
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+)
+
+func TestGetRequiredHyperShiftDeployment(t *testing.T) {
+	t.Setenv(envHyperShiftImage, "quay.io/test/hypershift:latest")
+	const controlNamespace = "clusters-test"
+	nodeSelector := map[string]string{"hypershift.openshift.io/control-plane": "true"}
+	tolerations := []corev1.Toleration{{Key: "hypershift.openshift.io/control-plane", Operator: corev1.TolerationOpExists}}
+
+	tests := []struct {
+		name           string
+		observedConfig string
+		expectTLS      bool
+	}{
+		{
+			name:           "no TLS profile",
+			observedConfig: `{"targetconfig":{"proxy":{"HTTPS_PROXY":"https://proxy.example.com"}}}`,
+		},
+		{
+			name:           "TLS profile",
+			observedConfig: `{"targetconfig":{"servingInfo":{"minTLSVersion":"VersionTLS13","cipherSuites":["TLS_AES_256_GCM_SHA384"]}}}`,
+			expectTLS:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opSpec := &operatorapi.OperatorSpec{
+				ManagementState: operatorapi.Managed,
+				ObservedConfig:  runtime.RawExtension{Raw: []byte(test.observedConfig)},
+			}
+			cfg := csioperatorclient.GetAWSEBSCSIOperatorConfig(true)
+
+			deployment, err := getRequiredHyperShiftDeployment(cfg, opSpec, controlNamespace, nodeSelector, tolerations)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if deployment.Namespace != controlNamespace {
+				t.Errorf("expected Deployment in namespace %s, got %s", controlNamespace, deployment.Namespace)
+			}
+			podSpec := deployment.Spec.Template.Spec
+			if !reflect.DeepEqual(podSpec.NodeSelector, nodeSelector) {
+				t.Errorf("expected node selector %v, got %v", nodeSelector, podSpec.NodeSelector)
+			}
+			for _, toleration := range tolerations {
+				found := false
+				for _, tol := range podSpec.Tolerations {
+					if reflect.DeepEqual(tol, toleration) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected toleration %+v, got %+v", toleration, podSpec.Tolerations)
+				}
+			}
+
+			container := podSpec.Containers[0]
+			foundConfigArg := false
+			for _, arg := range container.Args {
+				if arg == "--config=/var/run/configmaps/tls-profile-config/config.yaml" {
+					foundConfigArg = true
+				}
+			}
+			if foundConfigArg != test.expectTLS {
+				t.Errorf("expected --config arg %t, got %v", test.expectTLS, container.Args)
+			}
+			foundVolume := false
+			for _, volume := range podSpec.Volumes {
+				if volume.ConfigMap != nil && volume.ConfigMap.Name == deployment.Name+"-tls-profile" {
+					foundVolume = true
+				}
+			}
+			if foundVolume != test.expectTLS {
+				t.Errorf("expected TLS profile volume %t, got %+v", test.expectTLS, podSpec.Volumes)
+			}
+			foundMount := false
+			for _, mount := range container.VolumeMounts {
+				if mount.Name == "tls-profile-config" {
+					foundMount = true
+				}
+			}
+			if foundMount != test.expectTLS {
+				t.Errorf("expected TLS profile volume mount %t, got %+v", test.expectTLS, container.VolumeMounts)
+			}
+			if _, found := deployment.Spec.Template.Annotations["storage.openshift.io/tls-profile-hash"]; found != test.expectTLS {
+				t.Errorf("expected TLS profile hash annotation %t, got %v", test.expectTLS, deployment.Spec.Template.Annotations)
+			}
+			for _, env := range container.Env {
+				if env.Name == "TLS_MIN_VERSION" || env.Name == "TLS_CIPHER_SUITES" {
+					t.Errorf("unexpected env. var %s", env.Name)
+				}
+			}
+		})
+	}
+}
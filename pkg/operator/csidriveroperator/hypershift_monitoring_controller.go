@@ -0,0 +1,146 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+var _ factory.Controller = &HyperShiftMonitoringController{}
+
+const (
+	hyperShiftMonitoringControllerName = "CSIDriverOperatorMgmtMonitoringController"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, so the
+	// condition is not aggregated to the ClusterOperator: a management cluster
+	// without monitoring stack is a valid configuration.
+	monitoringCRDsPresentConditionSuffix = "CRDsPresent"
+)
+
+var serviceMonitorGK = schema.GroupKind{Group: "monitoring.coreos.com", Kind: "ServiceMonitor"}
+
+// This HyperShiftMonitoringController installs and syncs ServiceMonitor of the
+// CSI driver control plane components in the management cluster. The ServiceMonitor
+// is created only when the monitoring CRDs are present in the management cluster.
+// It produces following Conditions:
+// <CSI driver name>CSIDriverOperatorMgmtMonitoringControllerCRDsPresent
+// <CSI driver name>CSIDriverOperatorMgmtMonitoringControllerDegraded on error
+type HyperShiftMonitoringController struct {
+	name                string
+	operatorClient      v1helpers.OperatorClient
+	mgmtClient          *csoclients.Clients
+	controlNamespace    string
+	serviceMonitorAsset string
+	eventRecorder       events.Recorder
+	factory             *factory.Factory
+}
+
+func NewHyperShiftMonitoringController(
+	mgmtClient *csoclients.Clients,
+	guestClient *csoclients.Clients,
+	controlNamespace string,
+	csiOperatorConfig csioperatorclient.CSIOperatorConfig,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration,
+) factory.Controller {
+	f := factory.New()
+	f = f.ResyncEvery(resyncInterval)
+	f = f.WithSyncDegradedOnError(guestClient.OperatorClient)
+	// Necessary to do initial Sync after the controller starts.
+	f = f.WithPostStartHooks(initalSync)
+	// The actual event handlers are added in Run(), see CSIDriverOperatorCRController.
+	// ServiceMonitor informer is not used on purpose, the CRD may not exist in the
+	// management cluster.
+	f = f.WithInformers(guestClient.OperatorClient.Informer())
+
+	name := csiOperatorConfig.ConditionPrefix + hyperShiftMonitoringControllerName
+	c := &HyperShiftMonitoringController{
+		name:                name,
+		operatorClient:      guestClient.OperatorClient,
+		mgmtClient:          mgmtClient,
+		controlNamespace:    controlNamespace,
+		serviceMonitorAsset: csiOperatorConfig.MgmtServiceMonitorAsset,
+		eventRecorder:       eventRecorder.WithComponentSuffix(name),
+		factory:             f,
+	}
+	return c
+}
+
+func (c *HyperShiftMonitoringController) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("HyperShiftMonitoringController sync started")
+	defer klog.V(4).Infof("HyperShiftMonitoringController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	crdsPresent, err := c.monitoringCRDsPresent()
+	if err != nil {
+		return err
+	}
+
+	crdCondition := operatorapi.OperatorCondition{
+		Type:   c.name + monitoringCRDsPresentConditionSuffix,
+		Status: operatorapi.ConditionTrue,
+	}
+	if !crdsPresent {
+		crdCondition.Status = operatorapi.ConditionFalse
+		crdCondition.Reason = "NoMonitoringCRDs"
+		crdCondition.Message = fmt.Sprintf("ServiceMonitor CRD is not installed in the management cluster, metrics of CSI driver control plane in namespace %s are not collected", c.controlNamespace)
+	} else {
+		namespacedAssetFunc := namespaceReplacer(assets.ReadFile, "${CONTROLPLANE_NAMESPACE}", c.controlNamespace)
+		serviceMonitorBytes, err := namespacedAssetFunc(c.serviceMonitorAsset)
+		if err != nil {
+			return err
+		}
+		serviceMonitor := resourceread.ReadUnstructuredOrDie(serviceMonitorBytes)
+		if _, _, err := resourceapply.ApplyServiceMonitor(ctx, c.mgmtClient.DynamicClient, c.eventRecorder, serviceMonitor); err != nil {
+			return fmt.Errorf("failed to apply ServiceMonitor %s: %w", serviceMonitor.GetName(), err)
+		}
+	}
+
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(crdCondition))
+	return err
+}
+
+// monitoringCRDsPresent returns true if ServiceMonitor CRD is installed in the management cluster.
+func (c *HyperShiftMonitoringController) monitoringCRDsPresent() (bool, error) {
+	_, err := c.mgmtClient.RestMapper.RESTMapping(serviceMonitorGK, "v1")
+	if err == nil {
+		return true, nil
+	}
+	if meta.IsNoMatchError(err) {
+		// RESTMapper NoResourceMatch / NoKindMatch errors are cached. Reset the cache to get fresh results on the next sync.
+		c.mgmtClient.RestMapper.Reset()
+		operatormetrics.RESTMapperReset(c.name)
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *HyperShiftMonitoringController) Run(ctx context.Context, workers int) {
+	// This adds event handlers to informers.
+	ctrl := c.factory.WithSync(operatormetrics.InstrumentSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
+	ctrl.Run(ctx, workers)
+}
+
+func (c *HyperShiftMonitoringController) Name() string {
+	return c.name
+}
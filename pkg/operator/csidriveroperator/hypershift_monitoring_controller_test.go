@@ -0,0 +1,140 @@
+package csidriveroperator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var serviceMonitorGVR = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"}
+
+func TestHyperShiftMonitoringController(t *testing.T) {
+	const controlNamespace = "clusters-test"
+	const conditionType = "AWSEBSCSIDriverOperatorMgmtMonitoringControllerCRDsPresent"
+	// The cached discovery treats an empty API group list as an error and the
+	// RESTMapper would retry forever, so always serve the core group.
+	coreResources := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"},
+		},
+	}
+	serviceMonitorResources := []*metav1.APIResourceList{
+		coreResources,
+		{
+			GroupVersion: serviceMonitorGVR.GroupVersion().String(),
+			APIResources: []metav1.APIResource{
+				{Name: serviceMonitorGVR.Resource, Namespaced: true, Kind: "ServiceMonitor"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		mgmtResources        []*metav1.APIResourceList
+		expectedStatus       operatorapi.ConditionStatus
+		expectedReason       string
+		expectServiceMonitor bool
+	}{
+		{
+			name:                 "monitoring CRDs present",
+			mgmtResources:        serviceMonitorResources,
+			expectedStatus:       operatorapi.ConditionTrue,
+			expectServiceMonitor: true,
+		},
+		{
+			name:                 "monitoring CRDs absent",
+			mgmtResources:        []*metav1.APIResourceList{coreResources},
+			expectedStatus:       operatorapi.ConditionFalse,
+			expectedReason:       "NoMonitoringCRDs",
+			expectServiceMonitor: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			guestClients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			})
+			guestClients.OperatorInformers.Operator().V1().Storages().Informer().GetStore().Add(csoclients.GetCR())
+			csoclients.StartInformers(guestClients, ctx.Done())
+			csoclients.WaitForSync(guestClients, ctx.Done())
+
+			mgmtClients := csoclients.NewFakeMgmtClients(&csoclients.FakeTestObjects{})
+			discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: test.mgmtResources}}
+			mgmtClients.RestMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discovery))
+
+			recorder := events.NewInMemoryRecorder(hyperShiftMonitoringControllerName)
+			ctrl := NewHyperShiftMonitoringController(
+				mgmtClients,
+				guestClients,
+				controlNamespace,
+				csioperatorclient.GetAWSEBSCSIOperatorConfig(true),
+				recorder,
+				time.Minute,
+			).(*HyperShiftMonitoringController)
+
+			if err := ctrl.Sync(ctx, factory.NewSyncContext(ctrl.Name(), recorder)); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			// OperatorClient reads the status from an informer.
+			var condition *operatorapi.OperatorCondition
+			err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, wait.ForeverTestTimeout, true, func(context.Context) (bool, error) {
+				_, status, _, err := guestClients.OperatorClient.GetOperatorState()
+				if err != nil {
+					return false, err
+				}
+				condition = v1helpers.FindOperatorCondition(status.Conditions, conditionType)
+				return condition != nil, nil
+			})
+			if err != nil {
+				t.Fatalf("failed to wait for condition %s: %v", conditionType, err)
+			}
+			if condition.Status != test.expectedStatus {
+				t.Errorf("expected condition status %s, got %s", test.expectedStatus, condition.Status)
+			}
+			if condition.Reason != test.expectedReason {
+				t.Errorf("expected condition reason %q, got %q", test.expectedReason, condition.Reason)
+			}
+
+			serviceMonitor, err := mgmtClients.DynamicClient.Resource(serviceMonitorGVR).Namespace(controlNamespace).Get(ctx, "aws-ebs-csi-driver-controller-monitor", metav1.GetOptions{})
+			if !test.expectServiceMonitor {
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected no ServiceMonitor, got %v (error %v)", serviceMonitor, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected ServiceMonitor in namespace %s: %v", controlNamespace, err)
+			}
+			namespaces, _, err := unstructured.NestedStringSlice(serviceMonitor.Object, "spec", "namespaceSelector", "matchNames")
+			if err != nil {
+				t.Fatalf("failed to read ServiceMonitor namespaceSelector: %v", err)
+			}
+			if len(namespaces) != 1 || namespaces[0] != controlNamespace {
+				t.Errorf("expected ServiceMonitor to select namespace %s, got %v", controlNamespace, namespaces)
+			}
+		})
+	}
+}
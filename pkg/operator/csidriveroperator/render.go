@@ -0,0 +1,157 @@
+package csidriveroperator
+
+import (
+	"fmt"
+	"path"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderInput is the cluster state used to render CSI driver operators
+// without an API server.
+type RenderInput struct {
+	Infrastructure *configv1.Infrastructure
+	FeatureGates   featuregates.FeatureGate
+	OperatorSpec   *operatorapi.OperatorSpec
+	// ControlNamespace is the HyperShift control plane namespace. Empty in
+	// standalone clusters.
+	ControlNamespace string
+}
+
+// RenderResult is a rendered CSI driver operator.
+type RenderResult struct {
+	Config csioperatorclient.CSIOperatorConfig
+	// Run is the decision of shouldRunController, with its Reason.
+	Run    bool
+	Reason string
+	// Files are rendered manifests keyed by a relative file path. Manifests
+	// of the HyperShift control plane are in "mgmt" subdirectory.
+	Files map[string][]byte
+}
+
+// RenderDriverOperator renders all objects that CSO would create for a CSI
+// driver operator. The CSI driver is expected not to be installed yet.
+func RenderDriverOperator(cfg csioperatorclient.CSIOperatorConfig, input RenderInput) (*RenderResult, error) {
+	shouldRun, reason, err := shouldRunController(cfg, input.Infrastructure, input.FeatureGates, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &RenderResult{
+		Config: cfg,
+		Run:    shouldRun,
+		Reason: reason,
+		Files:  map[string][]byte{},
+	}
+	if !shouldRun {
+		return result, nil
+	}
+
+	dir := cfg.ConditionPrefix
+	mgmtDir := path.Join(dir, "mgmt")
+	hypershift := input.ControlNamespace != ""
+
+	if err := addAssets(result.Files, dir, assets.ReadFile, cfg.StaticAssets...); err != nil {
+		return nil, err
+	}
+
+	if cfg.CRAsset != "" {
+		cr := getRequestedClusterCSIDriver(cfg.CRAsset, input.OperatorSpec.LogLevel)
+		cr.APIVersion = operatorapi.GroupVersion.String()
+		cr.Kind = "ClusterCSIDriver"
+		if err := addObject(result.Files, path.Join(dir, path.Base(cfg.CRAsset)), cr); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.VolumeSnapshotClassAsset != "" {
+		if err := addAssets(result.Files, dir, assets.ReadFile, cfg.VolumeSnapshotClassAsset); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hypershift {
+		deployment, err := getRequiredStandaloneDeployment(cfg, input.OperatorSpec, input.Infrastructure)
+		if err != nil {
+			return nil, err
+		}
+		deployment.APIVersion = "apps/v1"
+		deployment.Kind = "Deployment"
+		if err := addObject(result.Files, path.Join(dir, path.Base(cfg.DeploymentAsset)), deployment); err != nil {
+			return nil, err
+		}
+		if err := addTLSProfileConfigMap(result.Files, dir, deployment, input.OperatorSpec); err != nil {
+			return nil, err
+		}
+		if cfg.ServiceMonitorAsset != "" {
+			if err := addAssets(result.Files, dir, assets.ReadFile, cfg.ServiceMonitorAsset); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	// HostedControlPlane node selector and tolerations are not available offline.
+	deployment, err := getRequiredHyperShiftDeployment(cfg, input.OperatorSpec, input.ControlNamespace, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	deployment.APIVersion = "apps/v1"
+	deployment.Kind = "Deployment"
+	if err := addObject(result.Files, path.Join(mgmtDir, path.Base(cfg.DeploymentAsset)), deployment); err != nil {
+		return nil, err
+	}
+	if err := addTLSProfileConfigMap(result.Files, mgmtDir, deployment, input.OperatorSpec); err != nil {
+		return nil, err
+	}
+
+	namespacedAssetFunc := namespaceReplacer(assets.ReadFile, "${CONTROLPLANE_NAMESPACE}", input.ControlNamespace)
+	if err := addAssets(result.Files, mgmtDir, namespacedAssetFunc, cfg.MgmtStaticAssets...); err != nil {
+		return nil, err
+	}
+	if cfg.MgmtServiceMonitorAsset != "" {
+		if err := addAssets(result.Files, mgmtDir, namespacedAssetFunc, cfg.MgmtServiceMonitorAsset); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// addTLSProfileConfigMap adds ConfigMap with the TLS security profile that the
+// Deployment mounts, if any.
+func addTLSProfileConfigMap(files map[string][]byte, dir string, deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) error {
+	cm, err := util.GetTLSProfileConfigMap(deployment, opSpec)
+	if err != nil || cm == nil {
+		return err
+	}
+	cm.APIVersion = "v1"
+	cm.Kind = "ConfigMap"
+	return addObject(files, path.Join(dir, fmt.Sprintf("v1_configmap_%s.yaml", cm.Name)), cm)
+}
+
+func addAssets(files map[string][]byte, dir string, assetFunc resourceapply.AssetFunc, assetNames ...string) error {
+	for _, name := range assetNames {
+		data, err := assetFunc(name)
+		if err != nil {
+			return fmt.Errorf("failed to read asset %s: %w", name, err)
+		}
+		files[path.Join(dir, path.Base(name))] = data
+	}
+	return nil
+}
+
+func addObject(files map[string][]byte, filePath string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filePath, err)
+	}
+	files[filePath] = data
+	return nil
+}
@@ -0,0 +1,126 @@
+package csidriveroperator
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRenderDriverOperator(t *testing.T) {
+	t.Setenv("AWS_EBS_DRIVER_OPERATOR_IMAGE", "quay.io/test/aws-ebs-operator:latest")
+	opSpec := &operatorapi.OperatorSpec{
+		ManagementState: operatorapi.Managed,
+		LogLevel:        operatorapi.Debug,
+		ObservedConfig: runtime.RawExtension{
+			Raw: []byte(`{"targetconfig":{"proxy":{"HTTPS_PROXY":"https://proxy.example.com"}}}`),
+		},
+	}
+
+	tests := []struct {
+		name             string
+		platform         v1.PlatformType
+		controlNamespace string
+		expectRun        bool
+		expectReason     string
+		expectDeployment string
+		expectNamespace  string
+		expectProxy      bool
+	}{
+		{
+			name:         "wrong platform",
+			platform:     v1.GCPPlatformType,
+			expectRun:    false,
+			expectReason: runReasonWrongPlatform,
+		},
+		{
+			name:             "standalone",
+			platform:         v1.AWSPlatformType,
+			expectRun:        true,
+			expectReason:     runReasonGA,
+			expectDeployment: "AWSEBS/apps_v1_deployment_aws-ebs-csi-driver-operator.yaml",
+			expectNamespace:  "openshift-cluster-csi-drivers",
+			expectProxy:      true,
+		},
+		{
+			name:             "hypershift",
+			platform:         v1.AWSPlatformType,
+			controlNamespace: "clusters-test",
+			expectRun:        true,
+			expectReason:     runReasonGA,
+			expectDeployment: "AWSEBS/mgmt/apps_v1_deployment_aws-ebs-csi-driver-operator.yaml",
+			expectNamespace:  "clusters-test",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := RenderInput{
+				Infrastructure: &v1.Infrastructure{
+					Status: v1.InfrastructureStatus{PlatformStatus: &v1.PlatformStatus{Type: test.platform}},
+				},
+				FeatureGates:     featuregates.NewFeatureGate(nil, nil),
+				OperatorSpec:     opSpec,
+				ControlNamespace: test.controlNamespace,
+			}
+			cfg := csioperatorclient.GetAWSEBSCSIOperatorConfig(test.controlNamespace != "")
+
+			result, err := RenderDriverOperator(cfg, input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Run != test.expectRun || result.Reason != test.expectReason {
+				t.Errorf("expected run=%t (%s), got run=%t (%s)", test.expectRun, test.expectReason, result.Run, result.Reason)
+			}
+			if !test.expectRun {
+				if len(result.Files) != 0 {
+					t.Errorf("expected no files, got %d", len(result.Files))
+				}
+				return
+			}
+
+			data, found := result.Files[test.expectDeployment]
+			if !found {
+				t.Fatalf("expected file %s, got files %v", test.expectDeployment, fileNames(result.Files))
+			}
+			deployment := resourceread.ReadDeploymentV1OrDie(data)
+			if deployment.Namespace != test.expectNamespace {
+				t.Errorf("expected Deployment in namespace %s, got %s", test.expectNamespace, deployment.Namespace)
+			}
+			container := deployment.Spec.Template.Spec.Containers[0]
+			if container.Image != "quay.io/test/aws-ebs-operator:latest" {
+				t.Errorf("expected operator image to be replaced, got %s", container.Image)
+			}
+			if !strings.Contains(strings.Join(container.Args, " "), "-v=4") {
+				t.Errorf("expected log level 4 in args, got %v", container.Args)
+			}
+			foundProxy := false
+			for _, env := range container.Env {
+				if env.Name == "HTTPS_PROXY" && env.Value == "https://proxy.example.com" {
+					foundProxy = true
+				}
+			}
+			if foundProxy != test.expectProxy {
+				t.Errorf("expected proxy env. var injected=%t, got %v", test.expectProxy, container.Env)
+			}
+			for name, data := range result.Files {
+				if strings.Contains(string(data), "${") {
+					t.Errorf("file %s contains unreplaced placeholder", name)
+				}
+			}
+		})
+	}
+}
+
+func fileNames(files map[string][]byte) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	return names
+}
@@ -8,13 +8,25 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/networkpolicy"
 )
 
 const (
@@ -31,8 +43,11 @@ const (
 	envKubeRBACProxyControlPlaneImage = "KUBE_RBAC_PROXY_CONTROL_PLANE_IMAGE"
 )
 
-var (
-	sidecarReplacer = strings.NewReplacer(
+// getSidecarReplacer returns a replacer of sidecar images in CSI driver
+// operator Deployments. The images are read from env. vars on each call, so
+// they can be set after the operator start, e.g. by the render command.
+func getSidecarReplacer() *strings.Replacer {
+	return strings.NewReplacer(
 		"${PROVISIONER_IMAGE}", os.Getenv(envProvisionerImage),
 		"${ATTACHER_IMAGE}", os.Getenv(envAttacherImage),
 		"${RESIZER_IMAGE}", os.Getenv(envResizerImage),
@@ -44,7 +59,7 @@ var (
 		"${KUBE_RBAC_PROXY_CONTROL_PLANE_IMAGE}", os.Getenv(envKubeRBACProxyControlPlaneImage),
 		"${TOOLS_IMAGE}", os.Getenv(envToolsImage),
 	)
-)
+}
 
 // factory.PostStartHook to poke newly started controller to resync.
 // This is useful if a controller is started later than at CSO startup
@@ -76,6 +91,72 @@ func reportUpdateEvent(recorder events.Recorder, obj runtime.Object, originalErr
 	}
 }
 
+// applyTLSProfileConfigMap applies ConfigMap with the observed TLS security
+// profile that the Deployment of a CSI driver operator mounts.
+func applyTLSProfileConfigMap(ctx context.Context, c coreclientv1.ConfigMapsGetter, recorder events.Recorder, d *appsv1.Deployment, opSpec *operatorv1.OperatorSpec) error {
+	cm, err := util.GetTLSProfileConfigMap(d, opSpec)
+	if err != nil {
+		return fmt.Errorf("failed to generate TLS security profile ConfigMap: %w", err)
+	}
+	if cm == nil {
+		return nil
+	}
+	_, _, err = resourceapply.ApplyConfigMap(ctx, c, recorder, cm)
+	return err
+}
+
+// deleteOperatorDeployment deletes Deployment of a CSI driver operator read
+// from the asset, together with its TLS security profile ConfigMap.
+func deleteOperatorDeployment(ctx context.Context, kubeClient kubernetes.Interface, recorder events.Recorder, assetFunc resourceapply.AssetFunc, file string) error {
+	objBytes, err := assetFunc(file)
+	if err != nil {
+		return err
+	}
+	d := resourceread.ReadDeploymentV1OrDie(objBytes)
+	err = kubeClient.AppsV1().Deployments(d.Namespace).Delete(ctx, d.Name, metav1.DeleteOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		recorder.Warningf("DeploymentDeleteFailed", "Failed to delete Deployment %s/%s: %v", d.Namespace, d.Name, err)
+		return err
+	default:
+		recorder.Eventf("DeploymentDeleted", "Deleted Deployment %s/%s", d.Namespace, d.Name)
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: util.TLSProfileConfigMapName(d.Name), Namespace: d.Namespace}}
+	_, _, err = resourceapply.DeleteConfigMap(ctx, kubeClient.CoreV1(), recorder, cm)
+	return err
+}
+
+// deleteAssets deletes objects created from the assets, including NetworkPolicies.
+func deleteAssets(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, recorder events.Recorder, assetFunc resourceapply.AssetFunc, files []string) error {
+	networkPolicyAssets, otherAssets := networkpolicy.SplitAssets(assetFunc, files)
+	var errs []error
+	for _, file := range networkPolicyAssets {
+		objBytes, err := assetFunc(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		policy, err := networkpolicy.ReadNetworkPolicyV1(objBytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot decode %q: %w", file, err))
+			continue
+		}
+		if _, err := networkpolicy.DeleteNetworkPolicy(ctx, kubeClient.NetworkingV1(), recorder, policy); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %q: %w", file, err))
+		}
+	}
+
+	clients := resourceapply.NewKubeClientHolder(kubeClient).WithDynamicClient(dynamicClient)
+	for _, result := range resourceapply.DeleteAll(ctx, clients, recorder, assetFunc, otherAssets...) {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %q: %w", result.File, result.Error))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 func checkDeploymentHealth(ctx context.Context, c appsclientv1.DeploymentsGetter, d *appsv1.Deployment) error {
 	d, err := c.Deployments(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
 	if err != nil {
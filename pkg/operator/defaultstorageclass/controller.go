@@ -8,6 +8,7 @@ import (
 	operatorapi "github.com/openshift/api/operator/v1"
 	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
@@ -16,6 +17,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	errutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	v1 "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/klog/v2"
 )
@@ -31,6 +33,9 @@ var supportedByCSIError = errors.New("only supported by a provided CSI Driver")
 
 // This Controller deploys a default StorageClass for in-tree volume plugins,
 // based on the underlying cloud (read from Infrastructure instance).
+// On platforms without any cloud (BareMetal, None, External), the StorageClass
+// is created from a template provided by the user in ConfigMap
+// default-storage-class-templates, see getStorageClassTemplate.
 // It produces following Conditions:
 // DefaultStorageClassControllerAvailable: the default storage class has been
 // created.
@@ -42,6 +47,7 @@ type Controller struct {
 	kubeClient         kubernetes.Interface
 	infraLister        openshiftv1.InfrastructureLister
 	storageClassLister v1.StorageClassLister
+	configMapLister    corelisters.ConfigMapLister
 	eventRecorder      events.Recorder
 }
 
@@ -53,12 +59,14 @@ func NewController(
 		kubeClient:         clients.KubeClient,
 		infraLister:        clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
 		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		configMapLister:    clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
 		eventRecorder:      eventRecorder,
 	}
-	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+	return factory.New().WithSync(operatormetrics.InstrumentSync("DefaultStorageClassController", c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
 		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
 	).ToController("DefaultStorageClassController", eventRecorder)
 }
 
@@ -160,13 +168,8 @@ func (c *Controller) syncStorageClass(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	// Check to see if the PlatformStatus is nil. This has been seen on some
-	// UPI installs on baremetal platforms
-	if infrastructure.Status.PlatformStatus == nil {
-		return unsupportedPlatformError
-	}
 
-	expectedSC, err := newStorageClassForCluster(infrastructure)
+	expectedSC, err := newStorageClassForCluster(c.configMapLister, infrastructure)
 	if err != nil {
 		return err
 	}
@@ -191,7 +194,13 @@ func (c *Controller) syncStorageClass(ctx context.Context) error {
 }
 
 // Returns an error indicating whether the StorageClass is provided by a CSI driver or an unsupported platform.
-func newStorageClassForCluster(infrastructure *configv1.Infrastructure) (*storagev1.StorageClass, error) {
+func newStorageClassForCluster(configMapLister corelisters.ConfigMapLister, infrastructure *configv1.Infrastructure) (*storagev1.StorageClass, error) {
+	// Check to see if the PlatformStatus is nil. This has been seen on some
+	// UPI installs on baremetal platforms, treat them as platform None.
+	if infrastructure.Status.PlatformStatus == nil {
+		return getStorageClassTemplate(configMapLister, configv1.NonePlatformType)
+	}
+
 	switch infrastructure.Status.PlatformStatus.Type {
 	case configv1.AWSPlatformType:
 		return nil, supportedByCSIError
@@ -208,8 +217,18 @@ func newStorageClassForCluster(infrastructure *configv1.Infrastructure) (*storag
 	case configv1.OvirtPlatformType:
 		return nil, supportedByCSIError
 	default:
-		return nil, unsupportedPlatformError
+		return getStorageClassTemplate(configMapLister, infrastructure.Status.PlatformStatus.Type)
+	}
+}
+
+// Render returns the default StorageClass that the Controller would create,
+// or nil when the platform has no StorageClass template.
+func Render(configMapLister corelisters.ConfigMapLister, infrastructure *configv1.Infrastructure) (*storagev1.StorageClass, error) {
+	sc, err := newStorageClassForCluster(configMapLister, infrastructure)
+	if err == unsupportedPlatformError || err == supportedByCSIError {
+		return nil, nil
 	}
+	return sc, err
 }
 
 // UpdateConditionFunc returns a func to update a condition.
@@ -13,6 +13,7 @@ import (
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +29,7 @@ type testObjects struct {
 	storage        *opv1.Storage
 	infrastructure *cfgv1.Infrastructure
 	storageClasses []*storagev1.StorageClass
+	configMaps     []*corev1.ConfigMap
 }
 
 type operatorTest struct {
@@ -43,6 +45,9 @@ func newController(test operatorTest) *testContext {
 	for _, c := range test.initialObjects.storageClasses {
 		initialObjects.CoreObjects = append(initialObjects.CoreObjects, c)
 	}
+	for _, c := range test.initialObjects.configMaps {
+		initialObjects.CoreObjects = append(initialObjects.CoreObjects, c)
+	}
 	if test.initialObjects.storage != nil {
 		initialObjects.OperatorObjects = []runtime.Object{test.initialObjects.storage}
 	}
@@ -109,6 +114,54 @@ func getAzureStackHubInfrastructure() *cfgv1.Infrastructure {
 	}
 }
 
+func getNilPlatformStatusInfrastructure() *cfgv1.Infrastructure {
+	return &cfgv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: infraConfigName,
+		},
+	}
+}
+
+const templateStorageClass = `apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: local
+provisioner: example.com/local
+volumeBindingMode: WaitForFirstConsumer
+`
+
+func getTemplatesConfigMap(templates map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      storageClassTemplatesConfigMapName,
+			Namespace: csoclients.OperatorNamespace,
+		},
+		Data: templates,
+	}
+}
+
+func getTemplateStorageClass(modifiers ...storageClassModifier) *storagev1.StorageClass {
+	bindingMode := storagev1.VolumeBindingWaitForFirstConsumer
+	sc := &storagev1.StorageClass{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StorageClass",
+			APIVersion: "storage.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local",
+			Annotations: map[string]string{
+				defaultSCAnnotation: "true",
+			},
+		},
+		Provisioner:       "example.com/local",
+		VolumeBindingMode: &bindingMode,
+	}
+	for _, modifier := range modifiers {
+		sc = modifier(sc)
+	}
+	return sc
+}
+
 func withTrueConditions(conditions ...string) csoclients.CrModifier {
 	return func(i *opv1.Storage) *opv1.Storage {
 		if i.Status.Conditions == nil {
@@ -157,6 +210,101 @@ func TestSync(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			// The controller creates default StorageClass from a template
+			name: "initial baremetal deployment with template",
+			initialObjects: testObjects{
+				storage:        csoclients.GetCR(),
+				infrastructure: getInfrastructure(cfgv1.BareMetalPlatformType),
+				configMaps: []*corev1.ConfigMap{
+					getTemplatesConfigMap(map[string]string{string(cfgv1.BareMetalPlatformType): templateStorageClass}),
+				},
+			},
+			expectedObjects: testObjects{
+				storage: csoclients.GetCR(
+					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
+					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
+				),
+				storageClasses: []*storagev1.StorageClass{getTemplateStorageClass()},
+			},
+			expectErr: false,
+		},
+		{
+			// Missing PlatformStatus is treated as platform None
+			name: "initial deployment without PlatformStatus with template",
+			initialObjects: testObjects{
+				storage:        csoclients.GetCR(),
+				infrastructure: getNilPlatformStatusInfrastructure(),
+				configMaps: []*corev1.ConfigMap{
+					getTemplatesConfigMap(map[string]string{string(cfgv1.NonePlatformType): templateStorageClass}),
+				},
+			},
+			expectedObjects: testObjects{
+				storage: csoclients.GetCR(
+					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
+					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
+				),
+				storageClasses: []*storagev1.StorageClass{getTemplateStorageClass()},
+			},
+			expectErr: false,
+		},
+		{
+			// Template for another platform is ignored
+			name: "external platform with template for another platform",
+			initialObjects: testObjects{
+				storage:        csoclients.GetCR(),
+				infrastructure: getInfrastructure(cfgv1.ExternalPlatformType),
+				configMaps: []*corev1.ConfigMap{
+					getTemplatesConfigMap(map[string]string{string(cfgv1.BareMetalPlatformType): templateStorageClass}),
+				},
+			},
+			expectedObjects: testObjects{
+				storage: csoclients.GetCR(
+					withTrueConditions(conditionsPrefix+"Disabled", conditionsPrefix+opv1.OperatorStatusTypeAvailable),
+					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
+					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeUpgradeable),
+				),
+			},
+			expectErr: false,
+		},
+		{
+			// The user made the StorageClass non-default, the controller keeps it
+			name: "non-default template StorageClass is not overwritten",
+			initialObjects: testObjects{
+				storage:        csoclients.GetCR(),
+				infrastructure: getInfrastructure(cfgv1.ExternalPlatformType),
+				storageClasses: []*storagev1.StorageClass{getTemplateStorageClass(withNoDefault)},
+				configMaps: []*corev1.ConfigMap{
+					getTemplatesConfigMap(map[string]string{string(cfgv1.ExternalPlatformType): templateStorageClass}),
+				},
+			},
+			expectedObjects: testObjects{
+				storage: csoclients.GetCR(
+					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
+					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
+				),
+				storageClasses: []*storagev1.StorageClass{getTemplateStorageClass(withNoDefault)},
+			},
+			expectErr: false,
+		},
+		{
+			// Invalid template is reported as an error
+			name: "invalid template",
+			initialObjects: testObjects{
+				storage:        csoclients.GetCR(),
+				infrastructure: getInfrastructure(cfgv1.BareMetalPlatformType),
+				configMaps: []*corev1.ConfigMap{
+					getTemplatesConfigMap(map[string]string{string(cfgv1.BareMetalPlatformType): "kind: ConfigMap\napiVersion: v1\n"}),
+				},
+			},
+			expectedObjects: testObjects{
+				storage: csoclients.GetCR(
+					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
+					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
+				),
+			},
+			expectErr: true,
+		},
 		{
 			// The controller returns error - missing Available is added
 			name: "infrastructure not found",
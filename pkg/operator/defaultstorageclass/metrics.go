@@ -0,0 +1,75 @@
+package defaultstorageclass
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	defaultStorageClassCountDesc = metrics.NewDesc(
+		"default_storage_class_count",
+		"Number of default storage classes currently configured.",
+		nil, nil,
+		metrics.ALPHA,
+		"",
+	)
+	defaultStorageClassInfoDesc = metrics.NewDesc(
+		"default_storage_class_info",
+		"Information about default storage classes currently configured.",
+		[]string{"name", "provisioner"}, nil,
+		metrics.ALPHA,
+		"",
+	)
+)
+
+// defaultStorageClassCollector reports default StorageClasses on each scrape.
+type defaultStorageClassCollector struct {
+	metrics.BaseStableCollector
+
+	storageClassLister v1.StorageClassLister
+}
+
+var _ metrics.StableCollector = &defaultStorageClassCollector{}
+
+func newDefaultStorageClassCollector(storageClassLister v1.StorageClassLister) metrics.StableCollector {
+	return &defaultStorageClassCollector{
+		storageClassLister: storageClassLister,
+	}
+}
+
+// RegisterMetrics registers metrics of default StorageClasses.
+// The StorageClass informer must be started by the caller.
+func RegisterMetrics(storageClassLister v1.StorageClassLister) {
+	klog.Infof("Registering default StorageClass metrics")
+	legacyregistry.CustomMustRegister(newDefaultStorageClassCollector(storageClassLister))
+}
+
+func (c *defaultStorageClassCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- defaultStorageClassCountDesc
+	ch <- defaultStorageClassInfoDesc
+}
+
+func (c *defaultStorageClassCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	scs, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		// Report nothing, the next scrape will try again.
+		klog.Errorf("Failed to list StorageClasses for metrics: %s", err)
+		return
+	}
+
+	defaultSCCount := 0
+	var defaultSCNames []string
+	for _, sc := range scs {
+		if !isDefaultStorageClass(sc) {
+			continue
+		}
+		defaultSCCount++
+		defaultSCNames = append(defaultSCNames, sc.Name)
+		ch <- metrics.NewLazyConstMetric(defaultStorageClassInfoDesc, metrics.GaugeValue, 1, sc.Name, sc.Provisioner)
+	}
+	klog.V(4).Infof("Current default StorageClass count: %v (%v)", defaultSCCount, defaultSCNames)
+	ch <- metrics.NewLazyConstMetric(defaultStorageClassCountDesc, metrics.GaugeValue, float64(defaultSCCount))
+}
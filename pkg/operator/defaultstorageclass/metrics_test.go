@@ -0,0 +1,104 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/component-base/metrics"
+)
+
+type errorStorageClassLister struct {
+	v1.StorageClassLister
+}
+
+func (l *errorStorageClassLister) List(selector labels.Selector) ([]*storagev1.StorageClass, error) {
+	return nil, errors.New("mock list error")
+}
+
+func TestDefaultStorageClassCollector(t *testing.T) {
+	tests := []struct {
+		name           string
+		storageClasses []*storagev1.StorageClass
+		expected       string
+	}{
+		{
+			name: "no StorageClass",
+			expected: `
+				# HELP default_storage_class_count [ALPHA] Number of default storage classes currently configured.
+				# TYPE default_storage_class_count gauge
+				default_storage_class_count 0
+			`,
+		},
+		{
+			name: "single default",
+			storageClasses: []*storagev1.StorageClass{
+				getDefaultStorageClass("gp3-csi", "ebs.csi.aws.com", 0),
+				{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Provisioner: "example.com/provisioner"},
+			},
+			expected: `
+				# HELP default_storage_class_count [ALPHA] Number of default storage classes currently configured.
+				# TYPE default_storage_class_count gauge
+				default_storage_class_count 1
+				# HELP default_storage_class_info [ALPHA] Information about default storage classes currently configured.
+				# TYPE default_storage_class_info gauge
+				default_storage_class_info{name="gp3-csi",provisioner="ebs.csi.aws.com"} 1
+			`,
+		},
+		{
+			name: "multiple defaults",
+			storageClasses: []*storagev1.StorageClass{
+				getDefaultStorageClass("gp3-csi", "ebs.csi.aws.com", 0),
+				getDefaultStorageClass("local", "example.com/local", 0),
+			},
+			expected: `
+				# HELP default_storage_class_count [ALPHA] Number of default storage classes currently configured.
+				# TYPE default_storage_class_count gauge
+				default_storage_class_count 2
+				# HELP default_storage_class_info [ALPHA] Information about default storage classes currently configured.
+				# TYPE default_storage_class_info gauge
+				default_storage_class_info{name="gp3-csi",provisioner="ebs.csi.aws.com"} 1
+				default_storage_class_info{name="local",provisioner="example.com/local"} 1
+			`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{}
+			for _, sc := range test.storageClasses {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, sc)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			lister := clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			registry := metrics.NewKubeRegistry()
+			registry.CustomMustRegister(newDefaultStorageClassCollector(lister))
+			// default_storage_class_count does not pass the lint, it's kept for compatibility with existing alerts.
+			if err := promtestutil.GatherAndCompare(registry, strings.NewReader(test.expected), "default_storage_class_count", "default_storage_class_info"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDefaultStorageClassCollectorListError(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	registry.CustomMustRegister(newDefaultStorageClassCollector(&errorStorageClassLister{}))
+	// Nothing is reported and the process keeps running.
+	if err := promtestutil.GatherAndCompare(registry, strings.NewReader(""), "default_storage_class_count", "default_storage_class_info"); err != nil {
+		t.Error(err)
+	}
+}
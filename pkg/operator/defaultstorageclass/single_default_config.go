@@ -0,0 +1,85 @@
+package defaultstorageclass
+
+import (
+	"fmt"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// SingleDefaultPolicy selects the StorageClass that stays default when there are more of them.
+type SingleDefaultPolicy string
+
+const (
+	// The most recently created default StorageClass stays default.
+	SingleDefaultPolicyNewest SingleDefaultPolicy = "Newest"
+	// The oldest default StorageClass stays default.
+	SingleDefaultPolicyOldest SingleDefaultPolicy = "Oldest"
+	// The default StorageClass of a CSI driver installed by CSO stays default.
+	// The oldest one is used when there are more of them.
+	SingleDefaultPolicyProvisioned SingleDefaultPolicy = "Provisioned"
+	// The default StorageClass with given name stays default.
+	SingleDefaultPolicyNamed SingleDefaultPolicy = "Named"
+)
+
+// SingleDefaultConfig is configuration of SingleDefaultController.
+type SingleDefaultConfig struct {
+	// Enabled enables removal of the default annotation from StorageClasses
+	// that were not selected by the Policy.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Policy to select the StorageClass that stays default.
+	Policy SingleDefaultPolicy `yaml:"policy,omitempty"`
+	// Name of the StorageClass that stays default with the Named policy.
+	Name string `yaml:"name,omitempty"`
+}
+
+var (
+	defaultSingleDefaultConfig = SingleDefaultConfig{
+		// The enforcement is opt-in
+		Enabled: false,
+		Policy:  SingleDefaultPolicyProvisioned,
+	}
+)
+
+const (
+	singleDefaultConfigMapName = "default-storage-class-enforcement"
+	configKey                  = "config.yaml"
+)
+
+func ParseSingleDefaultConfigMap(lister listerv1.ConfigMapLister) (*SingleDefaultConfig, error) {
+	cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(singleDefaultConfigMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Missing ConfigMap indicates default config
+			klog.V(4).Infof("Using default config, %s does not exist", singleDefaultConfigMapName)
+			return &defaultSingleDefaultConfig, nil
+		}
+		return nil, err
+	}
+
+	data, found := cm.Data[configKey]
+	if !found {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: expected key %s", singleDefaultConfigMapName, configKey)
+	}
+
+	config := defaultSingleDefaultConfig
+	err = yaml.UnmarshalStrict([]byte(data), &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: %s", singleDefaultConfigMapName, err)
+	}
+
+	switch config.Policy {
+	case SingleDefaultPolicyNewest, SingleDefaultPolicyOldest, SingleDefaultPolicyProvisioned:
+	case SingleDefaultPolicyNamed:
+		if config.Name == "" {
+			return nil, fmt.Errorf("invalid format of ConfigMap %s: policy %s requires name", singleDefaultConfigMapName, config.Policy)
+		}
+	default:
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: unknown policy %q", singleDefaultConfigMapName, config.Policy)
+	}
+	klog.V(4).Infof("Parsed ConfigMap %s: %+v", singleDefaultConfigMapName, config)
+	return &config, nil
+}
@@ -0,0 +1,217 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	singleDefaultConditionsPrefix = "SingleDefaultStorageClassController"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, it only
+	// reports what the controller did.
+	singleDefaultEnforcedConditionType = singleDefaultConditionsPrefix + "Enforced"
+	multipleDefaultsRemovedReason      = "MultipleDefaultsRemoved"
+
+	defaultSCAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultSCAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// This SingleDefaultController makes sure there is at most one default StorageClass.
+// It is opt-in, see SingleDefaultConfig. When there are multiple default StorageClasses,
+// it keeps the one selected by the configured policy and removes the default
+// annotation from the others. An event is emitted for each changed StorageClass.
+// It produces following Conditions:
+// SingleDefaultStorageClassControllerEnforced - what the controller did with default
+// StorageClasses. The last removal of default annotations stays in the condition
+// until there are multiple default StorageClasses again.
+// SingleDefaultStorageClassControllerDegraded - error updating StorageClasses or
+// invalid configuration.
+type SingleDefaultController struct {
+	operatorClient         v1helpers.OperatorClient
+	kubeClient             kubernetes.Interface
+	storageClassLister     v1.StorageClassLister
+	configMapLister        corelisters.ConfigMapLister
+	clusterCSIDriverLister oplisters.ClusterCSIDriverLister
+	eventRecorder          events.Recorder
+}
+
+func NewSingleDefaultController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &SingleDefaultController{
+		operatorClient:         clients.OperatorClient,
+		kubeClient:             clients.KubeClient,
+		storageClassLister:     clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		configMapLister:        clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
+		eventRecorder:          eventRecorder.WithComponentSuffix(singleDefaultConditionsPrefix),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(singleDefaultConditionsPrefix, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer(),
+	).ToController(singleDefaultConditionsPrefix, c.eventRecorder)
+}
+
+func (c *SingleDefaultController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("SingleDefaultStorageClassController sync started")
+	defer klog.V(4).Infof("SingleDefaultStorageClassController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	cfg, err := ParseSingleDefaultConfigMap(c.configMapLister)
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		_, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, removeConditionFn(singleDefaultEnforcedConditionType))
+		return err
+	}
+
+	scs, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var defaultSCs []*storagev1.StorageClass
+	for _, sc := range scs {
+		if isDefaultStorageClass(sc) {
+			defaultSCs = append(defaultSCs, sc)
+		}
+	}
+
+	enforcedCnd := operatorapi.OperatorCondition{
+		Type:   singleDefaultEnforcedConditionType,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if len(defaultSCs) <= 1 {
+		// Keep the record of which StorageClasses were demoted and why, it's
+		// still valid after the demotion fixed the cluster.
+		if cnd := v1helpers.FindOperatorCondition(opStatus.Conditions, singleDefaultEnforcedConditionType); cnd != nil && cnd.Reason == multipleDefaultsRemovedReason {
+			return nil
+		}
+		enforcedCnd.Message = "No default StorageClass found"
+		if len(defaultSCs) == 1 {
+			enforcedCnd.Message = fmt.Sprintf("StorageClass %s is the only default StorageClass", defaultSCs[0].Name)
+		}
+		_, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(enforcedCnd))
+		return err
+	}
+
+	selected, err := c.selectDefault(cfg, defaultSCs)
+	if err != nil {
+		return err
+	}
+	if selected == nil {
+		// Don't touch anything, the admin must fix the config or the StorageClasses
+		enforcedCnd.Status = operatorapi.ConditionFalse
+		enforcedCnd.Reason = "NoMatchingStorageClass"
+		enforcedCnd.Message = fmt.Sprintf("%d default StorageClasses found, but none of them matches policy %s", len(defaultSCs), cfg.Policy)
+		_, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(enforcedCnd))
+		return err
+	}
+
+	var demoted []string
+	for _, sc := range defaultSCs {
+		if sc.Name == selected.Name {
+			continue
+		}
+		if err := c.removeDefault(ctx, sc, selected, cfg.Policy); err != nil {
+			return err
+		}
+		demoted = append(demoted, sc.Name)
+	}
+
+	enforcedCnd.Reason = multipleDefaultsRemovedReason
+	enforcedCnd.Message = fmt.Sprintf("StorageClass %s kept as default by policy %s, default annotation removed from: %s", selected.Name, cfg.Policy, strings.Join(demoted, ", "))
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(enforcedCnd))
+	return err
+}
+
+// selectDefault returns the StorageClass that should stay default or nil,
+// if the policy does not select any.
+func (c *SingleDefaultController) selectDefault(cfg *SingleDefaultConfig, defaultSCs []*storagev1.StorageClass) (*storagev1.StorageClass, error) {
+	sorted := make([]*storagev1.StorageClass, len(defaultSCs))
+	copy(sorted, defaultSCs)
+	// Oldest first, name as tie breaker for a stable result
+	sort.Slice(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	switch cfg.Policy {
+	case SingleDefaultPolicyOldest:
+		return sorted[0], nil
+	case SingleDefaultPolicyNewest:
+		return sorted[len(sorted)-1], nil
+	case SingleDefaultPolicyNamed:
+		for _, sc := range sorted {
+			if sc.Name == cfg.Name {
+				return sc, nil
+			}
+		}
+		return nil, nil
+	case SingleDefaultPolicyProvisioned:
+		for _, sc := range sorted {
+			// CSO creates ClusterCSIDriver for each CSI driver it installs.
+			_, err := c.clusterCSIDriverLister.Get(sc.Provisioner)
+			if err == nil {
+				return sc, nil
+			}
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown policy %q", cfg.Policy)
+}
+
+func (c *SingleDefaultController) removeDefault(ctx context.Context, sc, selected *storagev1.StorageClass, policy SingleDefaultPolicy) error {
+	scCopy := sc.DeepCopy()
+	// Set to "false" instead of removing, it's what users do to make a StorageClass non-default
+	// and it's respected by all controllers that create StorageClasses.
+	scCopy.Annotations[defaultSCAnnotation] = "false"
+	if _, found := scCopy.Annotations[betaDefaultSCAnnotation]; found {
+		scCopy.Annotations[betaDefaultSCAnnotation] = "false"
+	}
+	if _, err := c.kubeClient.StorageV1().StorageClasses().Update(ctx, scCopy, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to remove default annotation from StorageClass %s: %w", sc.Name, err)
+	}
+	klog.V(2).Infof("Removed default annotation from StorageClass %s, %s is the default one", sc.Name, selected.Name)
+	c.eventRecorder.Warningf("DefaultStorageClassAnnotationRemoved",
+		"Removed default annotation from StorageClass %s, %s was selected as the only default StorageClass by policy %s", sc.Name, selected.Name, policy)
+	return nil
+}
+
+func isDefaultStorageClass(sc *storagev1.StorageClass) bool {
+	return sc.Annotations[defaultSCAnnotation] == "true" || sc.Annotations[betaDefaultSCAnnotation] == "true"
+}
@@ -0,0 +1,249 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func getDefaultStorageClass(name, provisioner string, age time.Duration) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			Annotations: map[string]string{
+				defaultSCAnnotation: "true",
+			},
+		},
+		Provisioner: provisioner,
+	}
+}
+
+func getSingleDefaultConfigMap(config string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      singleDefaultConfigMapName,
+			Namespace: csoclients.OperatorNamespace,
+		},
+		Data: map[string]string{
+			configKey: config,
+		},
+	}
+}
+
+func TestSingleDefaultSync(t *testing.T) {
+	storageClasses := []runtime.Object{
+		getDefaultStorageClass("old", "kubernetes.io/no-provisioner", 3*time.Hour),
+		getDefaultStorageClass("csi", "ebs.csi.aws.com", 2*time.Hour),
+		getDefaultStorageClass("new", "example.com/provisioner", 1*time.Hour),
+	}
+	ebs := &opv1.ClusterCSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"}}
+
+	tests := []struct {
+		name              string
+		config            *corev1.ConfigMap
+		expectedDefaults  []string
+		expectedCondition opv1.ConditionStatus
+		expectErr         bool
+	}{
+		{
+			name:             "disabled by default",
+			expectedDefaults: []string{"old", "csi", "new"},
+		},
+		{
+			name:              "oldest",
+			config:            getSingleDefaultConfigMap("enabled: true\npolicy: Oldest"),
+			expectedDefaults:  []string{"old"},
+			expectedCondition: opv1.ConditionTrue,
+		},
+		{
+			name:              "newest",
+			config:            getSingleDefaultConfigMap("enabled: true\npolicy: Newest"),
+			expectedDefaults:  []string{"new"},
+			expectedCondition: opv1.ConditionTrue,
+		},
+		{
+			name:              "provisioned by CSO",
+			config:            getSingleDefaultConfigMap("enabled: true\npolicy: Provisioned"),
+			expectedDefaults:  []string{"csi"},
+			expectedCondition: opv1.ConditionTrue,
+		},
+		{
+			name:              "named",
+			config:            getSingleDefaultConfigMap("enabled: true\npolicy: Named\nname: new"),
+			expectedDefaults:  []string{"new"},
+			expectedCondition: opv1.ConditionTrue,
+		},
+		{
+			name:              "named StorageClass is not default",
+			config:            getSingleDefaultConfigMap("enabled: true\npolicy: Named\nname: missing"),
+			expectedDefaults:  []string{"old", "csi", "new"},
+			expectedCondition: opv1.ConditionFalse,
+		},
+		{
+			name:             "invalid policy",
+			config:           getSingleDefaultConfigMap("enabled: true\npolicy: Random"),
+			expectedDefaults: []string{"old", "csi", "new"},
+			expectErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				CoreObjects:     storageClasses,
+				OperatorObjects: []runtime.Object{csoclients.GetCR(), ebs},
+			}
+			if test.config != nil {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, test.config)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			ctrl := NewSingleDefaultController(clients, events.NewInMemoryRecorder("operator"))
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			err := ctrl.Sync(ctx, nil)
+			if err != nil && !test.expectErr {
+				t.Errorf("sync() returned unexpected error: %v", err)
+			}
+			if err == nil && test.expectErr {
+				t.Error("sync() unexpectedly succeeded when error was expected")
+			}
+
+			scList, err := clients.KubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list StorageClasses: %v", err)
+			}
+			var defaults []string
+			for i := range scList.Items {
+				if isDefaultStorageClass(&scList.Items[i]) {
+					defaults = append(defaults, scList.Items[i].Name)
+				}
+			}
+			if len(defaults) != len(test.expectedDefaults) {
+				t.Errorf("expected default StorageClasses %v, got %v", test.expectedDefaults, defaults)
+			}
+			for _, name := range test.expectedDefaults {
+				found := false
+				for _, d := range defaults {
+					if d == name {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected StorageClass %s to be default, got %v", name, defaults)
+				}
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, singleDefaultEnforcedConditionType)
+			if test.expectedCondition == "" {
+				if cnd != nil {
+					t.Errorf("expected no %s condition, got %+v", singleDefaultEnforcedConditionType, cnd)
+				}
+				return
+			}
+			if cnd == nil || cnd.Status != test.expectedCondition {
+				t.Errorf("expected %s=%s, got %+v", singleDefaultEnforcedConditionType, test.expectedCondition, cnd)
+			}
+		})
+	}
+}
+
+func TestSingleDefaultEnforcedMessage(t *testing.T) {
+	lastAction := opv1.OperatorCondition{
+		Type:    singleDefaultEnforcedConditionType,
+		Status:  opv1.ConditionTrue,
+		Reason:  multipleDefaultsRemovedReason,
+		Message: "StorageClass old kept as default by policy Oldest, default annotation removed from: new",
+	}
+
+	tests := []struct {
+		name              string
+		storageClasses    []runtime.Object
+		initialConditions []opv1.OperatorCondition
+		expectedReason    string
+		expectedMessage   string
+	}{
+		{
+			name:            "no default",
+			expectedReason:  "AsExpected",
+			expectedMessage: "No default StorageClass found",
+		},
+		{
+			name:            "single default",
+			storageClasses:  []runtime.Object{getDefaultStorageClass("old", "kubernetes.io/no-provisioner", time.Hour)},
+			expectedReason:  "AsExpected",
+			expectedMessage: "StorageClass old is the only default StorageClass",
+		},
+		{
+			name:              "last removal is kept",
+			storageClasses:    []runtime.Object{getDefaultStorageClass("old", "kubernetes.io/no-provisioner", time.Hour)},
+			initialConditions: []opv1.OperatorCondition{lastAction},
+			expectedReason:    multipleDefaultsRemovedReason,
+			expectedMessage:   lastAction.Message,
+		},
+		{
+			name: "new removal replaces the last one",
+			storageClasses: []runtime.Object{
+				getDefaultStorageClass("old", "kubernetes.io/no-provisioner", 2*time.Hour),
+				getDefaultStorageClass("csi", "ebs.csi.aws.com", time.Hour),
+			},
+			initialConditions: []opv1.OperatorCondition{lastAction},
+			expectedReason:    multipleDefaultsRemovedReason,
+			expectedMessage:   "StorageClass old kept as default by policy Oldest, default annotation removed from: csi",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cr := csoclients.GetCR()
+			cr.Status.Conditions = test.initialConditions
+			initialObjects := &csoclients.FakeTestObjects{
+				CoreObjects:     append(test.storageClasses, getSingleDefaultConfigMap("enabled: true\npolicy: Oldest")),
+				OperatorObjects: []runtime.Object{cr},
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			ctrl := NewSingleDefaultController(clients, events.NewInMemoryRecorder("operator"))
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			if err := ctrl.Sync(ctx, nil); err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, singleDefaultEnforcedConditionType)
+			if cnd == nil {
+				t.Fatalf("expected %s condition, got none", singleDefaultEnforcedConditionType)
+			}
+			if cnd.Reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q", test.expectedReason, cnd.Reason)
+			}
+			if cnd.Message != test.expectedMessage {
+				t.Errorf("expected message %q, got %q", test.expectedMessage, cnd.Message)
+			}
+		})
+	}
+}
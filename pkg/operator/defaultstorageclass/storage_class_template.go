@@ -0,0 +1,73 @@
+package defaultstorageclass
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ConfigMap with StorageClass templates for platforms without a CSI driver installed by CSO.
+	// Each key is a platform type (e.g. "BareMetal", "None", "External") and its value
+	// is a StorageClass manifest.
+	storageClassTemplatesConfigMapName = "default-storage-class-templates"
+)
+
+var (
+	// Platforms where the default StorageClass can be provided by a template.
+	templatePlatforms = map[configv1.PlatformType]bool{
+		configv1.BareMetalPlatformType: true,
+		configv1.NonePlatformType:      true,
+		configv1.ExternalPlatformType:  true,
+	}
+)
+
+// getStorageClassTemplate returns StorageClass from the templates ConfigMap for given platform.
+// It returns unsupportedPlatformError when there is no template for the platform.
+func getStorageClassTemplate(lister listerv1.ConfigMapLister, platform configv1.PlatformType) (*storagev1.StorageClass, error) {
+	if !templatePlatforms[platform] {
+		return nil, unsupportedPlatformError
+	}
+
+	cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(storageClassTemplatesConfigMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("ConfigMap %s does not exist, no default StorageClass for platform %s", storageClassTemplatesConfigMapName, platform)
+			return nil, unsupportedPlatformError
+		}
+		return nil, err
+	}
+
+	data, found := cm.Data[string(platform)]
+	if !found {
+		klog.V(4).Infof("ConfigMap %s has no template for platform %s", storageClassTemplatesConfigMapName, platform)
+		return nil, unsupportedPlatformError
+	}
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(data), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StorageClass template for platform %s in ConfigMap %s: %s", platform, storageClassTemplatesConfigMapName, err)
+	}
+	sc, ok := obj.(*storagev1.StorageClass)
+	if !ok {
+		return nil, fmt.Errorf("invalid StorageClass template for platform %s in ConfigMap %s: expected StorageClass, got %T", platform, storageClassTemplatesConfigMapName, obj)
+	}
+	if sc.Name == "" || sc.Provisioner == "" {
+		return nil, fmt.Errorf("invalid StorageClass template for platform %s in ConfigMap %s: name and provisioner must be set", platform, storageClassTemplatesConfigMapName)
+	}
+
+	// The template provides the default StorageClass, unless the user says otherwise.
+	if _, found := sc.Annotations[defaultSCAnnotation]; !found {
+		if sc.Annotations == nil {
+			sc.Annotations = map[string]string{}
+		}
+		sc.Annotations[defaultSCAnnotation] = "true"
+	}
+	return sc, nil
+}
@@ -0,0 +1,254 @@
+package defaultvolumesnapshotclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apiextlisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	conditionsPrefix = "DefaultVolumeSnapshotClassController"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, a missing
+	// or duplicate default VolumeSnapshotClass is reported by an alert and it should
+	// not affect the ClusterOperator.
+	violatedConditionType = conditionsPrefix + "SingleDefaultViolated"
+
+	defaultVSCAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+	snapshotClassCRDName = "volumesnapshotclasses.snapshot.storage.k8s.io"
+)
+
+var (
+	volumeSnapshotClassGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+
+	defaultVolumeSnapshotClassCount = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "default_volume_snapshot_classes",
+			Help:           "Number of default VolumeSnapshotClasses of a CSI driver installed by the cluster-storage-operator.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(defaultVolumeSnapshotClassCount)
+}
+
+// This Controller makes sure that each CSI driver started by CSO has exactly one
+// default VolumeSnapshotClass. When a driver has none, the controller marks the
+// VolumeSnapshotClass from the driver's VolumeSnapshotClassAsset as the default,
+// or creates it from the asset when it does not exist. A VolumeSnapshotClass made
+// non-default by the user is not overwritten.
+// VolumeSnapshotClasses are listed on each sync instead of using an informer,
+// the CRD does not exist when the CSISnapshot capability is disabled.
+// It produces following Conditions:
+// DefaultVolumeSnapshotClassControllerSingleDefaultViolated - a CSI driver has no
+// or more than one default VolumeSnapshotClass.
+// DefaultVolumeSnapshotClassControllerDegraded - error creating or updating the VolumeSnapshotClass.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	dynamicClient  dynamic.Interface
+	crdLister      apiextlisters.CustomResourceDefinitionLister
+	driversGetter  csidriveroperator.RunningDriversGetter
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	driversGetter csidriveroperator.RunningDriversGetter,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		dynamicClient:  clients.DynamicClient,
+		crdLister:      clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Lister(),
+		driversGetter:  driversGetter,
+		eventRecorder:  eventRecorder.WithComponentSuffix(conditionsPrefix),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(conditionsPrefix, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Informer(),
+		// ClusterCSIDrivers are created right after a CSI driver operator is started.
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer(),
+	).ResyncEvery(resyncInterval).ToController(conditionsPrefix, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("DefaultVolumeSnapshotClassController sync started")
+	defer klog.V(4).Infof("DefaultVolumeSnapshotClassController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	_, err = c.crdLister.Get(snapshotClassCRDName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(4).Infof("CRD %s does not exist, skipping default VolumeSnapshotClasses", snapshotClassCRDName)
+			defaultVolumeSnapshotClassCount.Reset()
+			_, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, removeConditionFn(violatedConditionType))
+			return err
+		}
+		return err
+	}
+
+	drivers, synced := c.driversGetter.RunningDrivers()
+	if !synced {
+		klog.V(4).Infof("Waiting for CSI driver operators to be evaluated")
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), 5*time.Second)
+		return nil
+	}
+
+	vscList, err := c.dynamicClient.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	vscsByDriver := map[string][]*unstructured.Unstructured{}
+	for i := range vscList.Items {
+		vsc := &vscList.Items[i]
+		driver, _, _ := unstructured.NestedString(vsc.Object, "driver")
+		vscsByDriver[driver] = append(vscsByDriver[driver], vsc)
+	}
+
+	sort.Slice(drivers, func(i, j int) bool {
+		return drivers[i].CSIDriverName < drivers[j].CSIDriverName
+	})
+	counts := map[string]int{}
+	var violations []string
+	for _, cfg := range drivers {
+		vscs := vscsByDriver[cfg.CSIDriverName]
+		if len(vscs) == 0 && cfg.VolumeSnapshotClassAsset == "" {
+			// The driver does not support snapshots
+			continue
+		}
+		defaults, err := c.syncDriver(ctx, cfg, vscs)
+		if err != nil {
+			return err
+		}
+		counts[cfg.CSIDriverName] = len(defaults)
+		switch {
+		case len(defaults) == 0:
+			violations = append(violations, fmt.Sprintf("CSI driver %s has no default VolumeSnapshotClass", cfg.CSIDriverName))
+		case len(defaults) > 1:
+			violations = append(violations, fmt.Sprintf("CSI driver %s has %d default VolumeSnapshotClasses: %s", cfg.CSIDriverName, len(defaults), strings.Join(defaults, ", ")))
+		}
+	}
+
+	defaultVolumeSnapshotClassCount.Reset()
+	for driver, count := range counts {
+		defaultVolumeSnapshotClassCount.WithLabelValues(driver).Set(float64(count))
+	}
+
+	violatedCnd := operatorapi.OperatorCondition{
+		Type:   violatedConditionType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if len(violations) > 0 {
+		violatedCnd.Status = operatorapi.ConditionTrue
+		violatedCnd.Reason = "InvalidDefaultVolumeSnapshotClasses"
+		violatedCnd.Message = strings.Join(violations, "; ")
+	}
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(violatedCnd))
+	return err
+}
+
+// syncDriver makes sure the driver has a default VolumeSnapshotClass. When it
+// has none, it marks the existing VolumeSnapshotClass from the driver's
+// VolumeSnapshotClassAsset as the default or creates it from the asset.
+// It returns names of default VolumeSnapshotClasses of the driver.
+func (c *Controller) syncDriver(ctx context.Context, cfg csioperatorclient.CSIOperatorConfig, vscs []*unstructured.Unstructured) ([]string, error) {
+	var defaults []string
+	for _, vsc := range vscs {
+		if vsc.GetAnnotations()[defaultVSCAnnotation] == "true" {
+			defaults = append(defaults, vsc.GetName())
+		}
+	}
+	if len(defaults) > 0 || cfg.VolumeSnapshotClassAsset == "" {
+		return defaults, nil
+	}
+
+	vscBytes, err := assets.ReadFile(cfg.VolumeSnapshotClassAsset)
+	if err != nil {
+		return nil, err
+	}
+	required := resourceread.ReadUnstructuredOrDie(vscBytes)
+	for _, vsc := range vscs {
+		if vsc.GetName() == required.GetName() {
+			return c.markDefault(ctx, cfg, vsc)
+		}
+	}
+
+	klog.V(2).Infof("CSI driver %s has no default VolumeSnapshotClass, creating %s", cfg.CSIDriverName, required.GetName())
+	if _, err := c.dynamicClient.Resource(volumeSnapshotClassGVR).Create(ctx, required, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create VolumeSnapshotClass %s: %w", required.GetName(), err)
+		}
+		// Created by the CSI driver operator in the meantime, it's marked
+		// as the default in the next sync.
+		return defaults, nil
+	}
+	c.eventRecorder.Eventf("VolumeSnapshotClassCreated", "Created default VolumeSnapshotClass %s for CSI driver %s", required.GetName(), cfg.CSIDriverName)
+	return []string{required.GetName()}, nil
+}
+
+// markDefault marks an existing VolumeSnapshotClass as the default one.
+// It returns names of default VolumeSnapshotClasses of the driver.
+func (c *Controller) markDefault(ctx context.Context, cfg csioperatorclient.CSIOperatorConfig, vsc *unstructured.Unstructured) ([]string, error) {
+	if _, found := vsc.GetAnnotations()[defaultVSCAnnotation]; found {
+		// Don't overwrite default annotation of the existing VolumeSnapshotClass!
+		// User may have made it non-default.
+		return nil, nil
+	}
+
+	klog.V(2).Infof("CSI driver %s has no default VolumeSnapshotClass, marking %s as the default", cfg.CSIDriverName, vsc.GetName())
+	vsc = vsc.DeepCopy()
+	annotations := vsc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[defaultVSCAnnotation] = "true"
+	vsc.SetAnnotations(annotations)
+	if _, err := c.dynamicClient.Resource(volumeSnapshotClassGVR).Update(ctx, vsc, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to mark VolumeSnapshotClass %s as the default: %w", vsc.GetName(), err)
+	}
+	c.eventRecorder.Eventf("VolumeSnapshotClassUpdated", "Marked VolumeSnapshotClass %s as the default for CSI driver %s", vsc.GetName(), cfg.CSIDriverName)
+	return []string{vsc.GetName()}, nil
+}
+
+func removeConditionFn(condType string) v1helpers.UpdateStatusFunc {
+	return func(oldStatus *operatorapi.OperatorStatus) error {
+		v1helpers.RemoveOperatorCondition(&oldStatus.Conditions, condType)
+		return nil
+	}
+}
@@ -0,0 +1,243 @@
+package defaultvolumesnapshotclass
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+type fakeDriversGetter struct {
+	drivers []csioperatorclient.CSIOperatorConfig
+}
+
+func (f *fakeDriversGetter) RunningDrivers() ([]csioperatorclient.CSIOperatorConfig, bool) {
+	return f.drivers, true
+}
+
+// getVolumeSnapshotClass returns a VolumeSnapshotClass with the given value of
+// the default annotation, empty isDefault means no annotation.
+func getVolumeSnapshotClass(name, driver, isDefault string) *unstructured.Unstructured {
+	vsc := &unstructured.Unstructured{}
+	vsc.SetAPIVersion("snapshot.storage.k8s.io/v1")
+	vsc.SetKind("VolumeSnapshotClass")
+	vsc.SetName(name)
+	vsc.Object["driver"] = driver
+	vsc.Object["deletionPolicy"] = "Delete"
+	if isDefault != "" {
+		vsc.SetAnnotations(map[string]string{defaultVSCAnnotation: isDefault})
+	}
+	return vsc
+}
+
+func getCRD() *apiextv1.CustomResourceDefinition {
+	return &apiextv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: snapshotClassCRDName,
+		},
+	}
+}
+
+func TestSync(t *testing.T) {
+	aws := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:            csioperatorclient.AWSEBSCSIDriverName,
+		VolumeSnapshotClassAsset: "volumesnapshotclasses/aws-ebs.yaml",
+	}
+	noSnapshots := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName: "nosnapshots.csi.example.com",
+	}
+
+	tests := []struct {
+		name               string
+		noCRD              bool
+		drivers            []csioperatorclient.CSIOperatorConfig
+		vscs               []*unstructured.Unstructured
+		expectedDefaults   map[string][]string
+		expectedVSCs       []string
+		expectedViolated   opv1.ConditionStatus
+		expectedMetricText string
+	}{
+		{
+			name:    "snapshot CRD is not installed",
+			noCRD:   true,
+			drivers: []csioperatorclient.CSIOperatorConfig{aws},
+		},
+		{
+			name:    "missing VolumeSnapshotClass is created",
+			drivers: []csioperatorclient.CSIOperatorConfig{aws, noSnapshots},
+			expectedDefaults: map[string][]string{
+				csioperatorclient.AWSEBSCSIDriverName: {"csi-aws-vsc"},
+			},
+			expectedVSCs:     []string{"csi-aws-vsc"},
+			expectedViolated: opv1.ConditionFalse,
+			expectedMetricText: `
+				# HELP default_volume_snapshot_classes [ALPHA] Number of default VolumeSnapshotClasses of a CSI driver installed by the cluster-storage-operator.
+				# TYPE default_volume_snapshot_classes gauge
+				default_volume_snapshot_classes{driver="ebs.csi.aws.com"} 1
+			`,
+		},
+		{
+			name:    "VolumeSnapshotClass of the CSI driver operator is marked as the default",
+			drivers: []csioperatorclient.CSIOperatorConfig{aws, noSnapshots},
+			vscs: []*unstructured.Unstructured{
+				getVolumeSnapshotClass("csi-aws-vsc", csioperatorclient.AWSEBSCSIDriverName, ""),
+				getVolumeSnapshotClass("user-vsc", csioperatorclient.AWSEBSCSIDriverName, ""),
+			},
+			expectedDefaults: map[string][]string{
+				csioperatorclient.AWSEBSCSIDriverName: {"csi-aws-vsc"},
+			},
+			expectedVSCs:     []string{"csi-aws-vsc", "user-vsc"},
+			expectedViolated: opv1.ConditionFalse,
+			expectedMetricText: `
+				# HELP default_volume_snapshot_classes [ALPHA] Number of default VolumeSnapshotClasses of a CSI driver installed by the cluster-storage-operator.
+				# TYPE default_volume_snapshot_classes gauge
+				default_volume_snapshot_classes{driver="ebs.csi.aws.com"} 1
+			`,
+		},
+		{
+			name:    "multiple defaults are reported",
+			drivers: []csioperatorclient.CSIOperatorConfig{aws},
+			vscs: []*unstructured.Unstructured{
+				getVolumeSnapshotClass("csi-aws-vsc", csioperatorclient.AWSEBSCSIDriverName, "true"),
+				getVolumeSnapshotClass("user-vsc", csioperatorclient.AWSEBSCSIDriverName, "true"),
+			},
+			expectedDefaults: map[string][]string{
+				csioperatorclient.AWSEBSCSIDriverName: {"csi-aws-vsc", "user-vsc"},
+			},
+			expectedViolated: opv1.ConditionTrue,
+			expectedMetricText: `
+				# HELP default_volume_snapshot_classes [ALPHA] Number of default VolumeSnapshotClasses of a CSI driver installed by the cluster-storage-operator.
+				# TYPE default_volume_snapshot_classes gauge
+				default_volume_snapshot_classes{driver="ebs.csi.aws.com"} 2
+			`,
+		},
+		{
+			name:    "non-default VolumeSnapshotClass is not overwritten",
+			drivers: []csioperatorclient.CSIOperatorConfig{aws},
+			vscs: []*unstructured.Unstructured{
+				getVolumeSnapshotClass("csi-aws-vsc", csioperatorclient.AWSEBSCSIDriverName, "false"),
+			},
+			expectedVSCs:     []string{"csi-aws-vsc"},
+			expectedViolated: opv1.ConditionTrue,
+			expectedMetricText: `
+				# HELP default_volume_snapshot_classes [ALPHA] Number of default VolumeSnapshotClasses of a CSI driver installed by the cluster-storage-operator.
+				# TYPE default_volume_snapshot_classes gauge
+				default_volume_snapshot_classes{driver="ebs.csi.aws.com"} 0
+			`,
+		},
+		{
+			name:    "VolumeSnapshotClasses of drivers not installed by CSO are ignored",
+			drivers: []csioperatorclient.CSIOperatorConfig{noSnapshots},
+			vscs: []*unstructured.Unstructured{
+				getVolumeSnapshotClass("a", "other.csi.example.com", "true"),
+				getVolumeSnapshotClass("b", "other.csi.example.com", "true"),
+			},
+			expectedDefaults: map[string][]string{
+				"other.csi.example.com": {"a", "b"},
+			},
+			expectedViolated: opv1.ConditionFalse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			}
+			if !test.noCRD {
+				initialObjects.ExtensionObjects = []runtime.Object{getCRD()}
+			}
+			for _, vsc := range test.vscs {
+				initialObjects.DynamicObjects = append(initialObjects.DynamicObjects, vsc)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			recorder := events.NewInMemoryRecorder("operator")
+			ctrl := NewController(clients, &fakeDriversGetter{drivers: test.drivers}, 0, recorder)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			defaultVolumeSnapshotClassCount.Reset()
+
+			if err := ctrl.Sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			vscList, err := clients.DynamicClient.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list VolumeSnapshotClasses: %v", err)
+			}
+			var vscNames []string
+			for _, vsc := range vscList.Items {
+				vscNames = append(vscNames, vsc.GetName())
+			}
+			sort.Strings(vscNames)
+			if test.expectedVSCs == nil {
+				for _, vsc := range test.vscs {
+					test.expectedVSCs = append(test.expectedVSCs, vsc.GetName())
+				}
+			}
+			if strings.Join(vscNames, ",") != strings.Join(test.expectedVSCs, ",") {
+				t.Errorf("expected VolumeSnapshotClasses %v, got %v", test.expectedVSCs, vscNames)
+			}
+			defaults := map[string][]string{}
+			for _, vsc := range vscList.Items {
+				if vsc.GetAnnotations()[defaultVSCAnnotation] == "true" {
+					driver, _, _ := unstructured.NestedString(vsc.Object, "driver")
+					defaults[driver] = append(defaults[driver], vsc.GetName())
+				}
+			}
+			for driver := range defaults {
+				sort.Strings(defaults[driver])
+			}
+			if len(defaults) != len(test.expectedDefaults) {
+				t.Errorf("expected default VolumeSnapshotClasses %v, got %v", test.expectedDefaults, defaults)
+			}
+			for driver, expected := range test.expectedDefaults {
+				if len(defaults[driver]) != len(expected) {
+					t.Errorf("expected default VolumeSnapshotClasses %v for driver %s, got %v", expected, driver, defaults[driver])
+					continue
+				}
+				for i := range expected {
+					if defaults[driver][i] != expected[i] {
+						t.Errorf("expected default VolumeSnapshotClasses %v for driver %s, got %v", expected, driver, defaults[driver])
+					}
+				}
+			}
+
+			if test.expectedMetricText != "" {
+				if err := testutil.CollectAndCompare(defaultVolumeSnapshotClassCount, strings.NewReader(test.expectedMetricText), "default_volume_snapshot_classes"); err != nil {
+					t.Error(err)
+				}
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, violatedConditionType)
+			if test.expectedViolated == "" {
+				if cnd != nil {
+					t.Errorf("expected no %s condition, got %+v", violatedConditionType, cnd)
+				}
+				return
+			}
+			if cnd == nil || cnd.Status != test.expectedViolated {
+				t.Errorf("expected %s=%s, got %+v", violatedConditionType, test.expectedViolated, cnd)
+			}
+		})
+	}
+}
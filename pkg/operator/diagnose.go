@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/diagnose"
+	"github.com/openshift/library-go/pkg/config/client"
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DiagnoseOptions are inputs of RunDiagnose.
+type DiagnoseOptions struct {
+	// Kubeconfig of the cluster. In-cluster config is used when empty.
+	// In HyperShift, it's kubeconfig of the guest cluster.
+	Kubeconfig string
+	// OutputFile is the gzipped tarball to write. A file with a timestamp in
+	// the current directory is used when empty.
+	OutputFile string
+	// LogLines is the number of the most recent log lines of each container.
+	LogLines int64
+	// HyperShift gathers CSI driver operators of a HyperShift hosted cluster.
+	HyperShift bool
+}
+
+// RunDiagnose gathers storage diagnostic data of a cluster into a tarball and
+// writes a summary of detected problems to out.
+func RunDiagnose(ctx context.Context, opts DiagnoseOptions, out io.Writer) error {
+	restConfig, err := client.GetKubeConfigOrInClusterConfig(opts.Kubeconfig, nil)
+	if err != nil {
+		return err
+	}
+	clients, err := csoclients.NewClients(&controllercmd.ControllerContext{
+		KubeConfig:      restConfig,
+		ProtoKubeConfig: restConfig,
+	}, resync)
+	if err != nil {
+		return err
+	}
+
+	infrastructure, err := clients.ConfigClientSet.ConfigV1().Infrastructures().Get(ctx, infraConfigName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(out, "Warning: failed to get Infrastructure: %v\n", err)
+		infrastructure = &configv1.Infrastructure{}
+	}
+	featureGates, err := getLiveFeatureGates(ctx, clients)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: failed to get feature gates, CSI driver operator decisions are not explained: %v\n", err)
+	}
+
+	bundle := diagnose.Gather(ctx, clients, diagnose.Options{
+		LogLines:      opts.LogLines,
+		DriverConfigs: offlineDriverConfigs(opts.HyperShift, infrastructure),
+		FeatureGates:  featureGates,
+	})
+
+	outputFile := opts.OutputFile
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("storage-diagnostics-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	rootDir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(outputFile), ".gz"), ".tar")
+	if err := bundle.WriteTarball(f, rootDir); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprint(out, bundle.Summary())
+	if len(bundle.Errors) > 0 {
+		fmt.Fprintf(out, "Failed to gather some data, see errors.txt in the bundle\n")
+	}
+	fmt.Fprintf(out, "Written %d files to %s\n", len(bundle.Files), outputFile)
+	return nil
+}
+
+// getLiveFeatureGates returns feature gates of the desired cluster version.
+func getLiveFeatureGates(ctx context.Context, clients *csoclients.Clients) (featuregates.FeatureGate, error) {
+	featureGate, err := clients.ConfigClientSet.ConfigV1().FeatureGates().Get(ctx, featureGateConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterVersion, err := clients.ConfigClientSet.ConfigV1().ClusterVersions().Get(ctx, clusterVersionName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getFeatureGates(featureGate, clusterVersion.Status.Desired.Version)
+}
@@ -0,0 +1,38 @@
+package diagnose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"path"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// WriteTarball writes all files of the bundle as a gzipped tarball, with all
+// files in rootDir directory.
+func (b *Bundle) WriteTarball(w io.Writer, rootDir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range sets.List(sets.KeySet(b.Files)) {
+		data := b.Files[name]
+		header := &tar.Header{
+			Name:    path.Join(rootDir, name),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,464 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultSCAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultSCAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+	defaultVSCAnnotation    = "snapshot.storage.kubernetes.io/is-default-class"
+
+	managedCloudConfigName = "kube-cloud-config"
+	infraConfigName        = "cluster"
+)
+
+var (
+	// Namespaces with CSO and CSI driver operator Deployments, Pods and Events.
+	gatheredNamespaces = []string{
+		csoclients.OperatorNamespace,
+		csoclients.CSIOperatorNamespace,
+	}
+
+	snapshotClassGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+)
+
+// Options configure what Gather collects.
+type Options struct {
+	// LogLines is the number of the most recent log lines of each container.
+	LogLines int64
+	// DriverConfigs are the CSI driver operators that CSO manages in the cluster.
+	DriverConfigs []csioperatorclient.CSIOperatorConfig
+	// FeatureGates are used to explain the driver starter decisions.
+	FeatureGates featuregates.FeatureGate
+}
+
+// Bundle is the gathered diagnostic data.
+type Bundle struct {
+	// Files keyed by a relative file path.
+	Files map[string][]byte
+	// Problems are human readable problems detected in the cluster.
+	Problems []string
+	// Errors are failures to gather some data. Gather does not stop on
+	// errors, a partial bundle is still useful.
+	Errors []string
+}
+
+type gatherer struct {
+	clients *csoclients.Clients
+	opts    Options
+	bundle  *Bundle
+}
+
+// Gather collects storage related objects, logs and events from the cluster
+// and detects common problems.
+func Gather(ctx context.Context, clients *csoclients.Clients, opts Options) *Bundle {
+	g := &gatherer{
+		clients: clients,
+		opts:    opts,
+		bundle:  &Bundle{Files: map[string][]byte{}},
+	}
+
+	g.gatherStorage(ctx)
+	installed := g.gatherClusterCSIDrivers(ctx)
+	csiDrivers := g.gatherCSIDrivers(ctx)
+	g.gatherCSINodes(ctx)
+	g.gatherStorageClasses(ctx)
+	g.gatherVolumeSnapshotClasses(ctx)
+	infrastructure := g.gatherInfrastructure(ctx)
+	g.gatherCloudConfig(ctx, infrastructure)
+	for _, ns := range gatheredNamespaces {
+		g.gatherDeployments(ctx, ns)
+		g.gatherPods(ctx, ns)
+		g.gatherEvents(ctx, ns)
+	}
+	g.gatherDrivers(ctx, infrastructure, csiDrivers, installed)
+
+	g.bundle.Files["summary.txt"] = []byte(g.bundle.Summary())
+	if len(g.bundle.Errors) > 0 {
+		g.bundle.Files["errors.txt"] = []byte(strings.Join(g.bundle.Errors, "\n") + "\n")
+	}
+	return g.bundle
+}
+
+// Summary returns the detected problems as text.
+func (b *Bundle) Summary() string {
+	if len(b.Problems) == 0 {
+		return "No problems detected\n"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Detected %d problem(s):\n", len(b.Problems))
+	for _, problem := range b.Problems {
+		fmt.Fprintf(&sb, "- %s\n", problem)
+	}
+	return sb.String()
+}
+
+func (g *gatherer) problem(format string, args ...interface{}) {
+	g.bundle.Problems = append(g.bundle.Problems, fmt.Sprintf(format, args...))
+}
+
+func (g *gatherer) error(err error, format string, args ...interface{}) {
+	g.bundle.Errors = append(g.bundle.Errors, fmt.Sprintf("%s: %v", fmt.Sprintf(format, args...), err))
+}
+
+// addObject adds a YAML file with the object. Typed objects from clients have
+// empty TypeMeta, gvk is set to make the files usable with "oc apply".
+func (g *gatherer) addObject(filePath string, obj runtime.Object, gvk schema.GroupVersionKind) {
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		g.error(err, "failed to marshal %s", filePath)
+		return
+	}
+	g.bundle.Files[filePath] = data
+}
+
+func clusterPath(resource, name string) string {
+	return path.Join("cluster-scoped-resources", resource, name+".yaml")
+}
+
+func namespacedPath(namespace, resource, name string) string {
+	return path.Join("namespaces", namespace, resource, name+".yaml")
+}
+
+func (g *gatherer) gatherStorage(ctx context.Context) {
+	storage, err := g.clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, operatorclient.GlobalConfigName, metav1.GetOptions{})
+	if err != nil {
+		g.error(err, "failed to get Storage %s", operatorclient.GlobalConfigName)
+		return
+	}
+	g.addObject(clusterPath("storages", storage.Name), storage, operatorv1.GroupVersion.WithKind("Storage"))
+	for _, problem := range conditionProblems(storage.Status.Conditions) {
+		g.problem("Storage %s: %s", storage.Name, problem)
+	}
+}
+
+// gatherClusterCSIDrivers returns names of existing ClusterCSIDrivers.
+func (g *gatherer) gatherClusterCSIDrivers(ctx context.Context) map[string]bool {
+	installed := map[string]bool{}
+	list, err := g.clients.OperatorClientSet.OperatorV1().ClusterCSIDrivers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list ClusterCSIDrivers")
+		return installed
+	}
+	for i := range list.Items {
+		ccd := &list.Items[i]
+		installed[ccd.Name] = true
+		g.addObject(clusterPath("clustercsidrivers", ccd.Name), ccd, operatorv1.GroupVersion.WithKind("ClusterCSIDriver"))
+		for _, problem := range conditionProblems(ccd.Status.Conditions) {
+			g.problem("ClusterCSIDriver %s: %s", ccd.Name, problem)
+		}
+	}
+	return installed
+}
+
+// gatherCSIDrivers returns existing CSIDrivers by their name.
+func (g *gatherer) gatherCSIDrivers(ctx context.Context) map[string]*storagev1.CSIDriver {
+	csiDrivers := map[string]*storagev1.CSIDriver{}
+	list, err := g.clients.KubeClient.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list CSIDrivers")
+		return csiDrivers
+	}
+	for i := range list.Items {
+		csiDriver := &list.Items[i]
+		csiDrivers[csiDriver.Name] = csiDriver
+		g.addObject(clusterPath("csidrivers", csiDriver.Name), csiDriver, storagev1.SchemeGroupVersion.WithKind("CSIDriver"))
+	}
+	return csiDrivers
+}
+
+func (g *gatherer) gatherCSINodes(ctx context.Context) {
+	list, err := g.clients.KubeClient.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list CSINodes")
+		return
+	}
+	for i := range list.Items {
+		g.addObject(clusterPath("csinodes", list.Items[i].Name), &list.Items[i], storagev1.SchemeGroupVersion.WithKind("CSINode"))
+	}
+}
+
+func (g *gatherer) gatherStorageClasses(ctx context.Context) {
+	list, err := g.clients.KubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list StorageClasses")
+		return
+	}
+	var defaults []string
+	for i := range list.Items {
+		sc := &list.Items[i]
+		g.addObject(clusterPath("storageclasses", sc.Name), sc, storagev1.SchemeGroupVersion.WithKind("StorageClass"))
+		if sc.Annotations[defaultSCAnnotation] == "true" || sc.Annotations[betaDefaultSCAnnotation] == "true" {
+			defaults = append(defaults, sc.Name)
+		}
+	}
+	if len(defaults) > 1 {
+		g.problem("Multiple default StorageClasses: %s", strings.Join(defaults, ", "))
+	}
+}
+
+func (g *gatherer) gatherVolumeSnapshotClasses(ctx context.Context) {
+	list, err := g.clients.DynamicClient.Resource(snapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// The CRD does not exist when the CSISnapshot capability is disabled.
+		if !errors.IsNotFound(err) {
+			g.error(err, "failed to list VolumeSnapshotClasses")
+		}
+		return
+	}
+	defaults := map[string][]string{}
+	var drivers []string
+	for i := range list.Items {
+		vsc := &list.Items[i]
+		g.addObject(clusterPath("volumesnapshotclasses", vsc.GetName()), vsc, vsc.GroupVersionKind())
+		if vsc.GetAnnotations()[defaultVSCAnnotation] != "true" {
+			continue
+		}
+		driver, _, _ := unstructured.NestedString(vsc.Object, "driver")
+		if _, found := defaults[driver]; !found {
+			drivers = append(drivers, driver)
+		}
+		defaults[driver] = append(defaults[driver], vsc.GetName())
+	}
+	for _, driver := range drivers {
+		if len(defaults[driver]) > 1 {
+			g.problem("Multiple default VolumeSnapshotClasses of driver %s: %s", driver, strings.Join(defaults[driver], ", "))
+		}
+	}
+}
+
+func (g *gatherer) gatherInfrastructure(ctx context.Context) *configv1.Infrastructure {
+	infrastructure, err := g.clients.ConfigClientSet.ConfigV1().Infrastructures().Get(ctx, infraConfigName, metav1.GetOptions{})
+	if err != nil {
+		g.error(err, "failed to get Infrastructure %s", infraConfigName)
+		return nil
+	}
+	g.addObject(clusterPath("infrastructures", infrastructure.Name), infrastructure, configv1.GroupVersion.WithKind("Infrastructure"))
+	return infrastructure
+}
+
+// gatherCloudConfig collects the cloud config provided by the user and the
+// one managed by OpenShift, with credentials redacted.
+func (g *gatherer) gatherCloudConfig(ctx context.Context, infrastructure *configv1.Infrastructure) {
+	var configMaps []types.NamespacedName
+	if infrastructure != nil && infrastructure.Spec.CloudConfig.Name != "" {
+		configMaps = append(configMaps, types.NamespacedName{Namespace: csoclients.CloudConfigNamespace, Name: infrastructure.Spec.CloudConfig.Name})
+	}
+	configMaps = append(configMaps, types.NamespacedName{Namespace: csoclients.ManagedConfigNamespace, Name: managedCloudConfigName})
+
+	for _, name := range configMaps {
+		cm, err := g.clients.KubeClient.CoreV1().ConfigMaps(name.Namespace).Get(ctx, name.Name, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				g.error(err, "failed to get ConfigMap %s", name)
+			}
+			continue
+		}
+		for key, value := range cm.Data {
+			cm.Data[key] = redactConfig(value)
+		}
+		for key := range cm.BinaryData {
+			cm.BinaryData[key] = []byte(redacted)
+		}
+		g.addObject(namespacedPath(cm.Namespace, "configmaps", cm.Name), cm, corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	}
+}
+
+func (g *gatherer) gatherDeployments(ctx context.Context, namespace string) {
+	list, err := g.clients.KubeClient.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list Deployments in %s", namespace)
+		return
+	}
+	for i := range list.Items {
+		deployment := &list.Items[i]
+		g.addObject(namespacedPath(namespace, "deployments", deployment.Name), deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))
+		if deployment.Spec.Replicas != nil && deployment.Status.AvailableReplicas < *deployment.Spec.Replicas {
+			g.problem("Deployment %s/%s has %d of %d replicas available", namespace, deployment.Name, deployment.Status.AvailableReplicas, *deployment.Spec.Replicas)
+		}
+	}
+}
+
+func (g *gatherer) gatherPods(ctx context.Context, namespace string) {
+	list, err := g.clients.KubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list Pods in %s", namespace)
+		return
+	}
+	for i := range list.Items {
+		pod := &list.Items[i]
+		g.addObject(namespacedPath(namespace, "pods", pod.Name), pod, corev1.SchemeGroupVersion.WithKind("Pod"))
+		for _, problem := range podProblems(pod) {
+			g.problem("Pod %s/%s: %s", namespace, pod.Name, problem)
+		}
+
+		for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			g.gatherLogs(ctx, pod, status.Name, false)
+			if status.RestartCount > 0 {
+				g.gatherLogs(ctx, pod, status.Name, true)
+			}
+		}
+	}
+}
+
+func (g *gatherer) gatherLogs(ctx context.Context, pod *corev1.Pod, container string, previous bool) {
+	logOptions := &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+	}
+	if g.opts.LogLines > 0 {
+		logOptions.TailLines = &g.opts.LogLines
+	}
+	logs, err := g.clients.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).DoRaw(ctx)
+	if err != nil {
+		g.error(err, "failed to get logs of container %s in Pod %s/%s", container, pod.Namespace, pod.Name)
+		return
+	}
+	fileName := container + ".log"
+	if previous {
+		fileName = container + ".previous.log"
+	}
+	g.bundle.Files[path.Join("namespaces", pod.Namespace, "pods", pod.Name, fileName)] = logs
+}
+
+func (g *gatherer) gatherEvents(ctx context.Context, namespace string) {
+	list, err := g.clients.KubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		g.error(err, "failed to list Events in %s", namespace)
+		return
+	}
+	list.APIVersion = "v1"
+	list.Kind = "EventList"
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		g.error(err, "failed to marshal Events in %s", namespace)
+		return
+	}
+	g.bundle.Files[path.Join("namespaces", namespace, "events.yaml")] = data
+}
+
+// gatherDrivers explains the driver starter decisions and collects objects
+// that CSO creates for installed CSI driver operators.
+func (g *gatherer) gatherDrivers(ctx context.Context, infrastructure *configv1.Infrastructure, csiDrivers map[string]*storagev1.CSIDriver, installed map[string]bool) {
+	var explanations []csidriveroperator.DriverExplanation
+	for _, cfg := range g.opts.DriverConfigs {
+		csiDriver := csiDrivers[cfg.CSIDriverName]
+		if csidriveroperator.IsUnsupportedCSIDriver(cfg, csiDriver) {
+			g.problem("CSIDriver %s is not provided by OpenShift", cfg.CSIDriverName)
+		}
+		if infrastructure != nil && g.opts.FeatureGates != nil {
+			explanations = append(explanations, csidriveroperator.ExplainDriverOperator(cfg, infrastructure, g.opts.FeatureGates, csiDriver, installed[cfg.CSIDriverName]))
+		}
+		if installed[cfg.CSIDriverName] {
+			for _, assetName := range cfg.StaticAssets {
+				g.gatherAssetObject(ctx, cfg, assetName)
+			}
+		}
+	}
+
+	if explanations != nil {
+		data, err := json.MarshalIndent(explanations, "", "  ")
+		if err != nil {
+			g.error(err, "failed to marshal driver decisions")
+			return
+		}
+		g.bundle.Files["drivers.json"] = data
+	}
+}
+
+// gatherAssetObject collects the object that CSO created from an asset.
+func (g *gatherer) gatherAssetObject(ctx context.Context, cfg csioperatorclient.CSIOperatorConfig, assetName string) {
+	data, err := assets.ReadFile(assetName)
+	if err != nil {
+		g.error(err, "failed to read asset %s", assetName)
+		return
+	}
+	asset := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &asset.Object); err != nil {
+		g.error(err, "failed to parse asset %s", assetName)
+		return
+	}
+	gvk := asset.GroupVersionKind()
+	mapping, err := g.clients.RestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		g.error(err, "failed to map %s", gvk)
+		return
+	}
+	obj, err := g.clients.DynamicClient.Resource(mapping.Resource).Namespace(asset.GetNamespace()).Get(ctx, asset.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			g.error(err, "failed to get %s %s", gvk.Kind, asset.GetName())
+		}
+		return
+	}
+	if gvk.Group == "" && gvk.Kind == "Secret" {
+		redactSecret(obj)
+	}
+
+	name := strings.ToLower(gvk.Kind) + "-" + obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = strings.ToLower(gvk.Kind) + "-" + obj.GetNamespace() + "-" + obj.GetName()
+	}
+	g.addObject(path.Join("drivers", cfg.ConditionPrefix, name+".yaml"), obj, gvk)
+}
+
+// conditionProblems returns problems reported in operator conditions.
+func conditionProblems(conditions []operatorv1.OperatorCondition) []string {
+	var problems []string
+	for _, cnd := range conditions {
+		switch {
+		case strings.HasSuffix(cnd.Type, operatorv1.OperatorStatusTypeDegraded) && cnd.Status == operatorv1.ConditionTrue,
+			strings.HasSuffix(cnd.Type, operatorv1.OperatorStatusTypeAvailable) && cnd.Status == operatorv1.ConditionFalse:
+			problems = append(problems, fmt.Sprintf("%s=%s: %s: %s", cnd.Type, cnd.Status, cnd.Reason, cnd.Message))
+		}
+	}
+	return problems
+}
+
+// podProblems returns reasons why a Pod is not healthy.
+func podProblems(pod *corev1.Pod) []string {
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return nil
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return []string{fmt.Sprintf("phase is %s", pod.Status.Phase)}
+	}
+	var problems []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil {
+			problems = append(problems, fmt.Sprintf("container %s is waiting: %s", status.Name, status.State.Waiting.Reason))
+		} else if !status.Ready {
+			problems = append(problems, fmt.Sprintf("container %s is not ready", status.Name))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,199 @@
+package diagnose
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGather(t *testing.T) {
+	storage := csoclients.GetCR(func(cr *opv1.Storage) *opv1.Storage {
+		cr.Status.Conditions = []opv1.OperatorCondition{
+			{Type: "AWSEBSCSIDriverOperatorCRDegraded", Status: opv1.ConditionTrue, Reason: "SyncError", Message: "boom"},
+			{Type: "AWSEBSCSIDriverOperatorCRAvailable", Status: opv1.ConditionTrue},
+		}
+		return cr
+	})
+	infrastructure := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: infraConfigName},
+		Spec: configv1.InfrastructureSpec{
+			CloudConfig: configv1.ConfigMapFileReference{Name: "cloud-provider-config", Key: "config"},
+		},
+		Status: configv1.InfrastructureStatus{PlatformStatus: &configv1.PlatformStatus{Type: configv1.AWSPlatformType}},
+	}
+	cloudConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cloud-provider-config", Namespace: csoclients.CloudConfigNamespace},
+		Data: map[string]string{
+			"config": "[Global]\nregion = us-east-1\npassword = \"hunter2\"\napplication-credential-secret=s3cr3t\n",
+		},
+	}
+	unsupportedDriver := &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-ebs-csi-driver-operator-1", Namespace: csoclients.CSIOperatorNamespace},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "operator",
+					RestartCount: 3,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				},
+			},
+		},
+	}
+
+	clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+		CoreObjects: []runtime.Object{
+			defaultStorageClass("gp2-csi"),
+			defaultStorageClass("gp3-csi"),
+			unsupportedDriver,
+			cloudConfig,
+			pod,
+		},
+		OperatorObjects: []runtime.Object{storage},
+		ConfigObjects:   []runtime.Object{infrastructure},
+	})
+
+	bundle := Gather(context.TODO(), clients, Options{
+		LogLines:      100,
+		DriverConfigs: []csioperatorclient.CSIOperatorConfig{csioperatorclient.GetAWSEBSCSIOperatorConfig(false)},
+		FeatureGates:  featuregates.NewFeatureGate(nil, nil),
+	})
+
+	expectedProblems := []string{
+		"Storage cluster: AWSEBSCSIDriverOperatorCRDegraded=True: SyncError: boom",
+		"Multiple default StorageClasses: gp2-csi, gp3-csi",
+		"Pod openshift-cluster-csi-drivers/aws-ebs-csi-driver-operator-1: container operator is waiting: CrashLoopBackOff",
+		"CSIDriver ebs.csi.aws.com is not provided by OpenShift",
+	}
+	if strings.Join(bundle.Problems, "\n") != strings.Join(expectedProblems, "\n") {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(expectedProblems, "\n"), strings.Join(bundle.Problems, "\n"))
+	}
+
+	expectedFiles := []string{
+		"cluster-scoped-resources/storages/cluster.yaml",
+		"cluster-scoped-resources/csidrivers/ebs.csi.aws.com.yaml",
+		"cluster-scoped-resources/storageclasses/gp2-csi.yaml",
+		"cluster-scoped-resources/infrastructures/cluster.yaml",
+		"namespaces/openshift-config/configmaps/cloud-provider-config.yaml",
+		"namespaces/openshift-cluster-csi-drivers/pods/aws-ebs-csi-driver-operator-1.yaml",
+		"namespaces/openshift-cluster-csi-drivers/pods/aws-ebs-csi-driver-operator-1/operator.log",
+		"namespaces/openshift-cluster-csi-drivers/pods/aws-ebs-csi-driver-operator-1/operator.previous.log",
+		"namespaces/openshift-cluster-csi-drivers/events.yaml",
+		"drivers.json",
+		"summary.txt",
+	}
+	for _, name := range expectedFiles {
+		if _, found := bundle.Files[name]; !found {
+			t.Errorf("expected file %s in the bundle", name)
+		}
+	}
+
+	config := string(bundle.Files["namespaces/openshift-config/configmaps/cloud-provider-config.yaml"])
+	if strings.Contains(config, "hunter2") || strings.Contains(config, "s3cr3t") {
+		t.Errorf("expected redacted cloud config, got:\n%s", config)
+	}
+	if !strings.Contains(config, "us-east-1") {
+		t.Errorf("expected region in cloud config, got:\n%s", config)
+	}
+	if !strings.Contains(string(bundle.Files["drivers.json"]), `"reason": "GA"`) {
+		t.Errorf("expected AWS EBS driver decision, got:\n%s", bundle.Files["drivers.json"])
+	}
+
+	buf := &bytes.Buffer{}
+	if err := bundle.WriteTarball(buf, "test"); err != nil {
+		t.Fatalf("failed to write tarball: %v", err)
+	}
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	count := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(header.Name, "test/") {
+			t.Errorf("expected file %s in test/ directory", header.Name)
+		}
+		count++
+	}
+	if count != len(bundle.Files) {
+		t.Errorf("expected %d files in the tarball, got %d", len(bundle.Files), count)
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name:     "ini",
+			config:   "[Global]\nuser = \"admin\"\npassword = \"secret value\"\n",
+			expected: "[Global]\nuser = \"admin\"\npassword = \"REDACTED\"\n",
+		},
+		{
+			name:     "json",
+			config:   "{\n  \"aadClientId\": \"id\",\n  \"aadClientSecret\": \"foo\",\n  \"aadClientCertPassword\": \"bar\"\n}",
+			expected: "{\n  \"aadClientId\": \"id\",\n  \"aadClientSecret\": \"REDACTED\",\n  \"aadClientCertPassword\": \"REDACTED\"\n}",
+		},
+		{
+			name:     "single line json",
+			config:   `{"cloud":"AzurePublicCloud","tenantId":"tenant","aadClientId":"id","aadClientSecret":"foo","useManagedIdentityExtension":false,"aadClientCertPassword":"b\"ar"}`,
+			expected: `{"cloud":"AzurePublicCloud","tenantId":"tenant","aadClientId":"id","aadClientSecret":"REDACTED","useManagedIdentityExtension":false,"aadClientCertPassword":"REDACTED"}`,
+		},
+		{
+			name:     "json with unquoted value and nested object",
+			config:   `{"tokenTTL":3600,"secretRef":{"name":"cloud-credentials"}}`,
+			expected: `{"tokenTTL":"REDACTED","secretRef":{"name":"cloud-credentials"}}`,
+		},
+		{
+			name:     "empty ini value",
+			config:   "password =\nuser = admin\n",
+			expected: "password =\nuser = admin\n",
+		},
+		{
+			name:     "yaml",
+			config:   "clouds:\n  openstack:\n    auth:\n      application_credential_secret: foo\n      username: admin\n",
+			expected: "clouds:\n  openstack:\n    auth:\n      application_credential_secret: \"REDACTED\"\n      username: admin\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := redactConfig(test.config)
+			if result != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func defaultStorageClass(name string) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{defaultSCAnnotation: "true"},
+		},
+	}
+}
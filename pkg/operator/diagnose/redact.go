@@ -0,0 +1,34 @@
+package diagnose
+
+import (
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveValue matches a value of a sensitive key in INI, YAML or JSON
+// formatted cloud configs, such as `password = "foo"` or `"aadClientSecret": "bar",`.
+// A key can be anywhere in a line, e.g. in a single line JSON. Values of
+// nested JSON objects and lists are not matched.
+var sensitiveValue = regexp.MustCompile(`(?i)((?:^|[\s{,;])"?[a-z0-9_.-]*(?:password|secret|token|credential|api-?key|private-?key)[a-z0-9_.-]*"?\s*[:=][ \t]*)("(?:[^"\\]|\\.)*"|'[^']*'|[^\s,#;{}\[\]"']+)`)
+
+// redactConfig replaces values of keys that look like credentials.
+func redactConfig(config string) string {
+	return sensitiveValue.ReplaceAllString(config, `${1}"`+redacted+`"`)
+}
+
+// redactSecret replaces all values in a Secret, only its keys are kept.
+func redactSecret(secret *unstructured.Unstructured) {
+	for _, field := range []string{"data", "stringData"} {
+		values, found, _ := unstructured.NestedMap(secret.Object, field)
+		if !found {
+			continue
+		}
+		for key := range values {
+			values[key] = redacted
+		}
+		unstructured.SetNestedMap(secret.Object, values, field)
+	}
+}
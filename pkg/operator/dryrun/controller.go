@@ -0,0 +1,94 @@
+package dryrun
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "DryRunReportController"
+
+	reportConfigMapName = "cluster-storage-operator-dry-run"
+	reportKey           = "report.yaml"
+
+	// Max. number of changes listed in the report, to keep the ConfigMap under its size limit.
+	maxReportedChanges = 200
+
+	publishInterval = 30 * time.Second
+)
+
+// report is the content of the report ConfigMap.
+type report struct {
+	Changes []Change `yaml:"changes,omitempty"`
+	// Number of changes not listed in the report because of its size.
+	Omitted int `yaml:"omitted,omitempty"`
+}
+
+// This Controller periodically publishes changes collected by a Reporter in
+// ConfigMap cluster-storage-operator-dry-run. It uses a client without
+// dry-run, the ConfigMap is the only object that CSO writes in dry-run mode.
+type ReportController struct {
+	kubeClient    kubernetes.Interface
+	namespace     string
+	reporter      *Reporter
+	eventRecorder events.Recorder
+
+	publishedGeneration int64
+}
+
+func NewReportController(
+	kubeClient kubernetes.Interface,
+	namespace string,
+	reporter *Reporter,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &ReportController{
+		kubeClient:          kubeClient,
+		namespace:           namespace,
+		reporter:            reporter,
+		eventRecorder:       eventRecorder.WithComponentSuffix(controllerName),
+		publishedGeneration: -1,
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(controllerName, c.sync)).ResyncEvery(publishInterval).ToController(controllerName, c.eventRecorder)
+}
+
+func (c *ReportController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	changes, generation := c.reporter.Changes()
+	if generation == c.publishedGeneration {
+		return nil
+	}
+	klog.V(4).Infof("Publishing %d dry-run changes", len(changes))
+
+	r := report{Changes: changes}
+	if len(changes) > maxReportedChanges {
+		r.Changes = changes[:maxReportedChanges]
+		r.Omitted = len(changes) - maxReportedChanges
+	}
+	data, err := yaml.Marshal(&r)
+	if err != nil {
+		return err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reportConfigMapName,
+			Namespace: c.namespace,
+		},
+		Data: map[string]string{
+			reportKey: string(data),
+		},
+	}
+	if _, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, cm); err != nil {
+		return err
+	}
+	c.publishedGeneration = generation
+	return nil
+}
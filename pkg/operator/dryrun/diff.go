@@ -0,0 +1,92 @@
+package dryrun
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// FieldDiff is a field with a different value in the current and the new
+// object. Old or New is nil when the field is added or removed.
+type FieldDiff struct {
+	Path string      `yaml:"path"`
+	Old  interface{} `yaml:"old,omitempty"`
+	New  interface{} `yaml:"new,omitempty"`
+}
+
+// Fields set by the API server on each write.
+var ignoredMetadata = sets.New[string](
+	"resourceVersion",
+	"generation",
+	"managedFields",
+	"creationTimestamp",
+	"uid",
+	"selfLink",
+)
+
+var simpleKey = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Diff returns fields that differ between two objects decoded from JSON,
+// sorted by their path. Metadata set by the API server is ignored.
+func Diff(current, updated map[string]interface{}) []FieldDiff {
+	current = withoutIgnoredMetadata(current)
+	updated = withoutIgnoredMetadata(updated)
+	var diffs []FieldDiff
+	diffValues("", current, updated, &diffs)
+	return diffs
+}
+
+func withoutIgnoredMetadata(obj map[string]interface{}) map[string]interface{} {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	filtered := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if !ignoredMetadata.Has(key) {
+			filtered[key] = value
+		}
+	}
+	copied := make(map[string]interface{}, len(obj))
+	for key, value := range obj {
+		copied[key] = value
+	}
+	copied["metadata"] = filtered
+	return copied
+}
+
+func diffValues(path string, current, updated interface{}, diffs *[]FieldDiff) {
+	if reflect.DeepEqual(current, updated) {
+		return
+	}
+	currentMap, currentIsMap := current.(map[string]interface{})
+	updatedMap, updatedIsMap := updated.(map[string]interface{})
+	if currentIsMap && updatedIsMap {
+		keys := sets.KeySet(currentMap).Union(sets.KeySet(updatedMap))
+		for _, key := range sets.List(keys) {
+			diffValues(fieldPath(path, key), currentMap[key], updatedMap[key], diffs)
+		}
+		return
+	}
+	currentList, currentIsList := current.([]interface{})
+	updatedList, updatedIsList := updated.([]interface{})
+	if currentIsList && updatedIsList && len(currentList) == len(updatedList) {
+		for i := range currentList {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), currentList[i], updatedList[i], diffs)
+		}
+		return
+	}
+	*diffs = append(*diffs, FieldDiff{Path: path, Old: current, New: updated})
+}
+
+func fieldPath(path, key string) string {
+	if !simpleKey.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,329 @@
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/openshift/library-go/pkg/operator/events"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Names of clusters in HyperShift. Standalone clusters use an empty name.
+	ClusterManagement = "management"
+	ClusterGuest      = "guest"
+
+	// Max. number of changed fields listed in an event.
+	maxEventPaths = 10
+)
+
+var (
+	operations = map[string]string{
+		http.MethodPost:   "Create",
+		http.MethodPut:    "Update",
+		http.MethodPatch:  "Patch",
+		http.MethodDelete: "Delete",
+	}
+
+	// Resources that are not reported. Events of the operator are not
+	// interesting and reviews are not persisted.
+	ignoredResources = sets.New[string](
+		"events",
+		"tokenreviews",
+		"subjectaccessreviews",
+		"selfsubjectaccessreviews",
+		"localsubjectaccessreviews",
+	)
+)
+
+// Change is an API request that CSO would send without dry-run.
+type Change struct {
+	Cluster     string      `yaml:"cluster,omitempty"`
+	Operation   string      `yaml:"operation"`
+	Resource    string      `yaml:"resource"`
+	Subresource string      `yaml:"subresource,omitempty"`
+	Namespace   string      `yaml:"namespace,omitempty"`
+	Name        string      `yaml:"name"`
+	Diff        []FieldDiff `yaml:"diff,omitempty"`
+	// Time when the change was first seen. Repeated requests with the same
+	// diff do not update it.
+	Since string `yaml:"since"`
+}
+
+// Reporter receives results of dry-run requests from transports created by
+// WrapTransport. It keeps the last change of each object and records an event
+// when the change is new or different from the previous one.
+type Reporter struct {
+	eventRecorder events.Recorder
+	now           func() time.Time
+
+	lock    sync.Mutex
+	changes map[string]*Change
+	// generation is increased on each new, changed or removed Change.
+	generation int64
+}
+
+func NewReporter(eventRecorder events.Recorder) *Reporter {
+	return &Reporter{
+		eventRecorder: eventRecorder.WithComponentSuffix("dry-run"),
+		now:           time.Now,
+		changes:       map[string]*Change{},
+	}
+}
+
+// ControllerContext returns a copy of controllerContext with REST configs that
+// send all mutating requests in dry-run mode. Events of the controllers that
+// use the copy are only logged, they would describe changes that did not
+// happen.
+func (r *Reporter) ControllerContext(controllerContext *controllercmd.ControllerContext, cluster string) *controllercmd.ControllerContext {
+	dryRunContext := *controllerContext
+	dryRunContext.KubeConfig = rest.CopyConfig(controllerContext.KubeConfig)
+	dryRunContext.KubeConfig.Wrap(r.WrapTransport(cluster))
+	dryRunContext.ProtoKubeConfig = rest.CopyConfig(controllerContext.ProtoKubeConfig)
+	dryRunContext.ProtoKubeConfig.Wrap(r.WrapTransport(cluster))
+	dryRunContext.EventRecorder = events.NewLoggingEventRecorder(controllerContext.EventRecorder.ComponentName())
+	return &dryRunContext
+}
+
+// WrapTransport returns a transport wrapper that adds dryRun=All to all
+// mutating requests and reports their results.
+func (r *Reporter) WrapTransport(cluster string) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{
+			delegate: rt,
+			cluster:  cluster,
+			reporter: r,
+		}
+	}
+}
+
+// Changes returns the current changes, sorted by cluster, resource, namespace
+// and name, and generation of the Reporter.
+func (r *Reporter) Changes() ([]Change, int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	keys := make([]string, 0, len(r.changes))
+	for key := range r.changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	changes := make([]Change, 0, len(keys))
+	for _, key := range keys {
+		changes = append(changes, *r.changes[key])
+	}
+	return changes, r.generation
+}
+
+func (r *Reporter) record(change *Change, hasDiff bool) {
+	key := strings.Join([]string{change.Cluster, change.Resource, change.Namespace, change.Name, change.Subresource}, "/")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	previous, found := r.changes[key]
+	if !hasDiff {
+		// The object is already up to date.
+		if found {
+			delete(r.changes, key)
+			r.generation++
+		}
+		return
+	}
+	if found && previous.Operation == change.Operation && reflect.DeepEqual(previous.Diff, change.Diff) {
+		return
+	}
+	change.Since = r.now().UTC().Format(time.RFC3339)
+	r.changes[key] = change
+	r.generation++
+	r.eventRecorder.Eventf("DryRun"+change.Operation, "%s", eventMessage(change))
+}
+
+func eventMessage(change *Change) string {
+	object := change.Name
+	if change.Namespace != "" {
+		object = change.Namespace + "/" + change.Name
+	}
+	msg := fmt.Sprintf("Would %s %s %s", strings.ToLower(change.Operation), change.Resource, object)
+	if change.Subresource != "" {
+		msg += " " + change.Subresource
+	}
+	if change.Cluster != "" {
+		msg += " in " + change.Cluster + " cluster"
+	}
+	if len(change.Diff) == 0 {
+		return msg
+	}
+	var paths []string
+	for i, d := range change.Diff {
+		if i == maxEventPaths {
+			paths = append(paths, fmt.Sprintf("and %d more", len(change.Diff)-maxEventPaths))
+			break
+		}
+		paths = append(paths, d.Path)
+	}
+	return msg + ": " + strings.Join(paths, ", ")
+}
+
+type roundTripper struct {
+	delegate http.RoundTripper
+	cluster  string
+	reporter *Reporter
+}
+
+var _ http.RoundTripper = &roundTripper{}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation, mutating := operations[req.Method]
+	if !mutating {
+		return t.delegate.RoundTrip(req)
+	}
+
+	path, pathOK := parsePath(req.URL.Path)
+	report := pathOK && !ignoredResources.Has(path.resource)
+	var current map[string]interface{}
+	if report && req.Method != http.MethodPost {
+		current = t.get(req)
+	}
+
+	dryRunReq := req.Clone(req.Context())
+	query := dryRunReq.URL.Query()
+	query.Set("dryRun", metav1.DryRunAll)
+	dryRunReq.URL.RawQuery = query.Encode()
+	// JSON is needed to compute the diff, clients decode it too.
+	dryRunReq.Header.Set("Accept", "application/json")
+
+	resp, err := t.delegate.RoundTrip(dryRunReq)
+	if err != nil || !report || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		klog.V(2).Infof("Failed to decode dry-run response of %s %s: %v", req.Method, req.URL.Path, err)
+		result = nil
+	}
+
+	change := &Change{
+		Cluster:     t.cluster,
+		Operation:   operation,
+		Resource:    path.resource,
+		Subresource: path.subresource,
+		Namespace:   path.namespace,
+		Name:        path.name,
+	}
+	hasDiff := true
+	switch req.Method {
+	case http.MethodPost:
+		if change.Name == "" && result != nil {
+			metadata, _ := result["metadata"].(map[string]interface{})
+			change.Name, _ = metadata["name"].(string)
+		}
+	case http.MethodPut, http.MethodPatch:
+		if current != nil && result != nil {
+			change.Diff = Diff(current, result)
+			hasDiff = len(change.Diff) > 0
+		}
+	}
+	t.reporter.record(change, hasDiff)
+	return resp, nil
+}
+
+// get returns the current object at the URL of the request or nil, when it
+// cannot be read.
+func (t *roundTripper) get(req *http.Request) map[string]interface{} {
+	url := *req.URL
+	url.RawQuery = ""
+	getReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil
+	}
+	getReq.Header = req.Header.Clone()
+	getReq.Header.Del("Content-Type")
+	getReq.Header.Set("Accept", "application/json")
+
+	resp, err := t.delegate.RoundTrip(getReq)
+	if err != nil {
+		klog.V(2).Infof("Failed to get %s for dry-run diff: %v", url.Path, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		klog.V(2).Infof("Failed to decode %s for dry-run diff: %v", url.Path, err)
+		return nil
+	}
+	return obj
+}
+
+// apiPath is a parsed path of an API request.
+type apiPath struct {
+	// resource with its API group, such as "deployments.apps".
+	resource    string
+	namespace   string
+	name        string
+	subresource string
+}
+
+// parsePath parses /api/v1/... and /apis/<group>/<version>/... paths.
+func parsePath(path string) (apiPath, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	group := ""
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		group = parts[1]
+		parts = parts[3:]
+	default:
+		return apiPath{}, false
+	}
+
+	p := apiPath{}
+	if len(parts) >= 2 && parts[0] == "namespaces" {
+		p.namespace = parts[1]
+		parts = parts[2:]
+		if len(parts) == 0 {
+			// The namespace itself.
+			parts = []string{"namespaces", p.namespace}
+			p.namespace = ""
+		}
+	}
+	if len(parts) == 0 || len(parts) > 3 {
+		return apiPath{}, false
+	}
+	p.resource = parts[0]
+	if group != "" {
+		p.resource += "." + group
+	}
+	if len(parts) > 1 {
+		p.name = parts[1]
+	}
+	if len(parts) > 2 {
+		p.subresource = parts[2]
+	}
+	return p, true
+}
@@ -0,0 +1,201 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+)
+
+const testNamespace = "openshift-cluster-csi-drivers"
+
+// fakeAPIServer serves a single Deployment. It returns the request body as the
+// result of PUT and POST, like the API server in dry-run.
+type fakeAPIServer struct {
+	t          *testing.T
+	deployment *appsv1.Deployment
+	nonDryRun  []string
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.URL.Query().Get("dryRun") != metav1.DryRunAll {
+		s.nonDryRun = append(s.nonDryRun, req.Method+" "+req.URL.Path)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch req.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(s.deployment)
+	case http.MethodPut, http.MethodPost:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(body, &obj); err != nil {
+			s.t.Fatal(err)
+		}
+		metadata := obj["metadata"].(map[string]interface{})
+		metadata["resourceVersion"] = "2"
+		if req.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(obj)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-ebs-csi-driver-operator", Namespace: testNamespace, ResourceVersion: "1"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](1)},
+	}
+	apiServer := &fakeAPIServer{t: t, deployment: deployment}
+	server := httptest.NewServer(apiServer)
+	defer server.Close()
+
+	recorder := events.NewInMemoryRecorder("test")
+	reporter := NewReporter(recorder)
+	config := &rest.Config{Host: server.URL}
+	config.Wrap(reporter.WrapTransport(""))
+	client := kubernetes.NewForConfigOrDie(config)
+	ctx := context.TODO()
+
+	// Update without any change
+	if _, err := client.AppsV1().Deployments(testNamespace).Update(ctx, deployment.DeepCopy(), metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if changes, _ := reporter.Changes(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	// Update with a change
+	updated := deployment.DeepCopy()
+	updated.Spec.Replicas = ptr.To[int32](2)
+	updated.Annotations = map[string]string{"operator.openshift.io/spec-hash": "abc"}
+	result, err := client.AppsV1().Deployments(testNamespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if *result.Spec.Replicas != 2 {
+		t.Errorf("expected dry-run result with 2 replicas, got %d", *result.Spec.Replicas)
+	}
+	// Repeated update with the same change does not emit another event.
+	if _, err := client.AppsV1().Deployments(testNamespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	// Create
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: testNamespace}}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	// Events are not reported
+	event := &v1.Event{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: testNamespace}}
+	if _, err := client.CoreV1().Events(testNamespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if len(apiServer.nonDryRun) != 0 {
+		t.Errorf("expected only dry-run requests, got %v", apiServer.nonDryRun)
+	}
+
+	changes, _ := reporter.Changes()
+	expectedChanges := []Change{
+		{
+			Operation: "Create",
+			Resource:  "configmaps",
+			Namespace: testNamespace,
+			Name:      "test",
+		},
+		{
+			Operation: "Update",
+			Resource:  "deployments.apps",
+			Namespace: testNamespace,
+			Name:      "aws-ebs-csi-driver-operator",
+			Diff: []FieldDiff{
+				{Path: `metadata.annotations`, New: map[string]interface{}{"operator.openshift.io/spec-hash": "abc"}},
+				{Path: "spec.replicas", Old: float64(1), New: float64(2)},
+			},
+		},
+	}
+	for i := range changes {
+		changes[i].Since = ""
+	}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("expected changes:\n%+v\ngot:\n%+v", expectedChanges, changes)
+	}
+
+	var messages []string
+	for _, e := range recorder.Events() {
+		messages = append(messages, e.Reason+": "+e.Message)
+	}
+	expectedMessages := []string{
+		"DryRunUpdate: Would update deployments.apps openshift-cluster-csi-drivers/aws-ebs-csi-driver-operator: metadata.annotations, spec.replicas",
+		"DryRunCreate: Would create configmaps openshift-cluster-csi-drivers/test",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expectedMessages, "\n") {
+		t.Errorf("expected events:\n%s\ngot:\n%s", strings.Join(expectedMessages, "\n"), strings.Join(messages, "\n"))
+	}
+
+	// The update is not needed anymore
+	apiServer.deployment = updated
+	if _, err := client.AppsV1().Deployments(testNamespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if changes, _ := reporter.Changes(); len(changes) != 1 || changes[0].Resource != "configmaps" {
+		t.Errorf("expected only the ConfigMap change, got %+v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"resourceVersion": "1",
+			"annotations":     map[string]interface{}{"a.b/c": "1"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "operator", "image": "old"},
+			},
+			"args": []interface{}{"-v=2"},
+		},
+	}
+	updated := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"resourceVersion": "2",
+			"annotations":     map[string]interface{}{"a.b/c": "2"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "operator", "image": "new"},
+			},
+			"args": []interface{}{"-v=2", "-v=4"},
+		},
+	}
+	expected := []FieldDiff{
+		{Path: `metadata.annotations["a.b/c"]`, Old: "1", New: "2"},
+		{Path: "spec.args", Old: []interface{}{"-v=2"}, New: []interface{}{"-v=2", "-v=4"}},
+		{Path: "spec.containers[0].image", Old: "old", New: "new"},
+	}
+	diff := Diff(current, updated)
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected diff:\n%+v\ngot:\n%+v", expected, diff)
+	}
+}
@@ -0,0 +1,204 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configclient "github.com/openshift/client-go/config/clientset/versioned"
+	operatorclient "github.com/openshift/client-go/operator/clientset/versioned"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	infraConfigName       = "cluster"
+	featureGateConfigName = "cluster"
+	clusterVersionName    = "version"
+
+	ExplainOutputText = "text"
+	ExplainOutputJSON = "json"
+)
+
+// ExplainOptions are inputs of RunExplainDrivers. Objects are read either
+// from the cluster in Kubeconfig or from the files.
+type ExplainOptions struct {
+	// Kubeconfig reads all objects from a live cluster. In HyperShift, it's
+	// kubeconfig of the guest cluster.
+	Kubeconfig string
+	// InfrastructureFile is a YAML file with Infrastructure "cluster".
+	// Required when Kubeconfig is empty.
+	InfrastructureFile string
+	// FeatureGateFile is a YAML file with FeatureGate "cluster" with populated
+	// status. All feature gates are disabled when empty.
+	FeatureGateFile string
+	// ReleaseVersion selects FeatureGate status of the version. Desired
+	// version from ClusterVersion is used in a live cluster when empty.
+	ReleaseVersion string
+	// CSIDriversFile is a YAML file with a list of CSIDrivers, e.g. output of
+	// "oc get csidriver -o yaml". Optional.
+	CSIDriversFile string
+	// ClusterCSIDriversFile is a YAML file with a list of ClusterCSIDrivers.
+	// Optional.
+	ClusterCSIDriversFile string
+	// HyperShift evaluates CSI driver operators of a HyperShift hosted cluster.
+	HyperShift bool
+	// Output is either ExplainOutputText or ExplainOutputJSON.
+	Output string
+}
+
+// explainInput are the cluster objects that the driver starter uses in its decisions.
+type explainInput struct {
+	infrastructure    *configv1.Infrastructure
+	featureGate       *configv1.FeatureGate
+	releaseVersion    string
+	csiDrivers        []storagev1.CSIDriver
+	clusterCSIDrivers []operatorv1.ClusterCSIDriver
+}
+
+// RunExplainDrivers prints whether the driver starter would run each CSI
+// driver operator and why.
+func RunExplainDrivers(ctx context.Context, opts ExplainOptions, out io.Writer) error {
+	if opts.Output != ExplainOutputText && opts.Output != ExplainOutputJSON {
+		return fmt.Errorf("unknown output format %q, expected %s or %s", opts.Output, ExplainOutputText, ExplainOutputJSON)
+	}
+
+	var input *explainInput
+	var err error
+	if opts.Kubeconfig != "" {
+		input, err = readLiveExplainInput(ctx, opts.Kubeconfig)
+	} else {
+		input, err = readExplainInputFiles(opts)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.ReleaseVersion != "" {
+		input.releaseVersion = opts.ReleaseVersion
+	}
+
+	explanations, err := explainDrivers(input, opts.HyperShift)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == ExplainOutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanations)
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DRIVER\tRUN\tREASON\tMESSAGE")
+	for _, e := range explanations {
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", e.Driver, e.Run, e.Reason, e.Message)
+	}
+	return w.Flush()
+}
+
+func explainDrivers(input *explainInput, hypershift bool) ([]csidriveroperator.DriverExplanation, error) {
+	featureGates, err := getFeatureGates(input.featureGate, input.releaseVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	csiDrivers := map[string]*storagev1.CSIDriver{}
+	for i := range input.csiDrivers {
+		csiDrivers[input.csiDrivers[i].Name] = &input.csiDrivers[i]
+	}
+	installed := map[string]bool{}
+	for _, ccd := range input.clusterCSIDrivers {
+		installed[ccd.Name] = true
+	}
+
+	var explanations []csidriveroperator.DriverExplanation
+	for _, cfg := range offlineDriverConfigs(hypershift, input.infrastructure) {
+		explanations = append(explanations, csidriveroperator.ExplainDriverOperator(
+			cfg, input.infrastructure, featureGates, csiDrivers[cfg.CSIDriverName], installed[cfg.CSIDriverName]))
+	}
+	return explanations, nil
+}
+
+func readExplainInputFiles(opts ExplainOptions) (*explainInput, error) {
+	if opts.InfrastructureFile == "" {
+		return nil, fmt.Errorf("either kubeconfig or infrastructure file is required")
+	}
+	input := &explainInput{
+		infrastructure: &configv1.Infrastructure{},
+	}
+	if err := readYAMLFile(opts.InfrastructureFile, input.infrastructure); err != nil {
+		return nil, err
+	}
+	if opts.FeatureGateFile != "" {
+		input.featureGate = &configv1.FeatureGate{}
+		if err := readYAMLFile(opts.FeatureGateFile, input.featureGate); err != nil {
+			return nil, err
+		}
+	}
+	if opts.CSIDriversFile != "" {
+		list := &storagev1.CSIDriverList{}
+		if err := readYAMLFile(opts.CSIDriversFile, list); err != nil {
+			return nil, err
+		}
+		input.csiDrivers = list.Items
+	}
+	if opts.ClusterCSIDriversFile != "" {
+		list := &operatorv1.ClusterCSIDriverList{}
+		if err := readYAMLFile(opts.ClusterCSIDriversFile, list); err != nil {
+			return nil, err
+		}
+		input.clusterCSIDrivers = list.Items
+	}
+	return input, nil
+}
+
+func readLiveExplainInput(ctx context.Context, kubeconfig string) (*explainInput, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	configClient, err := configclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	operatorClient, err := operatorclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &explainInput{}
+	input.infrastructure, err = configClient.ConfigV1().Infrastructures().Get(ctx, infraConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Infrastructure: %w", err)
+	}
+	input.featureGate, err = configClient.ConfigV1().FeatureGates().Get(ctx, featureGateConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FeatureGate: %w", err)
+	}
+	clusterVersion, err := configClient.ConfigV1().ClusterVersions().Get(ctx, clusterVersionName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ClusterVersion: %w", err)
+	}
+	input.releaseVersion = clusterVersion.Status.Desired.Version
+	csiDrivers, err := kubeClient.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSIDrivers: %w", err)
+	}
+	input.csiDrivers = csiDrivers.Items
+	clusterCSIDrivers, err := operatorClient.OperatorV1().ClusterCSIDrivers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ClusterCSIDrivers: %w", err)
+	}
+	input.clusterCSIDrivers = clusterCSIDrivers.Items
+	return input, nil
+}
@@ -0,0 +1,288 @@
+package networkpolicy
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	operatorNamespace   = "openshift-cluster-storage-operator"
+	csiDriversNamespace = "openshift-cluster-csi-drivers"
+	monitoringNamespace = "openshift-monitoring"
+)
+
+type pod struct {
+	namespace string
+	labels    map[string]string
+}
+
+var (
+	csoPod             = pod{operatorNamespace, map[string]string{"name": "cluster-storage-operator"}}
+	problemDetectorPod = pod{operatorNamespace, map[string]string{"name": "vsphere-problem-detector-operator"}}
+	prometheusPod      = pod{monitoringNamespace, map[string]string{"app.kubernetes.io/name": "prometheus"}}
+	thanosQuerierPod   = pod{monitoringNamespace, map[string]string{"app.kubernetes.io/name": "thanos-query"}}
+	apiServerPod       = pod{"openshift-kube-apiserver", map[string]string{"app": "openshift-kube-apiserver"}}
+	csoOperatorPod     = pod{csiDriversNamespace, map[string]string{"name": "aws-ebs-csi-driver-operator"}}
+	csoControllerPod   = pod{csiDriversNamespace, map[string]string{"app": "aws-ebs-csi-driver-controller"}}
+	olmControllerPod   = pod{csiDriversNamespace, map[string]string{"app": "aws-efs-csi-driver-controller"}}
+	nfsServerPod       = pod{"nfs", map[string]string{"app": "nfs"}}
+	// Endpoints outside of the cluster are represented as pods in a namespace without NetworkPolicies.
+	cloudEndpointPod = pod{"external", map[string]string{"app": "openstack-cinder"}}
+	proxyPod         = pod{"external", map[string]string{"app": "proxy"}}
+)
+
+// TestAssets evaluates NetworkPolicies in assets for traffic that CSO and its
+// operands need and for traffic of pods that CSO does not manage.
+func TestAssets(t *testing.T) {
+	policies := readAssetPolicies(t)
+
+	tests := []struct {
+		name     string
+		src      pod
+		dst      pod
+		port     int32
+		expected bool
+	}{
+		{
+			name:     "CSO reads vsphere-problem-detector check results",
+			src:      csoPod,
+			dst:      problemDetectorPod,
+			port:     8444,
+			expected: true,
+		},
+		{
+			name:     "CSO queries thanos-querier",
+			src:      csoPod,
+			dst:      thanosQuerierPod,
+			port:     9091,
+			expected: true,
+		},
+		{
+			name:     "Prometheus scrapes vsphere-problem-detector",
+			src:      prometheusPod,
+			dst:      problemDetectorPod,
+			port:     8444,
+			expected: true,
+		},
+		{
+			name:     "Prometheus scrapes CSO",
+			src:      prometheusPod,
+			dst:      csoPod,
+			port:     8443,
+			expected: true,
+		},
+		{
+			name:     "vsphere-problem-detector cannot reach CSO",
+			src:      problemDetectorPod,
+			dst:      csoPod,
+			port:     8443,
+			expected: false,
+		},
+		{
+			name:     "CSI driver operator reaches the API server",
+			src:      csoOperatorPod,
+			dst:      apiServerPod,
+			port:     6443,
+			expected: true,
+		},
+		{
+			name:     "Prometheus scrapes CSI driver sidecars",
+			src:      prometheusPod,
+			dst:      csoControllerPod,
+			port:     9202,
+			expected: true,
+		},
+		{
+			name:     "CSI driver installed by CSO does not receive other traffic",
+			src:      nfsServerPod,
+			dst:      csoControllerPod,
+			port:     2049,
+			expected: false,
+		},
+		{
+			name:     "CSI driver reaches a cloud endpoint on a custom port",
+			src:      csoControllerPod,
+			dst:      cloudEndpointPod,
+			port:     13776,
+			expected: true,
+		},
+		{
+			name:     "CSI driver operator reaches the cluster-wide proxy",
+			src:      csoOperatorPod,
+			dst:      proxyPod,
+			port:     3128,
+			expected: true,
+		},
+		{
+			name:     "CSO reaches the cluster-wide proxy",
+			src:      csoPod,
+			dst:      proxyPod,
+			port:     3128,
+			expected: true,
+		},
+		{
+			name:     "CSI driver installed by OLM is not isolated",
+			src:      olmControllerPod,
+			dst:      nfsServerPod,
+			port:     2049,
+			expected: true,
+		},
+		{
+			name:     "CSI driver installed by OLM receives traffic",
+			src:      apiServerPod,
+			dst:      olmControllerPod,
+			port:     9443,
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allowed := allowedBy(policies, test.src, test.dst, test.port, networkingv1.PolicyTypeEgress) &&
+				allowedBy(policies, test.dst, test.src, test.port, networkingv1.PolicyTypeIngress)
+			if allowed != test.expected {
+				t.Errorf("expected traffic allowed=%v, got %v", test.expected, allowed)
+			}
+		})
+	}
+}
+
+// TestCSIDriversNamespaceSelectors checks that no NetworkPolicy selects all
+// pods in openshift-cluster-csi-drivers, the namespace is shared with CSI
+// drivers installed by OLM.
+func TestCSIDriversNamespaceSelectors(t *testing.T) {
+	for file, policy := range readAssetPolicies(t) {
+		if policy.Namespace != csiDriversNamespace {
+			continue
+		}
+		selector := policy.Spec.PodSelector
+		if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+			t.Errorf("NetworkPolicy %s in %s selects all pods in %s", policy.Name, file, csiDriversNamespace)
+		}
+	}
+}
+
+func readAssetPolicies(t *testing.T) map[string]*networkingv1.NetworkPolicy {
+	policies := map[string]*networkingv1.NetworkPolicy{}
+	err := fs.WalkDir(assets.FS(), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return err
+		}
+		networkPolicies, _ := SplitAssets(assets.ReadFile, []string{path})
+		if len(networkPolicies) == 0 {
+			return nil
+		}
+		objBytes, err := assets.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		policy, err := ReadNetworkPolicyV1(objBytes)
+		if err != nil {
+			t.Errorf("failed to read %s: %v", path, err)
+			return nil
+		}
+		policies[path] = policy
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read assets: %v", err)
+	}
+	return policies
+}
+
+// allowedBy returns true when policies of the given type in the namespace of
+// subject allow traffic from or to peer on a TCP port. IP blocks are not
+// evaluated, peers are pods.
+func allowedBy(policies map[string]*networkingv1.NetworkPolicy, subject, peer pod, port int32, policyType networkingv1.PolicyType) bool {
+	isolated := false
+	for _, policy := range policies {
+		if policy.Namespace != subject.namespace || !hasPolicyType(policy, policyType) || !selectorMatches(&policy.Spec.PodSelector, subject.labels) {
+			continue
+		}
+		isolated = true
+		if policyType == networkingv1.PolicyTypeEgress {
+			for _, rule := range policy.Spec.Egress {
+				if peersMatch(rule.To, policy.Namespace, peer) && portsMatch(rule.Ports, port) {
+					return true
+				}
+			}
+		} else {
+			for _, rule := range policy.Spec.Ingress {
+				if peersMatch(rule.From, policy.Namespace, peer) && portsMatch(rule.Ports, port) {
+					return true
+				}
+			}
+		}
+	}
+	return !isolated
+}
+
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(selector *metav1.LabelSelector, podLabels map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(podLabels))
+}
+
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, peer pod) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, p := range peers {
+		if p.IPBlock != nil {
+			continue
+		}
+		if p.NamespaceSelector == nil {
+			if peer.namespace != policyNamespace {
+				continue
+			}
+		} else if !selectorMatches(p.NamespaceSelector, map[string]string{corev1.LabelMetadataName: peer.namespace}) {
+			continue
+		}
+		if p.PodSelector != nil && !selectorMatches(p.PodSelector, peer.labels) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func portsMatch(ports []networkingv1.NetworkPolicyPort, port int32) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		if p.Protocol != nil && *p.Protocol != corev1.ProtocolTCP {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		first := int32(p.Port.IntValue())
+		last := first
+		if p.EndPort != nil {
+			last = *p.EndPort
+		}
+		if port >= first && port <= last {
+			return true
+		}
+	}
+	return false
+}
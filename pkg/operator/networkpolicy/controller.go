@@ -0,0 +1,134 @@
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var _ factory.Controller = &NetworkPolicyController{}
+
+// NetworkPolicyController applies NetworkPolicies from given assets.
+// It produces following Conditions:
+// <name>Degraded on error
+type NetworkPolicyController struct {
+	name           string
+	assetFunc      resourceapply.AssetFunc
+	files          []string
+	kubeClient     kubernetes.Interface
+	operatorClient v1helpers.OperatorClient
+	eventRecorder  events.Recorder
+	factory        *factory.Factory
+}
+
+// NewNetworkPolicyController returns a controller that applies NetworkPolicies in
+// given assets. It re-applies them when a NetworkPolicy changes in a namespace
+// covered by kubeInformers, other namespaces are re-applied on resync.
+// Like other per-driver controllers, it adds its event handlers only in Run(),
+// so it can be created long before it's started by a ControllerManager.
+func NewNetworkPolicyController(
+	name string,
+	assetFunc resourceapply.AssetFunc,
+	files []string,
+	kubeClient kubernetes.Interface,
+	kubeInformers v1helpers.KubeInformersForNamespaces,
+	operatorClient v1helpers.OperatorClient,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration,
+) factory.Controller {
+	f := factory.New()
+	f = f.ResyncEvery(resyncInterval)
+	f = f.WithSyncDegradedOnError(operatorClient)
+	// Necessary to do initial Sync after the controller starts.
+	f = f.WithPostStartHooks(func(ctx context.Context, syncContext factory.SyncContext) error {
+		syncContext.Queue().Add(factory.DefaultQueueKey)
+		return nil
+	})
+	f = f.WithInformers(operatorClient.Informer())
+	for _, namespace := range policyNamespaces(assetFunc, files) {
+		if kubeInformers.Namespaces().Has(namespace) {
+			f = f.WithInformers(kubeInformers.InformersFor(namespace).Networking().V1().NetworkPolicies().Informer())
+		}
+	}
+
+	return &NetworkPolicyController{
+		name:           name,
+		assetFunc:      assetFunc,
+		files:          files,
+		kubeClient:     kubeClient,
+		operatorClient: operatorClient,
+		eventRecorder:  eventRecorder.WithComponentSuffix(name),
+		factory:        f,
+	}
+}
+
+func (c *NetworkPolicyController) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("NetworkPolicyController %s sync started", c.name)
+	defer klog.V(4).Infof("NetworkPolicyController %s sync finished", c.name)
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	var errs []error
+	for _, file := range c.files {
+		objBytes, err := c.assetFunc(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", file, err))
+			continue
+		}
+		required, err := ReadNetworkPolicyV1(objBytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", file, err))
+			continue
+		}
+		if _, _, err := ApplyNetworkPolicy(ctx, c.kubeClient.NetworkingV1(), c.eventRecorder, required); err != nil {
+			errs = append(errs, fmt.Errorf("%q (NetworkPolicy %s/%s): %w", file, required.Namespace, required.Name, err))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (c *NetworkPolicyController) Run(ctx context.Context, workers int) {
+	// This adds event handlers to informers.
+	ctrl := c.factory.WithSync(operatormetrics.InstrumentSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
+	ctrl.Run(ctx, workers)
+}
+
+func (c *NetworkPolicyController) Name() string {
+	return c.name
+}
+
+func policyNamespaces(assetFunc resourceapply.AssetFunc, files []string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		objBytes, err := assetFunc(file)
+		if err != nil {
+			continue
+		}
+		policy, err := ReadNetworkPolicyV1(objBytes)
+		if err != nil {
+			continue
+		}
+		if !seen[policy.Namespace] {
+			seen[policy.Namespace] = true
+			namespaces = append(namespaces, policy.Namespace)
+		}
+	}
+	return namespaces
+}
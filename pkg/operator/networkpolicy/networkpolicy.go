@@ -0,0 +1,127 @@
+package networkpolicy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	networkingclientv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
+	"k8s.io/klog/v2"
+)
+
+const networkPolicyKind = "NetworkPolicy"
+
+var (
+	networkingScheme = runtime.NewScheme()
+	networkingCodecs = serializer.NewCodecFactory(networkingScheme)
+)
+
+func init() {
+	if err := networkingv1.AddToScheme(networkingScheme); err != nil {
+		panic(err)
+	}
+}
+
+// SplitAssets splits the given assets into NetworkPolicies and the rest.
+// library-go StaticResourceController can't apply NetworkPolicies, they must
+// be applied by NetworkPolicyController.
+// Assets that can't be read are returned with the rest, so the
+// StaticResourceController reports them.
+func SplitAssets(assetFunc resourceapply.AssetFunc, files []string) (networkPolicies []string, others []string) {
+	for _, file := range files {
+		objBytes, err := assetFunc(file)
+		if err != nil {
+			others = append(others, file)
+			continue
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(objBytes), 4096).Decode(&typeMeta); err != nil {
+			others = append(others, file)
+			continue
+		}
+		if typeMeta.Kind == networkPolicyKind {
+			networkPolicies = append(networkPolicies, file)
+		} else {
+			others = append(others, file)
+		}
+	}
+	return networkPolicies, others
+}
+
+func ReadNetworkPolicyV1(objBytes []byte) (*networkingv1.NetworkPolicy, error) {
+	requiredObj, err := runtime.Decode(networkingCodecs.UniversalDecoder(networkingv1.SchemeGroupVersion), objBytes)
+	if err != nil {
+		return nil, err
+	}
+	networkPolicy, ok := requiredObj.(*networkingv1.NetworkPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected NetworkPolicy, got %T", requiredObj)
+	}
+	return networkPolicy, nil
+}
+
+// ApplyNetworkPolicy merges objectmeta and requires the spec to match.
+func ApplyNetworkPolicy(ctx context.Context, client networkingclientv1.NetworkPoliciesGetter, recorder events.Recorder, required *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, bool, error) {
+	existing, err := client.NetworkPolicies(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		requiredCopy := required.DeepCopy()
+		actual, err := client.NetworkPolicies(required.Namespace).Create(
+			ctx, resourcemerge.WithCleanLabelsAndAnnotations(requiredCopy).(*networkingv1.NetworkPolicy), metav1.CreateOptions{})
+		if err != nil {
+			recorder.Warningf("NetworkPolicyCreateFailed", "Failed to create NetworkPolicy %s/%s: %v", required.Namespace, required.Name, err)
+		} else {
+			recorder.Eventf("NetworkPolicyCreated", "Created NetworkPolicy %s/%s because it was missing", required.Namespace, required.Name)
+		}
+		return actual, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	modified := false
+	existingCopy := existing.DeepCopy()
+
+	resourcemerge.EnsureObjectMeta(&modified, &existingCopy.ObjectMeta, required.ObjectMeta)
+	contentSame := equality.Semantic.DeepEqual(existingCopy.Spec, required.Spec)
+	if contentSame && !modified {
+		return existingCopy, false, nil
+	}
+
+	existingCopy.Spec = required.Spec
+
+	if klog.V(2).Enabled() {
+		klog.Infof("NetworkPolicy %s/%s changes: %v", required.Namespace, required.Name, resourceapply.JSONPatchNoError(existing, existingCopy))
+	}
+
+	actual, err := client.NetworkPolicies(required.Namespace).Update(ctx, existingCopy, metav1.UpdateOptions{})
+	if err != nil {
+		recorder.Warningf("NetworkPolicyUpdateFailed", "Failed to update NetworkPolicy %s/%s: %v", required.Namespace, required.Name, err)
+	} else {
+		recorder.Eventf("NetworkPolicyUpdated", "Updated NetworkPolicy %s/%s because it changed", required.Namespace, required.Name)
+	}
+	return actual, true, err
+}
+
+// DeleteNetworkPolicy deletes the NetworkPolicy, a missing one is not an error.
+func DeleteNetworkPolicy(ctx context.Context, client networkingclientv1.NetworkPoliciesGetter, recorder events.Recorder, required *networkingv1.NetworkPolicy) (bool, error) {
+	err := client.NetworkPolicies(required.Namespace).Delete(ctx, required.Name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		recorder.Warningf("NetworkPolicyDeleteFailed", "Failed to delete NetworkPolicy %s/%s: %v", required.Namespace, required.Name, err)
+		return false, err
+	}
+	recorder.Eventf("NetworkPolicyDeleted", "Deleted NetworkPolicy %s/%s", required.Namespace, required.Name)
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package networkpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func getNetworkPolicy(labels map[string]string, podLabels map[string]string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "default-deny",
+			Namespace: "openshift-cluster-storage-operator",
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+func TestApplyNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		existing        []runtime.Object
+		required        *networkingv1.NetworkPolicy
+		expectModified  bool
+		expectedActions []string
+		expectedPolicy  *networkingv1.NetworkPolicy
+	}{
+		{
+			name:            "create",
+			required:        getNetworkPolicy(nil, map[string]string{"name": "cluster-storage-operator"}),
+			expectModified:  true,
+			expectedActions: []string{"get", "create"},
+			expectedPolicy:  getNetworkPolicy(nil, map[string]string{"name": "cluster-storage-operator"}),
+		},
+		{
+			name:            "update spec",
+			existing:        []runtime.Object{getNetworkPolicy(nil, nil)},
+			required:        getNetworkPolicy(nil, map[string]string{"name": "cluster-storage-operator"}),
+			expectModified:  true,
+			expectedActions: []string{"get", "update"},
+			expectedPolicy:  getNetworkPolicy(nil, map[string]string{"name": "cluster-storage-operator"}),
+		},
+		{
+			name:            "update labels",
+			existing:        []runtime.Object{getNetworkPolicy(map[string]string{"existing": "label"}, nil)},
+			required:        getNetworkPolicy(map[string]string{"required": "label"}, nil),
+			expectModified:  true,
+			expectedActions: []string{"get", "update"},
+			expectedPolicy:  getNetworkPolicy(map[string]string{"existing": "label", "required": "label"}, nil),
+		},
+		{
+			name:            "no-op",
+			existing:        []runtime.Object{getNetworkPolicy(map[string]string{"existing": "label"}, map[string]string{"name": "cluster-storage-operator"})},
+			required:        getNetworkPolicy(nil, map[string]string{"name": "cluster-storage-operator"}),
+			expectModified:  false,
+			expectedActions: []string{"get"},
+			expectedPolicy:  getNetworkPolicy(map[string]string{"existing": "label"}, map[string]string{"name": "cluster-storage-operator"}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(test.existing...)
+			recorder := events.NewInMemoryRecorder("test")
+
+			_, modified, err := ApplyNetworkPolicy(context.TODO(), client.NetworkingV1(), recorder, test.required)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if modified != test.expectModified {
+				t.Errorf("expected modified=%v, got %v", test.expectModified, modified)
+			}
+
+			var actions []string
+			for _, action := range client.Actions() {
+				actions = append(actions, action.GetVerb())
+			}
+			if !equality.Semantic.DeepEqual(actions, test.expectedActions) {
+				t.Errorf("expected actions %v, got %v", test.expectedActions, actions)
+			}
+			if len(client.Actions()) > 1 {
+				// Create and Update must send the whole required spec.
+				sent := client.Actions()[1].(clienttesting.CreateAction).GetObject().(*networkingv1.NetworkPolicy)
+				if !equality.Semantic.DeepEqual(sent.Spec, test.required.Spec) {
+					t.Errorf("expected spec %+v, got %+v", test.required.Spec, sent.Spec)
+				}
+			}
+
+			policy, err := client.NetworkingV1().NetworkPolicies(test.required.Namespace).Get(context.TODO(), test.required.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get NetworkPolicy: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(policy.Labels, test.expectedPolicy.Labels) {
+				t.Errorf("expected labels %v, got %v", test.expectedPolicy.Labels, policy.Labels)
+			}
+			if !equality.Semantic.DeepEqual(policy.Spec, test.expectedPolicy.Spec) {
+				t.Errorf("expected spec %+v, got %+v", test.expectedPolicy.Spec, policy.Spec)
+			}
+		})
+	}
+}
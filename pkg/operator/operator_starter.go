@@ -3,15 +3,28 @@ package operator
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/configobservercontroller"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/defaultstorageclass"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/defaultvolumesnapshotclass"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/dryrun"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/networkpolicy"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/pendingpvc"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/podsecurity"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/releasedpv"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/selinuxreadiness"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/upgradereadiness"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/volumeattachment"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/vsphereproblemdetector"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
@@ -24,6 +37,8 @@ import (
 	"github.com/openshift/library-go/pkg/operator/status"
 	rbacv1 "k8s.io/api/rbac/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/transport"
 	"k8s.io/klog/v2"
 )
 
@@ -32,12 +47,24 @@ type OperatorStarter interface {
 	initClient(ctx context.Context) error
 }
 
+// featureGateListener is notified when FeatureGates change.
+type featureGateListener interface {
+	SetFeatureGates(featureGates featuregates.FeatureGate)
+}
+
 type commonStarter struct {
 	controllerConfig *controllercmd.ControllerContext
+	// dryRun enables dry-run mode, CSO reports changes it would make instead of applying them.
+	dryRun         bool
+	dryRunReporter *dryrun.Reporter
 
 	eventRecorder events.Recorder
 	versionGetter status.VersionGetter
-	featureGates  featuregates.FeatureGate
+
+	// protects featureGates and featureGateListeners, FeatureGates are updated when they change in the cluster
+	featureGateLock      sync.Mutex
+	featureGates         featuregates.FeatureGate
+	featureGateListeners []featureGateListener
 
 	commonClients *csoclients.Clients
 	// array of controllers that needs to be started.
@@ -45,6 +72,9 @@ type commonStarter struct {
 }
 
 func (csr *commonStarter) initClient(ctx context.Context) error {
+	if err := csr.initDryRun(""); err != nil {
+		return err
+	}
 	clients, err := csoclients.NewClients(csr.controllerConfig, resync)
 	if err != nil {
 		return err
@@ -54,16 +84,40 @@ func (csr *commonStarter) initClient(ctx context.Context) error {
 	return nil
 }
 
+// initDryRun replaces controllerConfig with its dry-run copy in dry-run mode.
+// The report of the changes is published in the operator namespace using
+// the original config.
+func (csr *commonStarter) initDryRun(cluster string) error {
+	if !csr.dryRun {
+		return nil
+	}
+	klog.Info("Running in dry-run mode, changes are only reported")
+	kubeClient, err := kubernetes.NewForConfig(csr.controllerConfig.ProtoKubeConfig)
+	if err != nil {
+		return err
+	}
+	csr.dryRunReporter = dryrun.NewReporter(csr.controllerConfig.EventRecorder)
+	reportController := dryrun.NewReportController(
+		kubeClient,
+		csr.controllerConfig.OperatorNamespace,
+		csr.dryRunReporter,
+		csr.controllerConfig.EventRecorder)
+	csr.controllers = append(csr.controllers, reportController)
+	csr.controllerConfig = csr.dryRunReporter.ControllerContext(csr.controllerConfig, cluster)
+	return nil
+}
+
 func (csr *commonStarter) getFeatureGate(ctx context.Context) error {
 	desiredVersion := status.VersionForOperatorFromEnv()
 	missingVersion := "0.0.1-snapshot"
 
-	// By default, this will exit(0) the process if the featuregates ever change to a different set of values.
 	featureGateAccessor := featuregates.NewFeatureGateAccess(
 		desiredVersion, missingVersion,
 		csr.commonClients.ConfigInformers.Config().V1().ClusterVersions(), csr.commonClients.ConfigInformers.Config().V1().FeatureGates(),
 		csr.eventRecorder,
 	)
+	// Pass FeatureGate changes to the controllers instead of the default exit(0) of the whole process.
+	featureGateAccessor.SetChangeHandler(csr.onFeatureGatesChange)
 	go featureGateAccessor.Run(ctx)
 	go csr.commonClients.ConfigInformers.Start(ctx.Done())
 
@@ -79,10 +133,44 @@ func (csr *commonStarter) getFeatureGate(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	csr.featureGateLock.Lock()
+	defer csr.featureGateLock.Unlock()
 	csr.featureGates = featureGates
 	return nil
 }
 
+func (csr *commonStarter) onFeatureGatesChange(featureChange featuregates.FeatureChange) {
+	if featureChange.Previous == nil {
+		// The initial FeatureGates are read by getFeatureGate.
+		return
+	}
+	klog.Infof("FeatureGates changed: enabled=%v, disabled=%v", featureChange.New.Enabled, featureChange.New.Disabled)
+	featureGates := featuregates.NewFeatureGate(featureChange.New.Enabled, featureChange.New.Disabled)
+
+	csr.featureGateLock.Lock()
+	defer csr.featureGateLock.Unlock()
+	csr.featureGates = featureGates
+	for _, listener := range csr.featureGateListeners {
+		listener.SetFeatureGates(featureGates)
+	}
+}
+
+func (csr *commonStarter) currentFeatureGates() featuregates.FeatureGate {
+	csr.featureGateLock.Lock()
+	defer csr.featureGateLock.Unlock()
+	return csr.featureGates
+}
+
+// addFeatureGateListener registers the listener for FeatureGate changes. The
+// listener gets the current FeatureGates too, they may have changed since
+// the listener was created.
+func (csr *commonStarter) addFeatureGateListener(listener featureGateListener) {
+	csr.featureGateLock.Lock()
+	defer csr.featureGateLock.Unlock()
+	csr.featureGateListeners = append(csr.featureGateListeners, listener)
+	listener.SetFeatureGates(csr.featureGates)
+}
+
 func (csr *commonStarter) CreateCommonControllers() error {
 	csr.versionGetter = status.NewVersionGetter()
 	csr.versionGetter.SetVersion("operator", status.VersionForOperatorFromEnv())
@@ -93,6 +181,39 @@ func (csr *commonStarter) CreateCommonControllers() error {
 	)
 	csr.controllers = append(csr.controllers, storageClassController)
 
+	singleDefaultStorageClassController := defaultstorageclass.NewSingleDefaultController(
+		csr.commonClients,
+		csr.eventRecorder,
+	)
+	csr.controllers = append(csr.controllers, singleDefaultStorageClassController)
+
+	// StorageClasses are in the guest cluster in HyperShift, commonClients point there.
+	defaultstorageclass.RegisterMetrics(csr.commonClients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister())
+
+	// Cluster-wide storage alerts, they're in the guest cluster in HyperShift.
+	prometheusRulesController := prometheusrules.NewStorageRulesController(
+		csr.commonClients,
+		resync,
+		csr.eventRecorder,
+	)
+	csr.controllers = append(csr.controllers, prometheusRulesController)
+
+	// PVCs are in the guest cluster in HyperShift.
+	pendingPVCController := pendingpvc.NewController(
+		csr.commonClients,
+		time.Minute,
+		csr.eventRecorder,
+	)
+	csr.controllers = append(csr.controllers, pendingPVCController)
+
+	// PVs, CSIDrivers and CSINodes are in the guest cluster in HyperShift.
+	upgradeReadinessController := upgradereadiness.NewController(
+		csr.commonClients,
+		resync,
+		csr.eventRecorder,
+	)
+	csr.controllers = append(csr.controllers, upgradeReadinessController)
+
 	relatedObjects := []configv1.ObjectReference{
 		{Resource: "namespaces", Name: operatorNamespace},
 		{Resource: "namespaces", Name: csoclients.CSIOperatorNamespace},
@@ -122,7 +243,7 @@ func (csr *commonStarter) CreateCommonControllers() error {
 	logLevelController := loglevel.NewClusterOperatorLoggingController(csr.commonClients.OperatorClient, csr.eventRecorder)
 	csr.controllers = append(csr.controllers, logLevelController)
 
-	// This controller observes a config (proxy for now) and writes it to CR.Spec.ObservedConfig for later use by the operator
+	// This controller observes a config (proxy and TLS security profile) and writes it to CR.Spec.ObservedConfig for later use by the operator
 	configObserverController := configobservercontroller.NewConfigObserverController(csr.commonClients, csr.eventRecorder)
 	csr.controllers = append(csr.controllers, configObserverController)
 	return nil
@@ -139,15 +260,32 @@ func (csr *commonStarter) startControllers(ctx context.Context) {
 	<-ctx.Done()
 }
 
+// NetworkPolicies of the namespaces managed by CSO in standalone clusters.
+// Only ingress is restricted. Egress goes to the API server, the cluster-wide
+// Proxy and cloud endpoints on any port, they are not known in advance.
+// CSI drivers can add their own NetworkPolicies to their StaticAssets.
+// openshift-cluster-csi-drivers is shared with CSI drivers installed by OLM,
+// NetworkPolicies there must select only pods of CSO drivers.
+var networkPolicyAssets = []string{
+	"networkpolicies/01_operator_default_deny.yaml",
+	"networkpolicies/03_operator_allow_metrics.yaml",
+	"networkpolicies/05_problem_detector_allow_operator.yaml",
+	"networkpolicies/06_csi_driver_operators_default_deny.yaml",
+	"networkpolicies/08_csi_driver_operators_allow_metrics.yaml",
+	"networkpolicies/09_csi_drivers_default_deny.yaml",
+	"networkpolicies/11_csi_drivers_allow_metrics.yaml",
+}
+
 type StandaloneStarter struct {
 	commonStarter
 }
 
 var _ OperatorStarter = &StandaloneStarter{}
 
-func NewStandaloneStarter(controllerConfig *controllercmd.ControllerContext) OperatorStarter {
+func NewStandaloneStarter(controllerConfig *controllercmd.ControllerContext, dryRun bool) OperatorStarter {
 	ssr := &StandaloneStarter{}
 	ssr.controllerConfig = controllerConfig
+	ssr.dryRun = dryRun
 	return ssr
 }
 
@@ -166,26 +304,75 @@ func (ssr *StandaloneStarter) StartOperator(ctx context.Context) error {
 		return err
 	}
 
-	countStorageClasses(ssr.commonClients)
+	networkPolicyController := networkpolicy.NewNetworkPolicyController(
+		"StorageOperatorNetworkPolicyController",
+		assets.ReadFile,
+		networkPolicyAssets,
+		ssr.commonClients.KubeClient,
+		ssr.commonClients.KubeInformers,
+		ssr.commonClients.OperatorClient,
+		ssr.eventRecorder,
+		resync)
+	ssr.controllers = append(ssr.controllers, networkPolicyController)
 
 	csiDriverConfigs := ssr.populateConfigs(ssr.commonClients)
-	csiDriverController, _ := csidriveroperator.NewStandaloneDriverStarter(
+	csiDriverController, csiDriverStarter := csidriveroperator.NewStandaloneDriverStarter(
 		ssr.commonClients,
-		ssr.featureGates,
+		ssr.currentFeatureGates(),
 		resync,
 		ssr.versionGetter,
 		status.VersionForOperandFromEnv(),
 		ssr.eventRecorder,
 		csiDriverConfigs)
 	ssr.controllers = append(ssr.controllers, csiDriverController)
+	ssr.addFeatureGateListener(csiDriverStarter)
 
-	vsphereProblemDetector := vsphereproblemdetector.NewVSphereProblemDetectorStarter(
+	podSecurityController := podsecurity.NewController(
 		ssr.commonClients,
+		csiDriverStarter,
+		resync,
+		ssr.eventRecorder)
+	ssr.controllers = append(ssr.controllers, podSecurityController)
+
+	volumeSnapshotClassController := defaultvolumesnapshotclass.NewController(
+		ssr.commonClients,
+		csiDriverStarter,
+		resync,
+		ssr.eventRecorder)
+	ssr.controllers = append(ssr.controllers, volumeSnapshotClassController)
+
+	volumeAttachmentController := volumeattachment.NewController(
+		ssr.commonClients,
+		csiDriverStarter,
+		time.Minute,
+		ssr.eventRecorder)
+	ssr.controllers = append(ssr.controllers, volumeAttachmentController)
+
+	releasedPVController := releasedpv.NewController(
+		ssr.commonClients,
+		csiDriverStarter,
+		time.Minute,
+		ssr.eventRecorder)
+	ssr.controllers = append(ssr.controllers, releasedPVController)
+
+	// The in-cluster Prometheus is not reachable from the management cluster in HyperShift.
+	selinuxReadinessController := selinuxreadiness.NewController(
+		ssr.commonClients,
+		status.VersionForOperatorFromEnv(),
 		resync,
-		ssr.versionGetter,
-		status.VersionForOperandFromEnv(),
 		ssr.eventRecorder)
-	ssr.controllers = append(ssr.controllers, vsphereProblemDetector)
+	ssr.controllers = append(ssr.controllers, selinuxReadinessController)
+
+	for _, detectorConfig := range ssr.populateProblemDetectorConfigs() {
+		problemDetectorStarter := problemdetector.NewStarter(
+			ssr.commonClients,
+			detectorConfig,
+			resync,
+			ssr.versionGetter,
+			status.VersionForOperandFromEnv(),
+			ssr.eventRecorder)
+		ssr.controllers = append(ssr.controllers, problemDetectorStarter)
+	}
 
 	klog.Info("Starting the Informers.")
 
@@ -195,6 +382,12 @@ func (ssr *StandaloneStarter) StartOperator(ctx context.Context) error {
 	return nil
 }
 
+func (ssr *StandaloneStarter) populateProblemDetectorConfigs() []problemdetector.ProblemDetectorConfig {
+	return []problemdetector.ProblemDetectorConfig{
+		vsphereproblemdetector.GetProblemDetectorConfig(),
+	}
+}
+
 func (ssr *StandaloneStarter) populateConfigs(clients *csoclients.Clients) []csioperatorclient.CSIOperatorConfig {
 	return []csioperatorclient.CSIOperatorConfig{
 		csioperatorclient.GetAWSEBSCSIOperatorConfig(false),
@@ -216,15 +409,23 @@ type HyperShiftStarter struct {
 	mgmtClient      *csoclients.Clients
 }
 
-func NewHyperShiftStarter(controllerConfig *controllercmd.ControllerContext, guestKubeConfig string) OperatorStarter {
+func NewHyperShiftStarter(controllerConfig *controllercmd.ControllerContext, guestKubeConfig string, dryRun bool) OperatorStarter {
 	hsr := &HyperShiftStarter{}
 	hsr.controllerConfig = controllerConfig
+	hsr.dryRun = dryRun
 	hsr.guestKubeConfig = guestKubeConfig
 	return hsr
 }
 
 func (hsr *HyperShiftStarter) initClient(ctx context.Context) error {
 	controlPlaneNamespace := hsr.controllerConfig.OperatorNamespace
+	if err := hsr.initDryRun(dryrun.ClusterManagement); err != nil {
+		return err
+	}
+	var guestWrapTransport transport.WrapperFunc
+	if hsr.dryRunReporter != nil {
+		guestWrapTransport = hsr.dryRunReporter.WrapTransport(dryrun.ClusterGuest)
+	}
 
 	mgmtClients, err := csoclients.NewHypershiftMgmtClients(hsr.controllerConfig, controlPlaneNamespace, resync)
 	if err != nil {
@@ -232,7 +433,7 @@ func (hsr *HyperShiftStarter) initClient(ctx context.Context) error {
 	}
 	hsr.mgmtClient = mgmtClients
 
-	guestClients, err := csoclients.NewHypershiftGuestClients(hsr.controllerConfig, hsr.guestKubeConfig, clusterOperatorName, resync)
+	guestClients, err := csoclients.NewHypershiftGuestClients(hsr.controllerConfig, hsr.guestKubeConfig, clusterOperatorName, resync, guestWrapTransport)
 	if err != nil {
 		return err
 	}
@@ -270,10 +471,10 @@ func (hsr *HyperShiftStarter) StartOperator(ctx context.Context) error {
 		return err
 	}
 
-	csiDriverController, _ := csidriveroperator.NewHypershiftDriverStarter(
+	csiDriverController, csiDriverStarter := csidriveroperator.NewHypershiftDriverStarter(
 		hsr.commonClients,
 		hsr.mgmtClient,
-		hsr.featureGates,
+		hsr.currentFeatureGates(),
 		controlPlaneNamespace,
 		resync,
 		hsr.versionGetter,
@@ -284,6 +485,39 @@ func (hsr *HyperShiftStarter) StartOperator(ctx context.Context) error {
 	)
 
 	hsr.controllers = append(hsr.controllers, csiDriverController)
+	hsr.addFeatureGateListener(csiDriverStarter)
+
+	// CSI driver node DaemonSets run in the guest cluster
+	podSecurityController := podsecurity.NewController(
+		hsr.commonClients,
+		csiDriverStarter,
+		resync,
+		hsr.eventRecorder)
+	hsr.controllers = append(hsr.controllers, podSecurityController)
+
+	// VolumeSnapshotClasses are in the guest cluster
+	volumeSnapshotClassController := defaultvolumesnapshotclass.NewController(
+		hsr.commonClients,
+		csiDriverStarter,
+		resync,
+		hsr.eventRecorder)
+	hsr.controllers = append(hsr.controllers, volumeSnapshotClassController)
+
+	// VolumeAttachments are in the guest cluster
+	volumeAttachmentController := volumeattachment.NewController(
+		hsr.commonClients,
+		csiDriverStarter,
+		time.Minute,
+		hsr.eventRecorder)
+	hsr.controllers = append(hsr.controllers, volumeAttachmentController)
+
+	// PersistentVolumes are in the guest cluster
+	releasedPVController := releasedpv.NewController(
+		hsr.commonClients,
+		csiDriverStarter,
+		time.Minute,
+		hsr.eventRecorder)
+	hsr.controllers = append(hsr.controllers, releasedPVController)
 	klog.Info("Starting the Informers.")
 
 	csoclients.StartGuestInformers(hsr.commonClients, ctx.Done())
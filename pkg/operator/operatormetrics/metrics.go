@@ -0,0 +1,193 @@
+package operatormetrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// Metrics about CSO's own controllers.
+
+const (
+	namespace = "cso"
+)
+
+var (
+	csiDriverOperatorEnabled = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      namespace,
+			Name:           "csi_driver_operator_enabled",
+			Help:           "Whether a CSI driver operator is started by the cluster-storage-operator (1) or not (0), with the reason of the decision.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "reason"},
+	)
+
+	controllerSyncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      namespace,
+			Name:           "controller_sync_duration_seconds",
+			Help:           "Duration of sync of a cluster-storage-operator controller.",
+			Buckets:        []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	controllerSyncErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Name:           "controller_sync_errors_total",
+			Help:           "Number of failed syncs of a cluster-storage-operator controller.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	waitForOperatorDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      namespace,
+			Name:           "csi_driver_operator_wait_for_operator_duration_seconds",
+			Help:           "Time between start of a CSI driver operator and the first status it reported.",
+			Buckets:        []float64{5, 15, 30, 60, 120, 300, 600, 1200, 3600},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver"},
+	)
+
+	restMapperResets = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      namespace,
+			Name:           "restmapper_resets_total",
+			Help:           "Number of RESTMapper cache resets after a missing API was detected.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller"},
+	)
+
+	waitForOperatorDesc = metrics.NewDesc(
+		namespace+"_csi_driver_operator_wait_for_operator_seconds",
+		"How long the cluster-storage-operator has been waiting for a CSI driver operator to report its status.",
+		[]string{"driver"}, nil,
+		metrics.ALPHA,
+		"",
+	)
+
+	waiting = &waitForOperatorCollector{
+		since: map[string]time.Time{},
+		now:   time.Now,
+	}
+)
+
+func init() {
+	legacyregistry.MustRegister(
+		csiDriverOperatorEnabled,
+		controllerSyncDuration,
+		controllerSyncErrors,
+		waitForOperatorDuration,
+		restMapperResets,
+	)
+	legacyregistry.CustomMustRegister(waiting)
+}
+
+// SetCSIDriverOperatorEnabled records the decision of the CSI driver starter about the driver.
+// Only the last reason is reported.
+func SetCSIDriverOperatorEnabled(driver string, enabled bool, reason string) {
+	csiDriverOperatorEnabled.DeletePartialMatch(map[string]string{"driver": driver})
+	value := 0.0
+	if enabled {
+		value = 1.0
+	}
+	csiDriverOperatorEnabled.WithLabelValues(driver, reason).Set(value)
+}
+
+// InstrumentSync wraps sync function of a controller to record its duration and errors.
+func InstrumentSync(controller string, sync factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		start := time.Now()
+		err := sync(ctx, syncCtx)
+		controllerSyncDuration.WithLabelValues(controller).Observe(time.Since(start).Seconds())
+		if err != nil {
+			controllerSyncErrors.WithLabelValues(controller).Inc()
+		}
+		return err
+	}
+}
+
+// RESTMapperReset records that a controller reset the RESTMapper cache.
+func RESTMapperReset(controller string) {
+	restMapperResets.WithLabelValues(controller).Inc()
+}
+
+// WaitForOperatorStarted records that a CSI driver operator has not reported its status yet.
+// Repeated calls keep the original start time.
+func WaitForOperatorStarted(driver string) {
+	waiting.start(driver)
+}
+
+// WaitForOperatorFinished records that a CSI driver operator reported its status.
+func WaitForOperatorFinished(driver string) {
+	if d, found := waiting.finish(driver); found {
+		waitForOperatorDuration.WithLabelValues(driver).Observe(d.Seconds())
+	}
+}
+
+// WaitForOperatorCancelled records that a CSI driver operator was stopped
+// before it reported its status. The waiting time is not observed.
+func WaitForOperatorCancelled(driver string) {
+	waiting.cancel(driver)
+}
+
+// waitForOperatorCollector reports CSI driver operators that have not reported their status yet,
+// computing the waiting time on each scrape.
+type waitForOperatorCollector struct {
+	metrics.BaseStableCollector
+
+	lock  sync.Mutex
+	since map[string]time.Time
+	now   func() time.Time
+}
+
+var _ metrics.StableCollector = &waitForOperatorCollector{}
+
+func (c *waitForOperatorCollector) start(driver string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, found := c.since[driver]; !found {
+		c.since[driver] = c.now()
+	}
+}
+
+func (c *waitForOperatorCollector) finish(driver string) (time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	start, found := c.since[driver]
+	if !found {
+		return 0, false
+	}
+	delete(c.since, driver)
+	return c.now().Sub(start), true
+}
+
+func (c *waitForOperatorCollector) cancel(driver string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.since, driver)
+}
+
+func (c *waitForOperatorCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- waitForOperatorDesc
+}
+
+func (c *waitForOperatorCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	for driver, start := range c.since {
+		ch <- metrics.NewLazyConstMetric(waitForOperatorDesc, metrics.GaugeValue, now.Sub(start).Seconds(), driver)
+	}
+}
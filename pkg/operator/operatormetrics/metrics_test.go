@@ -0,0 +1,91 @@
+package operatormetrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestInstrumentSync(t *testing.T) {
+	controllerSyncErrors.Reset()
+	sync := func(ctx context.Context, syncCtx factory.SyncContext) error {
+		return errors.New("mock error")
+	}
+
+	err := InstrumentSync("TestController", sync)(context.TODO(), nil)
+	if err == nil {
+		t.Fatal("expected error to be passed through")
+	}
+
+	expected := `
+		# HELP cso_controller_sync_errors_total [ALPHA] Number of failed syncs of a cluster-storage-operator controller.
+		# TYPE cso_controller_sync_errors_total counter
+		cso_controller_sync_errors_total{controller="TestController"} 1
+	`
+	if err := testutil.CollectAndCompare(controllerSyncErrors, strings.NewReader(expected), "cso_controller_sync_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetCSIDriverOperatorEnabled(t *testing.T) {
+	csiDriverOperatorEnabled.Reset()
+	SetCSIDriverOperatorEnabled("ebs.csi.aws.com", false, "FeatureGateDisabled")
+	SetCSIDriverOperatorEnabled("ebs.csi.aws.com", true, "FeatureGateEnabled")
+
+	// Only the last decision is reported
+	expected := `
+		# HELP cso_csi_driver_operator_enabled [ALPHA] Whether a CSI driver operator is started by the cluster-storage-operator (1) or not (0), with the reason of the decision.
+		# TYPE cso_csi_driver_operator_enabled gauge
+		cso_csi_driver_operator_enabled{driver="ebs.csi.aws.com",reason="FeatureGateEnabled"} 1
+	`
+	if err := testutil.CollectAndCompare(csiDriverOperatorEnabled, strings.NewReader(expected), "cso_csi_driver_operator_enabled"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWaitForOperatorCollector(t *testing.T) {
+	now := time.Now()
+	c := &waitForOperatorCollector{
+		since: map[string]time.Time{},
+		now:   func() time.Time { return now },
+	}
+
+	c.start("ebs.csi.aws.com")
+	now = now.Add(30 * time.Second)
+	// Repeated start does not reset the time
+	c.start("ebs.csi.aws.com")
+	c.start("disk.csi.azure.com")
+	now = now.Add(30 * time.Second)
+
+	expected := `
+		# HELP cso_csi_driver_operator_wait_for_operator_seconds [ALPHA] How long the cluster-storage-operator has been waiting for a CSI driver operator to report its status.
+		# TYPE cso_csi_driver_operator_wait_for_operator_seconds gauge
+		cso_csi_driver_operator_wait_for_operator_seconds{driver="disk.csi.azure.com"} 30
+		cso_csi_driver_operator_wait_for_operator_seconds{driver="ebs.csi.aws.com"} 60
+	`
+	if err := testutil.CustomCollectAndCompare(c, strings.NewReader(expected), "cso_csi_driver_operator_wait_for_operator_seconds"); err != nil {
+		t.Error(err)
+	}
+
+	d, found := c.finish("ebs.csi.aws.com")
+	if !found || d != 60*time.Second {
+		t.Errorf("expected 60s wait for ebs.csi.aws.com, got %v (found: %t)", d, found)
+	}
+	if _, found := c.finish("ebs.csi.aws.com"); found {
+		t.Errorf("expected ebs.csi.aws.com to be finished")
+	}
+
+	// A stopped CSI driver operator is not reported anymore
+	c.cancel("disk.csi.azure.com")
+	if len(c.since) != 0 {
+		t.Errorf("expected no waiting CSI driver operators, got %v", c.since)
+	}
+	if _, found := c.finish("disk.csi.azure.com"); found {
+		t.Errorf("expected disk.csi.azure.com to be cancelled")
+	}
+}
@@ -0,0 +1,233 @@
+package pendingpvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "PendingPVCController"
+
+	noProvisioner          = "kubernetes.io/no-provisioner"
+	selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+
+	// Reasons of a Pending PVC, reported in cso_pvc_pending_seconds.
+	reasonNoProvisioner         = "NoProvisioner"
+	reasonStorageClassNotFound  = "StorageClassNotFound"
+	reasonWaitForFirstConsumer  = "WaitForFirstConsumer"
+	reasonProvisioningFailed    = "ProvisioningFailed"
+	reasonWaitingForProvisioner = "WaitingForProvisioner"
+	reasonUnknown               = "Unknown"
+)
+
+const (
+	// Reasons of PVC events.
+	// Emitted by a provisioner when provisioning of a volume fails.
+	eventProvisioningFailed = "ProvisioningFailed"
+	// Emitted by external-provisioner when it starts provisioning of a volume.
+	eventProvisioning = "Provisioning"
+	// Emitted by the PV controller periodically when it waits for an external provisioner.
+	eventExternalProvisioning = "ExternalProvisioning"
+)
+
+type pendingPVC struct {
+	namespace    string
+	storageClass string
+	reason       string
+	since        time.Time
+}
+
+// This Controller finds PVCs that are Pending longer than PVC_PENDING_THRESHOLD_SECONDS
+// threshold of the cluster-wide storage alerts and evaluates why they are Pending,
+// using the PVC StorageClass and PVC events. The result is reported as
+// cso_pvc_pending_seconds metric, which feeds PersistentVolumeClaimPending alert.
+// It does not produce any Conditions, PVCs of the users should not make the
+// ClusterOperator Degraded.
+type Controller struct {
+	kubeClient         kubernetes.Interface
+	pvcLister          corelisters.PersistentVolumeClaimLister
+	storageClassLister storagelisters.StorageClassLister
+	configMapLister    corelisters.ConfigMapLister
+	collector          *pendingPVCCollector
+	now                func() time.Time
+	// Events are not watched, there are too many of them. PVC events of a namespace
+	// are listed at most once per resyncInterval and cached here.
+	resyncInterval time.Duration
+	pvcEvents      map[string]*namespaceEvents
+}
+
+type namespaceEvents struct {
+	listed time.Time
+	events map[types.UID][]v1.Event
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		kubeClient:         clients.KubeClient,
+		pvcLister:          clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Lister(),
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		configMapLister:    clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		collector:          pending,
+		now:                time.Now,
+		resyncInterval:     resyncInterval,
+		pvcEvents:          map[string]*namespaceEvents{},
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(controllerName, c.sync)).WithInformers(
+		clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+	).ResyncEvery(resyncInterval).ToController(controllerName, eventRecorder.WithComponentSuffix(controllerName))
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("PendingPVCController sync started")
+	defer klog.V(4).Infof("PendingPVCController sync finished")
+
+	pvcs, err := c.pvcLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	threshold := time.Duration(prometheusrules.StorageRulesThreshold(c.configMapLister, prometheusrules.PVCPendingThresholdSeconds)) * time.Second
+	now := c.now()
+	pvcsByNamespace := map[string][]*v1.PersistentVolumeClaim{}
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase != v1.ClaimPending {
+			continue
+		}
+		if now.Sub(pvc.CreationTimestamp.Time) < threshold {
+			continue
+		}
+		pvcsByNamespace[pvc.Namespace] = append(pvcsByNamespace[pvc.Namespace], pvc)
+	}
+
+	// Forget events of namespaces without Pending PVCs.
+	for namespace := range c.pvcEvents {
+		if _, found := pvcsByNamespace[namespace]; !found {
+			delete(c.pvcEvents, namespace)
+		}
+	}
+
+	var result []pendingPVC
+	for namespace, pvcs := range pvcsByNamespace {
+		pvcEvents, err := c.getPVCEvents(ctx, namespace, now)
+		if err != nil {
+			return err
+		}
+		for _, pvc := range pvcs {
+			storageClass, reason, err := c.classify(pvc, pvcEvents[pvc.UID])
+			if err != nil {
+				return err
+			}
+			klog.V(4).Infof("PVC %s/%s is Pending since %s: %s", pvc.Namespace, pvc.Name, pvc.CreationTimestamp, reason)
+			result = append(result, pendingPVC{
+				namespace:    pvc.Namespace,
+				storageClass: storageClass,
+				reason:       reason,
+				since:        pvc.CreationTimestamp.Time,
+			})
+		}
+	}
+	c.collector.set(result)
+	return nil
+}
+
+// getPVCEvents returns events of all PVCs in the namespace, listing them only
+// when the cached ones are older than resyncInterval.
+func (c *Controller) getPVCEvents(ctx context.Context, namespace string, now time.Time) (map[types.UID][]v1.Event, error) {
+	cached, found := c.pvcEvents[namespace]
+	if found && now.Sub(cached.listed) < c.resyncInterval {
+		return cached.events, nil
+	}
+	events, err := c.listPVCEvents(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	c.pvcEvents[namespace] = &namespaceEvents{listed: now, events: events}
+	return events, nil
+}
+
+// listPVCEvents returns events of all PVCs in the namespace.
+func (c *Controller) listPVCEvents(ctx context.Context, namespace string) (map[types.UID][]v1.Event, error) {
+	selector := fields.OneTermEqualSelector("involvedObject.kind", "PersistentVolumeClaim").String()
+	eventList, err := c.kubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	pvcEvents := map[types.UID][]v1.Event{}
+	for _, event := range eventList.Items {
+		if event.InvolvedObject.Kind != "PersistentVolumeClaim" {
+			continue
+		}
+		pvcEvents[event.InvolvedObject.UID] = append(pvcEvents[event.InvolvedObject.UID], event)
+	}
+	return pvcEvents, nil
+}
+
+// classify returns StorageClass of the PVC and the reason why it is Pending.
+func (c *Controller) classify(pvc *v1.PersistentVolumeClaim, pvcEvents []v1.Event) (string, string, error) {
+	scName := ""
+	if pvc.Spec.StorageClassName != nil {
+		scName = *pvc.Spec.StorageClassName
+	}
+	if scName == "" {
+		// Waiting for a PV created by the admin.
+		return scName, reasonNoProvisioner, nil
+	}
+
+	sc, err := c.storageClassLister.Get(scName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return scName, reasonStorageClassNotFound, nil
+		}
+		return "", "", err
+	}
+	if sc.Provisioner == noProvisioner {
+		return scName, reasonNoProvisioner, nil
+	}
+	if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		if _, found := pvc.Annotations[selectedNodeAnnotation]; !found {
+			// No Pod that uses the PVC has been scheduled.
+			return scName, reasonWaitForFirstConsumer, nil
+		}
+	}
+
+	// The PV controller emits ExternalProvisioning events all the time while the PVC
+	// is Pending, so the events are evaluated by priority and not by time.
+	eventReasons := sets.New[string]()
+	for _, event := range pvcEvents {
+		eventReasons.Insert(event.Reason)
+	}
+	switch {
+	case eventReasons.Has(eventProvisioningFailed):
+		return scName, reasonProvisioningFailed, nil
+	case eventReasons.Has(eventProvisioning):
+		// The provisioner is running, but it is slow.
+		return scName, reasonUnknown, nil
+	case eventReasons.Has(eventExternalProvisioning):
+		// Nobody picked up the PVC.
+		return scName, reasonWaitingForProvisioner, nil
+	}
+	return scName, reasonUnknown, nil
+}
@@ -0,0 +1,199 @@
+package pendingpvc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func getPVC(name, storageClass string, age time.Duration, phase v1.PersistentVolumeClaimPhase, annotations map[string]string) *v1.PersistentVolumeClaim {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "test",
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			Annotations:       annotations,
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: phase},
+	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+	return pvc
+}
+
+func getStorageClass(name, provisioner string, mode storagev1.VolumeBindingMode) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		Provisioner:       provisioner,
+		VolumeBindingMode: &mode,
+	}
+}
+
+func getEvent(pvcName, reason string) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: pvcName + "-" + reason, Namespace: "test"},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      "PersistentVolumeClaim",
+			Namespace: "test",
+			Name:      pvcName,
+			UID:       types.UID(pvcName),
+		},
+		Reason: reason,
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{
+			name: "bound and young PVCs are ignored",
+			objects: []runtime.Object{
+				getStorageClass("gp3-csi", "ebs.csi.aws.com", storagev1.VolumeBindingImmediate),
+				getPVC("bound", "gp3-csi", time.Hour, v1.ClaimBound, nil),
+				getPVC("young", "gp3-csi", time.Minute, v1.ClaimPending, nil),
+			},
+		},
+		{
+			name: "StorageClass based reasons",
+			objects: []runtime.Object{
+				getStorageClass("local", noProvisioner, storagev1.VolumeBindingWaitForFirstConsumer),
+				getStorageClass("gp3-csi", "ebs.csi.aws.com", storagev1.VolumeBindingWaitForFirstConsumer),
+				getPVC("no-sc", "", time.Hour, v1.ClaimPending, nil),
+				getPVC("local", "local", time.Hour, v1.ClaimPending, nil),
+				getPVC("missing-sc", "missing", 2*time.Hour, v1.ClaimPending, nil),
+				getPVC("no-pod", "gp3-csi", time.Hour, v1.ClaimPending, nil),
+			},
+			expected: `
+				# HELP cso_pvc_pending_seconds [ALPHA] The longest time a PersistentVolumeClaim has been Pending, for PVCs Pending longer than the configured threshold.
+				# TYPE cso_pvc_pending_seconds gauge
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="NoProvisioner",storageclass=""} 3600
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="NoProvisioner",storageclass="local"} 3600
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="StorageClassNotFound",storageclass="missing"} 7200
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="WaitForFirstConsumer",storageclass="gp3-csi"} 3600
+			`,
+		},
+		{
+			name: "event based reasons",
+			objects: []runtime.Object{
+				getStorageClass("gp3-csi", "ebs.csi.aws.com", storagev1.VolumeBindingWaitForFirstConsumer),
+				getPVC("quota-1", "gp3-csi", time.Hour, v1.ClaimPending, map[string]string{selectedNodeAnnotation: "node1"}),
+				getEvent("quota-1", eventExternalProvisioning),
+				getEvent("quota-1", eventProvisioning),
+				getEvent("quota-1", eventProvisioningFailed),
+				getPVC("quota-2", "gp3-csi", 2*time.Hour, v1.ClaimPending, map[string]string{selectedNodeAnnotation: "node1"}),
+				getEvent("quota-2", eventProvisioningFailed),
+				getPVC("no-driver", "gp3-csi", time.Hour, v1.ClaimPending, map[string]string{selectedNodeAnnotation: "node1"}),
+				getEvent("no-driver", eventExternalProvisioning),
+				getPVC("slow", "gp3-csi", time.Hour, v1.ClaimPending, map[string]string{selectedNodeAnnotation: "node1"}),
+				getEvent("slow", eventExternalProvisioning),
+				getEvent("slow", eventProvisioning),
+			},
+			expected: `
+				# HELP cso_pvc_pending_seconds [ALPHA] The longest time a PersistentVolumeClaim has been Pending, for PVCs Pending longer than the configured threshold.
+				# TYPE cso_pvc_pending_seconds gauge
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="ProvisioningFailed",storageclass="gp3-csi"} 7200
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="Unknown",storageclass="gp3-csi"} 3600
+				cso_pvc_pending_seconds{pvc_namespace="test",reason="WaitingForProvisioner",storageclass="gp3-csi"} 3600
+			`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{CoreObjects: test.objects})
+			collector := &pendingPVCCollector{now: func() time.Time { return now }}
+			c := &Controller{
+				kubeClient:         clients.KubeClient,
+				pvcLister:          clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Lister(),
+				storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+				configMapLister:    clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+				collector:          collector,
+				now:                func() time.Time { return now },
+				resyncInterval:     time.Minute,
+				pvcEvents:          map[string]*namespaceEvents{},
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).WaitForCacheSync(ctx.Done())
+
+			if err := c.sync(ctx, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := testutil.CustomCollectAndCompare(collector, strings.NewReader(test.expected), "cso_pvc_pending_seconds"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestEventsListedOncePerResync(t *testing.T) {
+	clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{CoreObjects: []runtime.Object{
+		getStorageClass("gp3-csi", "ebs.csi.aws.com", storagev1.VolumeBindingImmediate),
+		getPVC("pending", "gp3-csi", time.Hour, v1.ClaimPending, nil),
+	}})
+	syncTime := now
+	c := &Controller{
+		kubeClient:         clients.KubeClient,
+		pvcLister:          clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Lister(),
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		configMapLister:    clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		collector:          &pendingPVCCollector{now: func() time.Time { return syncTime }},
+		now:                func() time.Time { return syncTime },
+		resyncInterval:     time.Minute,
+		pvcEvents:          map[string]*namespaceEvents{},
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	csoclients.StartInformers(clients, ctx.Done())
+	csoclients.WaitForSync(clients, ctx.Done())
+	clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).WaitForCacheSync(ctx.Done())
+
+	fakeClient := clients.KubeClient.(*fake.Clientset)
+	eventLists := func() int {
+		count := 0
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() == "list" && action.GetResource().Resource == "events" {
+				count++
+			}
+		}
+		return count
+	}
+
+	for _, step := range []struct {
+		elapsed       time.Duration
+		expectedLists int
+	}{
+		{elapsed: 0, expectedLists: 1},
+		{elapsed: 30 * time.Second, expectedLists: 1},
+		{elapsed: 90 * time.Second, expectedLists: 2},
+	} {
+		syncTime = now.Add(step.elapsed)
+		if err := c.sync(ctx, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lists := eventLists(); lists != step.expectedLists {
+			t.Errorf("after %s: expected %d event lists, got %d", step.elapsed, step.expectedLists, lists)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pendingpvc
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	pvcPendingDesc = metrics.NewDesc(
+		"cso_pvc_pending_seconds",
+		"The longest time a PersistentVolumeClaim has been Pending, for PVCs Pending longer than the configured threshold.",
+		// Not "namespace", it would clash with the namespace label of the scrape target.
+		[]string{"pvc_namespace", "storageclass", "reason"}, nil,
+		metrics.ALPHA,
+		"",
+	)
+
+	pending = &pendingPVCCollector{
+		now: time.Now,
+	}
+)
+
+func init() {
+	legacyregistry.CustomMustRegister(pending)
+}
+
+type pendingPVCKey struct {
+	namespace    string
+	storageClass string
+	reason       string
+}
+
+// pendingPVCCollector reports PVCs found by the last sync of the Controller,
+// computing the pending time on each scrape.
+type pendingPVCCollector struct {
+	metrics.BaseStableCollector
+
+	lock sync.Mutex
+	// The oldest creation timestamp of Pending PVCs with the given labels.
+	since map[pendingPVCKey]time.Time
+	now   func() time.Time
+}
+
+var _ metrics.StableCollector = &pendingPVCCollector{}
+
+func (c *pendingPVCCollector) set(pvcs []pendingPVC) {
+	since := map[pendingPVCKey]time.Time{}
+	for _, pvc := range pvcs {
+		key := pendingPVCKey{
+			namespace:    pvc.namespace,
+			storageClass: pvc.storageClass,
+			reason:       pvc.reason,
+		}
+		if oldest, found := since[key]; !found || pvc.since.Before(oldest) {
+			since[key] = pvc.since
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.since = since
+}
+
+func (c *pendingPVCCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- pvcPendingDesc
+}
+
+func (c *pendingPVCCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	for key, since := range c.since {
+		ch <- metrics.NewLazyConstMetric(pvcPendingDesc, metrics.GaugeValue, now.Sub(since).Seconds(), key.namespace, key.storageClass, key.reason)
+	}
+}
@@ -0,0 +1,248 @@
+package podsecurity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	conditionsPrefix = "PodSecurityLabelController"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, drift is
+	// reconciled by the controller and it should not affect the ClusterOperator.
+	driftConditionType = conditionsPrefix + "LabelsDrifted"
+
+	enforceLabel = "pod-security.kubernetes.io/enforce"
+	auditLabel   = "pod-security.kubernetes.io/audit"
+	warnLabel    = "pod-security.kubernetes.io/warn"
+
+	// Pod security level applied by CSO to the namespace. Used to tell changes made
+	// by CSO (e.g. a new CSI driver was started) from changes made by someone else.
+	appliedLevelAnnotation = "storage.openshift.io/pod-security-level"
+)
+
+var (
+	podSecurityLabels = []string{enforceLabel, auditLabel, warnLabel}
+
+	// Namespaces with CSI drivers managed by this controller.
+	operandNamespaces = []string{
+		csoclients.CSIOperatorNamespace,
+		csioperatorclient.ManilaOperandNamespace,
+	}
+
+	levelOrder = map[string]int{
+		csioperatorclient.PodSecurityLevelRestricted: 0,
+		csioperatorclient.PodSecurityLevelBaseline:   1,
+		csioperatorclient.PodSecurityLevelPrivileged: 2,
+	}
+)
+
+// This Controller applies Pod Security Admission labels to namespaces with CSI drivers.
+// The level is computed from CSI drivers that are actually running in the namespace.
+// The controller never lowers the level a namespace already has, other pods may
+// depend on it, such as CSI drivers installed by OLM in openshift-cluster-csi-drivers.
+// Namespaces without any level and without a running CSI driver get the restricted level.
+// Changes of the labels made by anyone else are reverted and reported, unless they raise the level.
+// It produces following Conditions:
+// PodSecurityLabelControllerLabelsDrifted - the labels were changed by someone else
+// and CSO reverted them.
+// PodSecurityLabelControllerDegraded - error applying the labels.
+type Controller struct {
+	operatorClient  v1helpers.OperatorClient
+	kubeClient      kubernetes.Interface
+	namespaceLister corelisters.NamespaceLister
+	driversGetter   csidriveroperator.RunningDriversGetter
+	eventRecorder   events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	driversGetter csidriveroperator.RunningDriversGetter,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:  clients.OperatorClient,
+		kubeClient:      clients.KubeClient,
+		namespaceLister: clients.KubeInformers.InformersFor("").Core().V1().Namespaces().Lister(),
+		driversGetter:   driversGetter,
+		eventRecorder:   eventRecorder.WithComponentSuffix(conditionsPrefix),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(conditionsPrefix, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().Namespaces().Informer(),
+		// ClusterCSIDrivers are created right after a CSI driver operator is started.
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer(),
+	).ResyncEvery(resyncInterval).ToController(conditionsPrefix, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("PodSecurityLabelController sync started")
+	defer klog.V(4).Infof("PodSecurityLabelController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	drivers, synced := c.driversGetter.RunningDrivers()
+	if !synced {
+		// Don't lower the level of CSI drivers that are running, but CSO does not know about them yet.
+		klog.V(4).Infof("Waiting for CSI driver operators to be evaluated")
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), 5*time.Second)
+		return nil
+	}
+	levels := requiredLevels(drivers)
+	var drifted []string
+	for _, namespace := range operandNamespaces {
+		drift, err := c.syncNamespace(ctx, namespace, levels[namespace])
+		if err != nil {
+			return err
+		}
+		if drift != "" {
+			drifted = append(drifted, drift)
+		}
+	}
+
+	driftCnd := operatorapi.OperatorCondition{
+		Type:   driftConditionType,
+		Status: operatorapi.ConditionFalse,
+	}
+	if len(drifted) > 0 {
+		driftCnd.Status = operatorapi.ConditionTrue
+		driftCnd.Reason = "LabelsReverted"
+		driftCnd.Message = strings.Join(drifted, "; ")
+	} else if existing := v1helpers.FindOperatorCondition(opStatus.Conditions, driftConditionType); existing != nil && existing.Status == operatorapi.ConditionTrue {
+		// Keep the last reported drift, the labels are already fixed and the next sync would
+		// just hide what happened.
+		driftCnd = *existing
+	}
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(driftCnd))
+	return err
+}
+
+// syncNamespace applies pod security labels with the required level to the namespace,
+// or keeps its current level, if it's higher. It returns description of a drift,
+// if someone else lowered the labels.
+func (c *Controller) syncNamespace(ctx context.Context, name string, requiredLevel string) (string, error) {
+	ns, err := c.namespaceLister.Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// The namespace is created by the CSI driver static assets (Manila) or by CVO.
+			klog.V(4).Infof("Namespace %s does not exist, skipping pod security labels", name)
+			return "", nil
+		}
+		return "", err
+	}
+
+	appliedLevel, applied := ns.Annotations[appliedLevelAnnotation]
+	// The enforce label decides what pods are admitted and what may already run
+	// in the namespace, the level applied by CSO is restored when someone lowered it.
+	level := highestLevel(requiredLevel, ns.Labels[enforceLabel], appliedLevel)
+	if level == "" {
+		level = csioperatorclient.PodSecurityLevelRestricted
+	}
+
+	var drift string
+	if applied && !hasLevel(ns, appliedLevel) && !hasLevel(ns, level) {
+		drift = fmt.Sprintf("pod security labels of namespace %s were changed to %s, reverted to %s", name, describeLabels(ns), level)
+		c.eventRecorder.Warningf("PodSecurityLabelsDrifted", "Pod security labels of namespace %s were changed to %s, reverting them to %s", name, describeLabels(ns), level)
+	}
+
+	if hasLevel(ns, level) && appliedLevel == level {
+		return drift, nil
+	}
+
+	nsCopy := ns.DeepCopy()
+	if nsCopy.Labels == nil {
+		nsCopy.Labels = map[string]string{}
+	}
+	if nsCopy.Annotations == nil {
+		nsCopy.Annotations = map[string]string{}
+	}
+	for _, label := range podSecurityLabels {
+		nsCopy.Labels[label] = level
+	}
+	nsCopy.Annotations[appliedLevelAnnotation] = level
+	if _, err := c.kubeClient.CoreV1().Namespaces().Update(ctx, nsCopy, metav1.UpdateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to set pod security level of namespace %s to %s: %w", name, level, err)
+	}
+	if appliedLevel != level {
+		c.eventRecorder.Eventf("PodSecurityLevelChanged", "Pod security level of namespace %s set to %s", name, level)
+	}
+	return drift, nil
+}
+
+// requiredLevels returns the highest pod security level required by running CSI drivers, per namespace.
+func requiredLevels(drivers []csioperatorclient.CSIOperatorConfig) map[string]string {
+	levels := map[string]string{}
+	for _, cfg := range drivers {
+		namespace := cfg.OperandNamespace
+		if namespace == "" {
+			namespace = csoclients.CSIOperatorNamespace
+		}
+		level := cfg.PodSecurityLevel
+		if level == "" {
+			level = csioperatorclient.PodSecurityLevelPrivileged
+		}
+		levels[namespace] = highestLevel(levels[namespace], level)
+	}
+	return levels
+}
+
+// highestLevel returns the highest of given pod security levels. Unknown
+// levels are ignored, it returns "" when none of the levels is known.
+func highestLevel(levels ...string) string {
+	highest := ""
+	for _, level := range levels {
+		order, known := levelOrder[level]
+		if !known {
+			continue
+		}
+		if highest == "" || order > levelOrder[highest] {
+			highest = level
+		}
+	}
+	return highest
+}
+
+func hasLevel(ns *corev1.Namespace, level string) bool {
+	for _, label := range podSecurityLabels {
+		if ns.Labels[label] != level {
+			return false
+		}
+	}
+	return true
+}
+
+func describeLabels(ns *corev1.Namespace) string {
+	var values []string
+	for _, label := range podSecurityLabels {
+		value, found := ns.Labels[label]
+		if !found {
+			value = "<unset>"
+		}
+		values = append(values, label+"="+value)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,222 @@
+package podsecurity
+
+import (
+	"context"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakeDriversGetter struct {
+	drivers []csioperatorclient.CSIOperatorConfig
+	synced  bool
+}
+
+func (f *fakeDriversGetter) RunningDrivers() ([]csioperatorclient.CSIOperatorConfig, bool) {
+	return f.drivers, f.synced
+}
+
+func getNamespace(name string, level string, appliedLevel string) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	if level != "" {
+		ns.Labels = map[string]string{
+			enforceLabel: level,
+			auditLabel:   level,
+			warnLabel:    level,
+		}
+	}
+	if appliedLevel != "" {
+		ns.Annotations = map[string]string{
+			appliedLevelAnnotation: appliedLevel,
+		}
+	}
+	return ns
+}
+
+func TestSync(t *testing.T) {
+	manila := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:    "manila.csi.openstack.org",
+		OperandNamespace: csioperatorclient.ManilaOperandNamespace,
+	}
+	cinder := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName: "cinder.csi.openstack.org",
+	}
+	baseline := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:    "baseline.csi.example.com",
+		OperandNamespace: csioperatorclient.ManilaOperandNamespace,
+		PodSecurityLevel: csioperatorclient.PodSecurityLevelBaseline,
+	}
+
+	tests := []struct {
+		name            string
+		namespaces      []*corev1.Namespace
+		drivers         []csioperatorclient.CSIOperatorConfig
+		synced          bool
+		expectedLevels  map[string]string
+		expectedDrifted opv1.ConditionStatus
+	}{
+		{
+			name:       "no running drivers",
+			namespaces: []*corev1.Namespace{getNamespace(csoclients.CSIOperatorNamespace, "", "")},
+			synced:     true,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace: csioperatorclient.PodSecurityLevelRestricted,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "static privileged level is kept without running drivers",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csoclients.CSIOperatorNamespace, csioperatorclient.PodSecurityLevelPrivileged, ""),
+			},
+			synced: true,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "level is not lowered when drivers stop",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csioperatorclient.ManilaOperandNamespace, csioperatorclient.PodSecurityLevelPrivileged, csioperatorclient.PodSecurityLevelPrivileged),
+			},
+			drivers: []csioperatorclient.CSIOperatorConfig{baseline},
+			synced:  true,
+			expectedLevels: map[string]string{
+				csioperatorclient.ManilaOperandNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "level raised by someone else is kept",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csioperatorclient.ManilaOperandNamespace, csioperatorclient.PodSecurityLevelPrivileged, csioperatorclient.PodSecurityLevelBaseline),
+			},
+			drivers: []csioperatorclient.CSIOperatorConfig{baseline},
+			synced:  true,
+			expectedLevels: map[string]string{
+				csioperatorclient.ManilaOperandNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "drivers in both namespaces",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csoclients.CSIOperatorNamespace, "", ""),
+				getNamespace(csioperatorclient.ManilaOperandNamespace, "", ""),
+			},
+			drivers: []csioperatorclient.CSIOperatorConfig{cinder, manila},
+			synced:  true,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace:          csioperatorclient.PodSecurityLevelPrivileged,
+				csioperatorclient.ManilaOperandNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "highest level wins",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csioperatorclient.ManilaOperandNamespace, "", ""),
+			},
+			drivers: []csioperatorclient.CSIOperatorConfig{baseline, manila},
+			synced:  true,
+			expectedLevels: map[string]string{
+				csioperatorclient.ManilaOperandNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionFalse,
+		},
+		{
+			name: "drift is reverted",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csoclients.CSIOperatorNamespace, csioperatorclient.PodSecurityLevelBaseline, csioperatorclient.PodSecurityLevelPrivileged),
+			},
+			drivers: []csioperatorclient.CSIOperatorConfig{cinder},
+			synced:  true,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionTrue,
+		},
+		{
+			name: "lowered level is reverted without running drivers",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csoclients.CSIOperatorNamespace, csioperatorclient.PodSecurityLevelRestricted, csioperatorclient.PodSecurityLevelPrivileged),
+			},
+			synced: true,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+			expectedDrifted: opv1.ConditionTrue,
+		},
+		{
+			name: "level is not lowered before drivers are evaluated",
+			namespaces: []*corev1.Namespace{
+				getNamespace(csoclients.CSIOperatorNamespace, csioperatorclient.PodSecurityLevelPrivileged, csioperatorclient.PodSecurityLevelPrivileged),
+			},
+			synced: false,
+			expectedLevels: map[string]string{
+				csoclients.CSIOperatorNamespace: csioperatorclient.PodSecurityLevelPrivileged,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			}
+			for _, ns := range test.namespaces {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, ns)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			recorder := events.NewInMemoryRecorder("operator")
+			ctrl := NewController(clients, &fakeDriversGetter{drivers: test.drivers, synced: test.synced}, 0, recorder)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			if err := ctrl.Sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			for name, level := range test.expectedLevels {
+				ns, err := clients.KubeClient.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("failed to get namespace %s: %v", name, err)
+				}
+				if !hasLevel(ns, level) {
+					t.Errorf("expected namespace %s to have level %s, got labels %v", name, level, ns.Labels)
+				}
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, driftConditionType)
+			if test.expectedDrifted == "" {
+				if cnd != nil {
+					t.Errorf("expected no %s condition, got %+v", driftConditionType, cnd)
+				}
+				return
+			}
+			if cnd == nil || cnd.Status != test.expectedDrifted {
+				t.Errorf("expected %s=%s, got %+v", driftConditionType, test.expectedDrifted, cnd)
+			}
+		})
+	}
+}
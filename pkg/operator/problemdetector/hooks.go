@@ -0,0 +1,62 @@
+package problemdetector
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+	"github.com/openshift/library-go/pkg/operator/loglevel"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func withReplacerHook(imageReplacer *strings.Replacer) deploymentcontroller.ManifestHookFunc {
+	return func(spec *operatorapi.OperatorSpec, deployment []byte) ([]byte, error) {
+		newDeployment := string(deployment)
+		if imageReplacer != nil {
+			newDeployment = imageReplacer.Replace(newDeployment)
+		}
+		logLevel := loglevel.LogLevelToVerbosity(spec.LogLevel)
+		newDeployment = strings.ReplaceAll(newDeployment, "${LOG_LEVEL}", strconv.Itoa(logLevel))
+		return []byte(newDeployment), nil
+	}
+}
+
+// AddObjectHash adds hashes of the Deployment dependencies as annotations of
+// the Deployment and its Pod template, so the Deployment is restarted when
+// the dependencies change.
+func AddObjectHash(deployment *appsv1.Deployment, inputHashes map[string]string) error {
+	if deployment == nil {
+		return fmt.Errorf("invalid deployment: %v", deployment)
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	for k, v := range inputHashes {
+		annotationKey := fmt.Sprintf("operator.openshift.io/dep-%s", k)
+		if len(annotationKey) > 63 {
+			hash := sha256.Sum256([]byte(k))
+			annotationKey = fmt.Sprintf("operator.openshift.io/dep-%x", hash)
+			annotationKey = annotationKey[:63]
+		}
+		deployment.Annotations[annotationKey] = v
+		deployment.Spec.Template.Annotations[annotationKey] = v
+	}
+	return nil
+}
+
+func withProxyHook() deploymentcontroller.DeploymentHookFunc {
+	return func(opSpec *operatorapi.OperatorSpec, deployment *appsv1.Deployment) error {
+		// Cannot use csidrivercontrollerservicecontroller.WithObservedProxyDeploymentHook here.
+		// It expects proxy config at spec.observedConfig.targetcsiconfig.proxy,
+		// while CSO uses spec.observedConfig.targetconfig.proxy
+		err := util.InjectObservedProxyInDeploymentContainers(deployment, opSpec)
+		return err
+	}
+}
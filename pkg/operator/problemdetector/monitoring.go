@@ -0,0 +1,170 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	ov1 "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// Not ending with Available / Degraded / Progressing / Upgradeable, an invalid
+	// user config of the detector should not affect the ClusterOperator.
+	configValidConditionSuffix = "ConfigValid"
+)
+
+type monitoringController struct {
+	name            string
+	config          ProblemDetectorConfig
+	operatorClient  v1helpers.OperatorClient
+	kubeClient      kubernetes.Interface
+	dynamicClient   dynamic.Interface
+	configMapLister v1.ConfigMapLister
+	eventRecorder   events.Recorder
+
+	clusterCSIDriverLister ov1.ClusterCSIDriverLister
+}
+
+func newMonitoringController(
+	clients *csoclients.Clients,
+	config ProblemDetectorConfig,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration) factory.Controller {
+
+	name := config.ConditionPrefix + "MonitoringController"
+	c := &monitoringController{
+		name:            name,
+		config:          config,
+		operatorClient:  clients.OperatorClient,
+		kubeClient:      clients.KubeClient,
+		dynamicClient:   clients.DynamicClient,
+		configMapLister: clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		eventRecorder:   eventRecorder.WithComponentSuffix(name),
+
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
+	}
+
+	return factory.New().
+		WithSync(operatormetrics.InstrumentSync(name, c.sync)).
+		WithInformers(
+			c.operatorClient.Informer(),
+			clients.MonitoringInformer.Monitoring().V1().ServiceMonitors().Informer(),
+			clients.MonitoringInformer.Monitoring().V1().PrometheusRules().Informer(),
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+			clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer()).
+		ResyncEvery(resyncInterval).
+		WithSyncDegradedOnError(clients.OperatorClient).
+		ToController(name, c.eventRecorder)
+}
+
+func (c *monitoringController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+	smBytes, err := assets.ReadFile(c.config.ServiceMonitorAsset)
+	if err != nil {
+		return err
+	}
+	serviceMonitor := resourceread.ReadUnstructuredOrDie(smBytes)
+	_, _, err = resourceapply.ApplyServiceMonitor(ctx, c.dynamicClient, c.eventRecorder, serviceMonitor)
+	if err != nil {
+		return err
+	}
+
+	// An invalid user config must not break alerts of the detector, default
+	// alerts are used and the error is reported in a dedicated condition.
+	var configErrors []string
+	alertsConfig := &AlertsConfig{}
+	if c.config.ParseAlertsConfig != nil {
+		parsed, err := c.config.ParseAlertsConfig(c.configMapLister)
+		if err != nil {
+			configErrors = append(configErrors, err.Error())
+		} else {
+			alertsConfig = parsed
+		}
+	}
+
+	driverRemoved := false
+	if c.config.ClusterCSIDriverName != "" {
+		ccd, err := c.clusterCSIDriverLister.Get(c.config.ClusterCSIDriverName)
+		if err != nil {
+			return err
+		}
+		driverRemoved = ccd.Spec.OperatorSpec.ManagementState == operatorapi.Removed
+	}
+
+	overrides := alertsConfig.Overrides
+	if overrides == nil {
+		overrides = &prometheusrules.Overrides{}
+	}
+	prometheusRule, err := c.config.PrometheusRule.Render(overrides)
+	if err != nil {
+		configErrors = append(configErrors, err.Error())
+		prometheusRule, err = c.config.PrometheusRule.Render(&prometheusrules.Overrides{})
+		if err != nil {
+			return err
+		}
+	}
+
+	var message string
+	if alertsConfig.Disabled || driverRemoved {
+		_, _, err = resourceapply.DeletePrometheusRule(ctx, c.dynamicClient, c.eventRecorder, prometheusRule)
+		if err != nil {
+			return err
+		}
+		message = fmt.Sprintf("%s alerts are disabled", prometheusRule.GetName())
+	} else {
+		_, _, err = resourceapply.ApplyPrometheusRule(ctx, c.dynamicClient, c.eventRecorder, prometheusRule)
+		if err != nil {
+			return err
+		}
+		message = fmt.Sprintf("%s alerts are enabled", prometheusRule.GetName())
+	}
+
+	monitoringCondition := operatorapi.OperatorCondition{
+		Type:    c.name + operatorapi.OperatorStatusTypeAvailable,
+		Status:  operatorapi.ConditionTrue,
+		Message: message,
+	}
+	configCondition := operatorapi.OperatorCondition{
+		Type:   c.config.ConditionPrefix + configValidConditionSuffix,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if len(configErrors) > 0 {
+		configCondition.Status = operatorapi.ConditionFalse
+		configCondition.Reason = "InvalidConfig"
+		configCondition.Message = fmt.Sprintf("Using default %s alerts: %s", prometheusRule.GetName(), strings.Join(configErrors, "; "))
+	}
+	if _, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient,
+		v1helpers.UpdateConditionFn(monitoringCondition),
+		v1helpers.UpdateConditionFn(configCondition),
+	); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+var prometheusRuleGVR = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"}
+
+const testConfigMapName = "test-problem-detector"
+
+// getTestConfig returns a detector that uses vsphere-problem-detector assets.
+func getTestConfig() ProblemDetectorConfig {
+	return ProblemDetectorConfig{
+		ConditionPrefix:     "TestProblemDetector",
+		ServiceMonitorAsset: "vsphere_problem_detector/11_service_monitor.yaml",
+		PrometheusRule: prometheusrules.RuleAsset{
+			File: "vsphere_problem_detector/12_prometheusrules.yaml",
+		},
+		ClusterCSIDriverName: csioperatorclient.VMwareVSphereDriverName,
+		ParseAlertsConfig: func(lister corelisters.ConfigMapLister) (*AlertsConfig, error) {
+			cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(testConfigMapName)
+			if apierrors.IsNotFound(err) {
+				return &AlertsConfig{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if cm.Data["invalid"] != "" {
+				return nil, fmt.Errorf("%s", cm.Data["invalid"])
+			}
+			cfg := &AlertsConfig{Disabled: cm.Data["alertsDisabled"] == "true"}
+			if alert := cm.Data["infoAlert"]; alert != "" {
+				cfg.Overrides = &prometheusrules.Overrides{
+					Alerts: map[string]prometheusrules.AlertOverride{alert: {Severity: "info"}},
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+func getClusterCSIDriver(managementState opv1.ManagementState) *opv1.ClusterCSIDriver {
+	return &opv1.ClusterCSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: csioperatorclient.VMwareVSphereDriverName},
+		Spec: opv1.ClusterCSIDriverSpec{
+			OperatorSpec: opv1.OperatorSpec{ManagementState: managementState},
+		},
+	}
+}
+
+func getDetectorConfigMap(key, value string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testConfigMapName, Namespace: csoclients.OperatorNamespace},
+		Data:       map[string]string{key: value},
+	}
+}
+
+func getExistingPrometheusRule() *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{}
+	rule.SetAPIVersion("monitoring.coreos.com/v1")
+	rule.SetKind("PrometheusRule")
+	rule.SetName("vsphere-problem-detector")
+	rule.SetNamespace(csoclients.OperatorNamespace)
+	return rule
+}
+
+func TestSyncPrometheusRule(t *testing.T) {
+	tests := []struct {
+		name            string
+		ccdState        opv1.ManagementState
+		configMap       *v1.ConfigMap
+		initialRules    []runtime.Object
+		expectRule      bool
+		expectedMessage string
+		expectedConfig  opv1.ConditionStatus
+	}{
+		{
+			name:            "for new rule creation",
+			ccdState:        opv1.Managed,
+			expectRule:      true,
+			expectedMessage: "vsphere-problem-detector alerts are enabled",
+			expectedConfig:  opv1.ConditionTrue,
+		},
+		{
+			name:            "alerts disabled in ConfigMap",
+			ccdState:        opv1.Managed,
+			configMap:       getDetectorConfigMap("alertsDisabled", "true"),
+			initialRules:    []runtime.Object{getExistingPrometheusRule()},
+			expectRule:      false,
+			expectedMessage: "vsphere-problem-detector alerts are disabled",
+			expectedConfig:  opv1.ConditionTrue,
+		},
+		{
+			name:            "driver removed",
+			ccdState:        opv1.Removed,
+			initialRules:    []runtime.Object{getExistingPrometheusRule()},
+			expectRule:      false,
+			expectedMessage: "vsphere-problem-detector alerts are disabled",
+			expectedConfig:  opv1.ConditionTrue,
+		},
+		{
+			name:            "alert override",
+			ccdState:        opv1.Managed,
+			configMap:       getDetectorConfigMap("infoAlert", "VSphereOpenshiftNodeHealthFail"),
+			expectRule:      true,
+			expectedMessage: "vsphere-problem-detector alerts are enabled",
+			expectedConfig:  opv1.ConditionTrue,
+		},
+		{
+			name:            "invalid config",
+			ccdState:        opv1.Managed,
+			configMap:       getDetectorConfigMap("invalid", "mock error"),
+			expectRule:      true,
+			expectedMessage: "vsphere-problem-detector alerts are enabled",
+			expectedConfig:  opv1.ConditionFalse,
+		},
+		{
+			name:            "override of unknown alert",
+			ccdState:        opv1.Managed,
+			configMap:       getDetectorConfigMap("infoAlert", "UnknownAlert"),
+			expectRule:      true,
+			expectedMessage: "vsphere-problem-detector alerts are enabled",
+			expectedConfig:  opv1.ConditionFalse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR(), getClusterCSIDriver(test.ccdState)},
+				DynamicObjects:  test.initialRules,
+			}
+			if test.configMap != nil {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, test.configMap)
+			}
+
+			clients := csoclients.NewFakeClients(initialObjects)
+			recorder := events.NewInMemoryRecorder("vsphere-client")
+			config := getTestConfig()
+			ctrl := newMonitoringController(clients, config, recorder, 0)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).WaitForCacheSync(ctx.Done())
+
+			if err := ctrl.Sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rule, err := clients.DynamicClient.Resource(prometheusRuleGVR).Namespace(csoclients.OperatorNamespace).Get(ctx, "vsphere-problem-detector", metav1.GetOptions{})
+			if test.expectRule {
+				if err != nil {
+					t.Fatalf("expected PrometheusRule to exist: %v", err)
+				}
+				groups, _, _ := unstructured.NestedSlice(rule.Object, "spec", "groups")
+				if len(groups) != 2 {
+					t.Errorf("expected 2 groups in PrometheusRule, got %d", len(groups))
+				}
+			} else if !apierrors.IsNotFound(err) {
+				t.Errorf("expected PrometheusRule to be deleted, got: %v", err)
+			}
+
+			cr, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var message string
+			var configStatus opv1.ConditionStatus
+			for _, cnd := range cr.Status.Conditions {
+				if cnd.Type == config.ConditionPrefix+"MonitoringController"+opv1.OperatorStatusTypeAvailable {
+					message = cnd.Message
+				}
+				if cnd.Type == config.ConditionPrefix+configValidConditionSuffix {
+					configStatus = cnd.Status
+				}
+			}
+			if message != test.expectedMessage {
+				t.Errorf("expected condition message %q, got %q", test.expectedMessage, message)
+			}
+			if configStatus != test.expectedConfig {
+				t.Errorf("expected %s condition status %q, got %q", configValidConditionSuffix, test.expectedConfig, configStatus)
+			}
+		})
+	}
+}
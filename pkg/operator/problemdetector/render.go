@@ -0,0 +1,82 @@
+package problemdetector
+
+import (
+	"fmt"
+	"path"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"sigs.k8s.io/yaml"
+)
+
+// Render renders objects that the Starter creates for a detector on the given
+// infrastructure, keyed by a relative file path. It returns nil when the
+// detector does not run on the infrastructure platform.
+// Hash annotations of Secrets and ConfigMaps and user configuration of the
+// detector are not rendered, they need an API server.
+func Render(config ProblemDetectorConfig, opSpec *operatorapi.OperatorSpec, infrastructure *configv1.Infrastructure) (map[string][]byte, error) {
+	var platform configv1.PlatformType
+	if infrastructure.Status.PlatformStatus != nil {
+		platform = infrastructure.Status.PlatformStatus.Type
+	}
+	if platform != config.Platform {
+		return nil, nil
+	}
+
+	dir := config.ConditionPrefix
+	files := map[string][]byte{}
+	for _, name := range append(append([]string{}, config.StaticAssets...), config.SharedStaticAssets...) {
+		data, err := assets.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Join(dir, path.Base(name))] = data
+	}
+
+	deploymentAsset, err := assets.ReadFile(config.DeploymentAsset)
+	if err != nil {
+		return nil, err
+	}
+	deploymentAsset, err = withReplacerHook(config.ImageReplacer)(opSpec, deploymentAsset)
+	if err != nil {
+		return nil, err
+	}
+	deployment := resourceread.ReadDeploymentV1OrDie(deploymentAsset)
+	if err := withProxyHook()(opSpec, deployment); err != nil {
+		return nil, err
+	}
+	if infrastructure.Status.ControlPlaneTopology == configv1.ExternalTopologyMode {
+		deployment.Spec.Template.Spec.NodeSelector = map[string]string{}
+	}
+	deployment.APIVersion = "apps/v1"
+	deployment.Kind = "Deployment"
+	data, err := yaml.Marshal(deployment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Deployment: %w", err)
+	}
+	files[path.Join(dir, path.Base(config.DeploymentAsset))] = data
+
+	if config.ServiceMonitorAsset != "" {
+		data, err := assets.ReadFile(config.ServiceMonitorAsset)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Join(dir, path.Base(config.ServiceMonitorAsset))] = data
+	}
+
+	if config.PrometheusRule.File != "" {
+		rule, err := config.PrometheusRule.Render(&prometheusrules.Overrides{})
+		if err != nil {
+			return nil, err
+		}
+		data, err := yaml.Marshal(rule.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal PrometheusRule: %w", err)
+		}
+		files[path.Join(dir, path.Base(config.PrometheusRule.File))] = data
+	}
+	return files, nil
+}
@@ -0,0 +1,312 @@
+package problemdetector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Not ending with Available / Degraded / Progressing / Upgradeable, failing
+	// checks are reported by alerts and they should not affect the ClusterOperator.
+	clusterChecksConditionSuffix = "ClusterChecksPassed"
+	nodeChecksConditionSuffix    = "NodeChecksPassed"
+
+	// Token and service CA bundle of the operator ServiceAccount.
+	tokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+	scrapeTimeout         = 30 * time.Second
+	resultsResyncInterval = time.Minute
+	// Number of failed scrapes in a row after which the metrics endpoint is
+	// reported as unreachable instead of not ready yet.
+	maxScrapeFailures = 5
+	// Max. nodes listed in a condition message for a single check.
+	maxReportedNodes = 5
+
+	checkLabel = "check"
+	nodeLabel  = "node"
+)
+
+// metricsClient reads metrics of a detector.
+type metricsClient struct {
+	url        string
+	httpClient *http.Client
+	// tokenFile with a bearer token for the detector. The token is read on each
+	// scrape, because it is rotated by kubelet.
+	tokenFile string
+}
+
+// newMetricsClient returns a client of the detector metrics endpoint,
+// authenticated as the operator ServiceAccount. The endpoint is served with
+// a service-ca signed certificate.
+func newMetricsClient(url string) (*metricsClient, error) {
+	return newMetricsClientWithFiles(url, serviceCAFile, tokenFile)
+}
+
+func newMetricsClientWithFiles(url, caFile, tokenFile string) (*metricsClient, error) {
+	caData, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &metricsClient{
+		url:        url,
+		httpClient: &http.Client{Transport: transport, Timeout: scrapeTimeout},
+		tokenFile:  tokenFile,
+	}, nil
+}
+
+func (m *metricsClient) scrape(ctx context.Context) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	if m.tokenFile != "" {
+		token, err := os.ReadFile(m.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ServiceAccount token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: HTTP %d", m.url, resp.StatusCode)
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics from %s: %w", m.url, err)
+	}
+	return families, nil
+}
+
+// resultsController reads results of the detector checks from the detector
+// metrics and reports failing checks in conditions.
+type resultsController struct {
+	name           string
+	config         CheckResultsConfig
+	conditionTypes []string
+	operatorClient v1helpers.OperatorClient
+	// metrics is created on the first sync, the service CA bundle is not
+	// available in unit tests.
+	metrics       *metricsClient
+	newMetrics    func(url string) (*metricsClient, error)
+	eventRecorder events.Recorder
+	// Number of failed scrapes in a row.
+	scrapeFailures int
+}
+
+func newResultsController(
+	clients *csoclients.Clients,
+	config ProblemDetectorConfig,
+	eventRecorder events.Recorder) factory.Controller {
+
+	name := config.ConditionPrefix + "ResultsController"
+	c := &resultsController{
+		name:           name,
+		config:         *config.CheckResults,
+		operatorClient: clients.OperatorClient,
+		newMetrics:     newMetricsClient,
+		eventRecorder:  eventRecorder.WithComponentSuffix(name),
+	}
+	c.conditionTypes = []string{
+		config.ConditionPrefix + clusterChecksConditionSuffix,
+		config.ConditionPrefix + nodeChecksConditionSuffix,
+	}
+	return factory.New().
+		WithSync(operatormetrics.InstrumentSync(name, c.sync)).
+		WithInformers(
+			c.operatorClient.Informer(),
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Apps().V1().Deployments().Informer()).
+		ResyncEvery(resultsResyncInterval).
+		WithSyncDegradedOnError(clients.OperatorClient).
+		ToController(name, c.eventRecorder)
+}
+
+func (c *resultsController) sync(ctx context.Context, syncContext factory.SyncContext) error {
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	clusterCondition := operatorapi.OperatorCondition{
+		Type:   c.conditionTypes[0],
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	nodeCondition := operatorapi.OperatorCondition{
+		Type:   c.conditionTypes[1],
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+
+	families, err := c.scrape(ctx)
+	if err != nil {
+		c.scrapeFailures++
+		klog.V(2).Infof("%s: failed to get detector results (%d times in a row): %s", c.name, c.scrapeFailures, err)
+		// The detector may be starting or restarting, report the error only
+		// in the conditions. When it fails for too long, the endpoint is
+		// most probably not reachable at all.
+		reason := "ResultsUnavailable"
+		msg := fmt.Sprintf("Waiting for results of the checks, the detector may be starting: %s", err)
+		if c.scrapeFailures >= maxScrapeFailures {
+			reason = "ResultsEndpointUnreachable"
+			msg = fmt.Sprintf("Metrics endpoint %s of the detector is unreachable, failed %d times in a row: %s", c.config.MetricsURL, c.scrapeFailures, err)
+			if c.scrapeFailures == maxScrapeFailures {
+				c.eventRecorder.Warningf("ResultsEndpointUnreachable", "%s", msg)
+			}
+		}
+		for _, cnd := range []*operatorapi.OperatorCondition{&clusterCondition, &nodeCondition} {
+			cnd.Status = operatorapi.ConditionUnknown
+			cnd.Reason = reason
+			cnd.Message = msg
+		}
+	} else {
+		c.scrapeFailures = 0
+		if msg := c.clusterChecksMessage(families); msg != "" {
+			clusterCondition.Status = operatorapi.ConditionFalse
+			clusterCondition.Reason = "ClusterChecksFailed"
+			clusterCondition.Message = msg
+		}
+		if msg := c.nodeChecksMessage(families); msg != "" {
+			nodeCondition.Status = operatorapi.ConditionFalse
+			nodeCondition.Reason = "NodeChecksFailed"
+			nodeCondition.Message = msg
+		}
+	}
+
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient,
+		v1helpers.UpdateConditionFn(clusterCondition),
+		v1helpers.UpdateConditionFn(nodeCondition),
+	)
+	return err
+}
+
+func (c *resultsController) scrape(ctx context.Context) (map[string]*dto.MetricFamily, error) {
+	if c.metrics == nil {
+		metrics, err := c.newMetrics(c.config.MetricsURL)
+		if err != nil {
+			return nil, err
+		}
+		c.metrics = metrics
+	}
+	return c.metrics.scrape(ctx)
+}
+
+// clusterChecksMessage returns a message with failing cluster checks, or empty
+// string when all checks pass.
+func (c *resultsController) clusterChecksMessage(families map[string]*dto.MetricFamily) string {
+	failed := failingChecks(families[c.config.ClusterCheckMetric])
+	if len(failed) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, check := range sets.List(sets.KeySet(failed)) {
+		parts = append(parts, c.withRemediation(check, check))
+	}
+	return fmt.Sprintf("Failing cluster checks: %s", strings.Join(parts, "; "))
+}
+
+// nodeChecksMessage returns a message with failing node checks and their
+// nodes, or empty string when all checks pass.
+func (c *resultsController) nodeChecksMessage(families map[string]*dto.MetricFamily) string {
+	failed := failingChecks(families[c.config.NodeCheckMetric])
+	if len(failed) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, check := range sets.List(sets.KeySet(failed)) {
+		nodes := sets.List(failed[check])
+		nodeList := strings.Join(nodes, ", ")
+		if len(nodes) > maxReportedNodes {
+			nodeList = fmt.Sprintf("%s and %d more", strings.Join(nodes[:maxReportedNodes], ", "), len(nodes)-maxReportedNodes)
+		}
+		parts = append(parts, c.withRemediation(check, fmt.Sprintf("%s on nodes %s", check, nodeList)))
+	}
+	return fmt.Sprintf("Failing node checks: %s", strings.Join(parts, "; "))
+}
+
+func (c *resultsController) withRemediation(check, msg string) string {
+	if c.config.Remediation == nil {
+		return msg
+	}
+	if hint := c.config.Remediation(check); hint != "" {
+		return fmt.Sprintf("%s (%s)", msg, hint)
+	}
+	return msg
+}
+
+// failingChecks returns names of checks with non-zero value of the given
+// metric, together with values of their node label.
+func failingChecks(family *dto.MetricFamily) map[string]sets.Set[string] {
+	failed := map[string]sets.Set[string]{}
+	if family == nil {
+		return failed
+	}
+	for _, metric := range family.GetMetric() {
+		var value float64
+		switch {
+		case metric.GetGauge() != nil:
+			value = metric.GetGauge().GetValue()
+		case metric.GetUntyped() != nil:
+			value = metric.GetUntyped().GetValue()
+		}
+		if value == 0 {
+			continue
+		}
+		var check, node string
+		for _, label := range metric.GetLabel() {
+			switch label.GetName() {
+			case checkLabel:
+				check = label.GetValue()
+			case nodeLabel:
+				node = label.GetValue()
+			}
+		}
+		if check == "" {
+			continue
+		}
+		if failed[check] == nil {
+			failed[check] = sets.New[string]()
+		}
+		if node != "" {
+			failed[check].Insert(node)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,237 @@
+package problemdetector
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	testClusterCheckMetric = "test_cluster_check_errors"
+	testNodeCheckMetric    = "test_node_check_errors"
+)
+
+func TestSyncCheckResults(t *testing.T) {
+	tests := []struct {
+		name            string
+		metrics         string
+		statusCode      int
+		syncs           int
+		expectedCluster opv1.OperatorCondition
+		expectedNode    opv1.OperatorCondition
+	}{
+		{
+			name: "all checks pass",
+			metrics: `# TYPE test_cluster_check_errors gauge
+test_cluster_check_errors{check="CheckFolderPermissions"} 0
+# TYPE test_node_check_errors gauge
+test_node_check_errors{check="CheckNodeDiskUUID",node="node1"} 0
+`,
+			statusCode:      http.StatusOK,
+			expectedCluster: opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+			expectedNode:    opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+		},
+		{
+			name:            "no results yet",
+			metrics:         "",
+			statusCode:      http.StatusOK,
+			expectedCluster: opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+			expectedNode:    opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+		},
+		{
+			name: "cluster checks fail",
+			metrics: `# TYPE test_cluster_check_errors gauge
+test_cluster_check_errors{check="CheckFolderPermissions"} 1
+test_cluster_check_errors{check="CheckDefaultDatastore"} 1
+test_cluster_check_errors{check="CheckStorageClasses"} 0
+`,
+			statusCode: http.StatusOK,
+			expectedCluster: opv1.OperatorCondition{
+				Status:  opv1.ConditionFalse,
+				Reason:  "ClusterChecksFailed",
+				Message: "Failing cluster checks: CheckDefaultDatastore; CheckFolderPermissions (fix folder permissions)",
+			},
+			expectedNode: opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+		},
+		{
+			name: "node checks fail",
+			metrics: `# TYPE test_node_check_errors gauge
+test_node_check_errors{check="CheckNodeDiskUUID",node="node1"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node2"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node3"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node4"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node5"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node6"} 1
+test_node_check_errors{check="CheckNodeDiskUUID",node="node7"} 1
+test_node_check_errors{check="CheckNodeProviderID",node="node1"} 0
+test_node_check_errors{check="CheckNodeProviderID",node="node2"} 1
+`,
+			statusCode:      http.StatusOK,
+			expectedCluster: opv1.OperatorCondition{Status: opv1.ConditionTrue, Reason: "AsExpected"},
+			expectedNode: opv1.OperatorCondition{
+				Status:  opv1.ConditionFalse,
+				Reason:  "NodeChecksFailed",
+				Message: "Failing node checks: CheckNodeDiskUUID on nodes node1, node2, node3, node4, node5 and 2 more (enable disk UUID); CheckNodeProviderID on nodes node2",
+			},
+		},
+		{
+			name:       "metrics not available",
+			statusCode: http.StatusServiceUnavailable,
+			expectedCluster: opv1.OperatorCondition{
+				Status: opv1.ConditionUnknown,
+				Reason: "ResultsUnavailable",
+			},
+			expectedNode: opv1.OperatorCondition{
+				Status: opv1.ConditionUnknown,
+				Reason: "ResultsUnavailable",
+			},
+		},
+		{
+			name:       "metrics endpoint unreachable",
+			statusCode: http.StatusServiceUnavailable,
+			syncs:      maxScrapeFailures,
+			expectedCluster: opv1.OperatorCondition{
+				Status: opv1.ConditionUnknown,
+				Reason: "ResultsEndpointUnreachable",
+			},
+			expectedNode: opv1.OperatorCondition{
+				Status: opv1.ConditionUnknown,
+				Reason: "ResultsEndpointUnreachable",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statusCode)
+				fmt.Fprint(w, test.metrics)
+			}))
+			defer server.Close()
+
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			})
+			recorder := events.NewInMemoryRecorder("test")
+			ctrl := &resultsController{
+				name: "TestProblemDetectorResultsController",
+				config: CheckResultsConfig{
+					MetricsURL:         server.URL,
+					ClusterCheckMetric: testClusterCheckMetric,
+					NodeCheckMetric:    testNodeCheckMetric,
+					Remediation: func(check string) string {
+						return map[string]string{
+							"CheckFolderPermissions": "fix folder permissions",
+							"CheckNodeDiskUUID":      "enable disk UUID",
+						}[check]
+					},
+				},
+				conditionTypes: []string{"TestProblemDetectorClusterChecksPassed", "TestProblemDetectorNodeChecksPassed"},
+				operatorClient: clients.OperatorClient,
+				newMetrics: func(url string) (*metricsClient, error) {
+					return &metricsClient{url: url, httpClient: server.Client()}, nil
+				},
+				eventRecorder: recorder,
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			clients.OperatorClient.Informer()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			syncs := test.syncs
+			if syncs == 0 {
+				syncs = 1
+			}
+			for i := 0; i < syncs; i++ {
+				if err := ctrl.sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			cr, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkCondition(t, cr.Status.Conditions, "TestProblemDetectorClusterChecksPassed", test.expectedCluster)
+			checkCondition(t, cr.Status.Conditions, "TestProblemDetectorNodeChecksPassed", test.expectedNode)
+		})
+	}
+}
+
+func checkCondition(t *testing.T, conditions []opv1.OperatorCondition, cndType string, expected opv1.OperatorCondition) {
+	t.Helper()
+	cnd := v1helpers.FindOperatorCondition(conditions, cndType)
+	if cnd == nil {
+		t.Errorf("expected condition %s, got none", cndType)
+		return
+	}
+	if cnd.Status != expected.Status || cnd.Reason != expected.Reason {
+		t.Errorf("expected condition %s %s/%s, got %s/%s", cndType, expected.Status, expected.Reason, cnd.Status, cnd.Reason)
+	}
+	if expected.Message != "" && cnd.Message != expected.Message {
+		t.Errorf("expected condition %s message %q, got %q", cndType, expected.Message, cnd.Message)
+	}
+}
+
+// TestMetricsClient scrapes a TLS endpoint with a bearer token, like the
+// controller does with the detector Service.
+func TestMetricsClient(t *testing.T) {
+	const token = "test-token"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `# TYPE test_cluster_check_errors gauge
+test_cluster_check_errors{check="CheckFolderPermissions"} 1
+`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "service-ca.crt")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newMetricsClientWithFiles(server.URL, caFile, tokenFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	families, err := client.scrape(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed := failingChecks(families[testClusterCheckMetric])
+	if _, found := failed["CheckFolderPermissions"]; !found || len(failed) != 1 {
+		t.Errorf("expected failing CheckFolderPermissions, got %v", failed)
+	}
+
+	// The token is rotated by kubelet, it must be read on each scrape.
+	if err := os.WriteFile(tokenFile, []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.scrape(context.TODO()); err == nil {
+		t.Errorf("expected error with a rotated token, got none")
+	}
+}
@@ -0,0 +1,356 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	ov1 "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/controller/manager"
+	"github.com/openshift/library-go/pkg/operator/csi/csidrivercontrollerservicecontroller"
+	"github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/staticresourcecontroller"
+	"github.com/openshift/library-go/pkg/operator/status"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	infraConfigName = "cluster"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, a detector
+	// that is stopped on purpose should not affect the ClusterOperator.
+	activeConditionSuffix = "Active"
+)
+
+// Starter starts controllers of a problem detector when the cluster runs
+// on the detector platform. It stops the controllers and removes all objects
+// of the detector when the Storage CR is not Managed or the ClusterCSIDriver
+// of the platform is Removed.
+type Starter struct {
+	config                 ProblemDetectorConfig
+	name                   string
+	clients                *csoclients.Clients
+	resyncInterval         time.Duration
+	controller             manager.ControllerManager
+	operatorClient         v1helpers.OperatorClientWithFinalizers
+	infraLister            openshiftv1.InfrastructureLister
+	clusterCSIDriverLister ov1.ClusterCSIDriverLister
+	versionGetter          status.VersionGetter
+	targetVersion          string
+	eventRecorder          events.Recorder
+	// cancel stops the running controllers, nil when they are not running.
+	cancel context.CancelFunc
+	// done is closed when all controllers stopped after cancel.
+	done chan struct{}
+	// removed is true when objects of the stopped detector were deleted.
+	removed bool
+}
+
+func NewStarter(
+	clients *csoclients.Clients,
+	config ProblemDetectorConfig,
+	resyncInterval time.Duration,
+	versionGetter status.VersionGetter,
+	targetVersion string,
+	eventRecorder events.Recorder) factory.Controller {
+	name := config.ConditionPrefix + "Starter"
+	c := &Starter{
+		config:                 config,
+		name:                   name,
+		clients:                clients,
+		resyncInterval:         resyncInterval,
+		operatorClient:         clients.OperatorClient,
+		infraLister:            clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
+		versionGetter:          versionGetter,
+		targetVersion:          targetVersion,
+		eventRecorder:          eventRecorder.WithComponentSuffix(name),
+	}
+	// Create the controllers early to catch broken assets on operator startup.
+	c.controller = c.createManager(clients, resyncInterval)
+	return factory.New().WithSync(operatormetrics.InstrumentSync(name, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer(),
+	).ToController(name, eventRecorder)
+}
+
+func (c *Starter) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("%s.Sync started", c.name)
+	defer klog.V(4).Infof("%s.Sync finished", c.name)
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	infrastructure, err := c.infraLister.Get(infraConfigName)
+	if err != nil {
+		return err
+	}
+
+	var platform configv1.PlatformType
+	if infrastructure.Status.PlatformStatus != nil {
+		platform = infrastructure.Status.PlatformStatus.Type
+	}
+
+	// if not the detector platform return without any error
+	if platform != c.config.Platform {
+		return nil
+	}
+
+	reason, message, err := c.getInactiveReason(opSpec)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return c.stop(ctx, reason, message)
+	}
+
+	if c.cancel == nil {
+		c.start(ctx)
+	}
+	return c.updateActiveCondition(ctx, operatorapi.OperatorCondition{
+		Type:   c.config.ConditionPrefix + activeConditionSuffix,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	})
+}
+
+// getInactiveReason returns reason and message why the detector should not
+// run. Empty reason means the detector should run.
+func (c *Starter) getInactiveReason(opSpec *operatorapi.OperatorSpec) (string, string, error) {
+	if opSpec.ManagementState != operatorapi.Managed {
+		return "OperatorNotManaged", fmt.Sprintf("The storage operator is %s", opSpec.ManagementState), nil
+	}
+	if c.config.ClusterCSIDriverName == "" {
+		return "", "", nil
+	}
+	ccd, err := c.clusterCSIDriverLister.Get(c.config.ClusterCSIDriverName)
+	if apierrors.IsNotFound(err) {
+		// The ClusterCSIDriver is created by the CSI driver starter
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if ccd.Spec.ManagementState == operatorapi.Removed {
+		return "DriverRemoved", fmt.Sprintf("ClusterCSIDriver %s is Removed", ccd.Name), nil
+	}
+	return "", "", nil
+}
+
+func (c *Starter) start(ctx context.Context) {
+	if c.controller == nil {
+		// Stopped controllers can't be started again, create new ones.
+		c.controller = c.createManager(c.clients, c.resyncInterval)
+	}
+	klog.Infof("Starting %s", c.config.ConditionPrefix)
+	mgr := c.controller
+	mgrCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mgr.Start(mgrCtx)
+	}()
+	c.cancel = cancel
+	c.done = done
+	c.removed = false
+}
+
+func (c *Starter) stop(ctx context.Context, reason, message string) error {
+	if c.cancel != nil {
+		klog.Infof("Stopping %s: %s", c.config.ConditionPrefix, message)
+		c.cancel()
+		// Wait for the controllers, so they don't re-create objects or
+		// conditions removed below.
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c.cancel = nil
+		c.done = nil
+		c.controller = nil
+	}
+
+	if !c.removed {
+		if err := c.removeObjects(ctx); err != nil {
+			return err
+		}
+		c.removed = true
+	}
+
+	return c.updateActiveCondition(ctx, operatorapi.OperatorCondition{
+		Type:    c.config.ConditionPrefix + activeConditionSuffix,
+		Status:  operatorapi.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}, c.removeStaleConditionsFn())
+}
+
+// removeObjects deletes all objects created by controllers of the detector,
+// except SharedStaticAssets.
+func (c *Starter) removeObjects(ctx context.Context) error {
+	var errs []error
+
+	deploymentAsset, err := assets.ReadFile(c.config.DeploymentAsset)
+	if err != nil {
+		return err
+	}
+	deployment := resourceread.ReadDeploymentV1OrDie(deploymentAsset)
+	err = c.clients.KubeClient.AppsV1().Deployments(deployment.Namespace).Delete(ctx, deployment.Name, metav1.DeleteOptions{})
+	if err == nil {
+		c.eventRecorder.Eventf("DeploymentDeleted", "Deleted Deployment %s/%s", deployment.Namespace, deployment.Name)
+	} else if !apierrors.IsNotFound(err) {
+		errs = append(errs, err)
+	}
+
+	if c.config.ServiceMonitorAsset != "" {
+		smBytes, err := assets.ReadFile(c.config.ServiceMonitorAsset)
+		if err != nil {
+			return err
+		}
+		serviceMonitor := resourceread.ReadUnstructuredOrDie(smBytes)
+		if _, _, err := resourceapply.DeleteServiceMonitor(ctx, c.clients.DynamicClient, c.eventRecorder, serviceMonitor); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.config.PrometheusRule.File != "" {
+		prometheusRule, err := c.config.PrometheusRule.Render(&prometheusrules.Overrides{})
+		if err != nil {
+			return err
+		}
+		if _, _, err := resourceapply.DeletePrometheusRule(ctx, c.clients.DynamicClient, c.eventRecorder, prometheusRule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	results := resourceapply.DeleteAll(ctx, resourceapply.NewKubeClientHolder(c.clients.KubeClient), c.eventRecorder, assets.ReadFile, c.config.StaticAssets...)
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", result.File, result.Error))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// removeStaleConditionsFn removes conditions of the stopped controllers, so
+// e.g. a Degraded condition of the Deployment does not stay forever.
+func (c *Starter) removeStaleConditionsFn() v1helpers.UpdateStatusFunc {
+	return func(oldStatus *operatorapi.OperatorStatus) error {
+		var conditions []operatorapi.OperatorCondition
+		for _, cnd := range oldStatus.Conditions {
+			if strings.HasPrefix(cnd.Type, c.config.ConditionPrefix) &&
+				!strings.HasPrefix(cnd.Type, c.name) &&
+				cnd.Type != c.config.ConditionPrefix+activeConditionSuffix {
+				continue
+			}
+			conditions = append(conditions, cnd)
+		}
+		oldStatus.Conditions = conditions
+		return nil
+	}
+}
+
+func (c *Starter) updateActiveCondition(ctx context.Context, cnd operatorapi.OperatorCondition, extraFns ...v1helpers.UpdateStatusFunc) error {
+	fns := append([]v1helpers.UpdateStatusFunc{v1helpers.UpdateConditionFn(cnd)}, extraFns...)
+	_, _, err := v1helpers.UpdateStatus(ctx, c.operatorClient, fns...)
+	return err
+}
+
+func (c *Starter) createManager(
+	clients *csoclients.Clients,
+	resyncInterval time.Duration) manager.ControllerManager {
+	mgr := manager.NewControllerManager()
+
+	mgr = mgr.WithController(staticresourcecontroller.NewStaticResourceController(
+		c.name+"StaticController",
+		assets.ReadFile,
+		append(append([]string{}, c.config.StaticAssets...), c.config.SharedStaticAssets...),
+		resourceapply.NewKubeClientHolder(clients.KubeClient),
+		c.operatorClient,
+		c.eventRecorder).AddKubeInformers(clients.KubeInformers), 1)
+
+	deploymentAsset, err := assets.ReadFile(c.config.DeploymentAsset)
+	if err != nil {
+		panic(err)
+	}
+
+	secretInformer := clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().Secrets()
+	deploymentHooks := []deploymentcontroller.DeploymentHookFunc{
+		csidrivercontrollerservicecontroller.WithControlPlaneTopologyHook(clients.ConfigInformers),
+		withProxyHook(),
+	}
+	for _, secretName := range c.config.SecretNames {
+		deploymentHooks = append(deploymentHooks, csidrivercontrollerservicecontroller.WithSecretHashAnnotationHook(
+			csoclients.OperatorNamespace,
+			secretName,
+			secretInformer,
+		))
+	}
+	if c.config.ExtraDeploymentHooks != nil {
+		deploymentHooks = append(deploymentHooks, c.config.ExtraDeploymentHooks(clients)...)
+	}
+
+	deploymentController, err := deploymentcontroller.NewDeploymentControllerBuilder(
+		c.config.ConditionPrefix+"DeploymentController",
+		deploymentAsset,
+		c.eventRecorder,
+		clients.OperatorClient,
+		clients.KubeClient,
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Apps().V1().Deployments(),
+	).WithExtraInformers(
+		secretInformer.Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+	).WithManifestHooks(
+		withReplacerHook(c.config.ImageReplacer),
+	).WithDeploymentHooks(
+		deploymentHooks...,
+	).WithConditions(
+		// No Available Condition
+		operatorapi.OperatorStatusTypeProgressing,
+		operatorapi.OperatorStatusTypeDegraded,
+	).ToController()
+
+	if err != nil {
+		panic(err)
+	}
+
+	mgr = mgr.WithController(deploymentController, 1)
+
+	mgr = mgr.WithController(newMonitoringController(
+		clients,
+		c.config,
+		c.eventRecorder,
+		resyncInterval), 1)
+
+	if c.config.CheckResults != nil {
+		mgr = mgr.WithController(newResultsController(
+			clients,
+			c.config,
+			c.eventRecorder), 1)
+	}
+
+	return mgr
+}
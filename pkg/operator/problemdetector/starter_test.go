@@ -0,0 +1,268 @@
+package problemdetector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfgv1 "github.com/openshift/api/config/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	testDeploymentName     = "vsphere-problem-detector-operator"
+	testServiceAccountName = "vsphere-problem-detector-operator"
+)
+
+func getStarterTestConfig() ProblemDetectorConfig {
+	config := getTestConfig()
+	config.Platform = cfgv1.VSpherePlatformType
+	config.StaticAssets = []string{
+		"vsphere_problem_detector/01_sa.yaml",
+	}
+	config.SharedStaticAssets = []string{
+		"vsphere_problem_detector/06_configmap.yaml",
+	}
+	config.DeploymentAsset = "vsphere_problem_detector/07_deployment.yaml"
+	return config
+}
+
+func getInfrastructure(platformType cfgv1.PlatformType) *cfgv1.Infrastructure {
+	return &cfgv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: infraConfigName},
+		Status: cfgv1.InfrastructureStatus{
+			PlatformStatus: &cfgv1.PlatformStatus{Type: platformType},
+		},
+	}
+}
+
+func getDetectorObjects() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: testDeploymentName, Namespace: csoclients.OperatorNamespace}},
+		&v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: testServiceAccountName, Namespace: csoclients.OperatorNamespace}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca-bundle", Namespace: csoclients.OperatorNamespace}},
+	}
+}
+
+func withState(state opv1.ManagementState) csoclients.CrModifier {
+	return func(cr *opv1.Storage) *opv1.Storage {
+		cr.Spec.ManagementState = state
+		return cr
+	}
+}
+
+func withConditions(conditionTypes ...string) csoclients.CrModifier {
+	return func(cr *opv1.Storage) *opv1.Storage {
+		for _, cndType := range conditionTypes {
+			cr.Status.Conditions = append(cr.Status.Conditions, opv1.OperatorCondition{Type: cndType, Status: opv1.ConditionTrue})
+		}
+		return cr
+	}
+}
+
+func newTestStarter(clients *csoclients.Clients, config ProblemDetectorConfig, recorder events.Recorder) *Starter {
+	return &Starter{
+		config:                 config,
+		name:                   config.ConditionPrefix + "Starter",
+		clients:                clients,
+		operatorClient:         clients.OperatorClient,
+		infraLister:            clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
+		eventRecorder:          recorder,
+	}
+}
+
+func startTestInformers(ctx context.Context, clients *csoclients.Clients) {
+	clients.OperatorClient.Informer()
+	clients.ConfigInformers.Config().V1().Infrastructures().Informer()
+	clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer()
+	csoclients.StartInformers(clients, ctx.Done())
+	csoclients.WaitForSync(clients, ctx.Done())
+}
+
+func TestStarterInactive(t *testing.T) {
+	const (
+		staleCondition     = "TestProblemDetectorDeploymentControllerDegraded"
+		starterCondition   = "TestProblemDetectorStarterDegraded"
+		unrelatedCondition = "DefaultStorageClassControllerAvailable"
+	)
+
+	tests := []struct {
+		name              string
+		cr                *opv1.Storage
+		ccdState          opv1.ManagementState
+		platform          cfgv1.PlatformType
+		expectRemoved     bool
+		expectedCondition *opv1.OperatorCondition
+	}{
+		{
+			name:          "other platform",
+			cr:            csoclients.GetCR(withState(opv1.Unmanaged), withConditions(staleCondition)),
+			ccdState:      opv1.Removed,
+			platform:      cfgv1.AWSPlatformType,
+			expectRemoved: false,
+		},
+		{
+			name:          "driver removed",
+			cr:            csoclients.GetCR(withConditions(staleCondition, starterCondition, unrelatedCondition)),
+			ccdState:      opv1.Removed,
+			platform:      cfgv1.VSpherePlatformType,
+			expectRemoved: true,
+			expectedCondition: &opv1.OperatorCondition{
+				Type:    "TestProblemDetectorActive",
+				Status:  opv1.ConditionFalse,
+				Reason:  "DriverRemoved",
+				Message: "ClusterCSIDriver csi.vsphere.vmware.com is Removed",
+			},
+		},
+		{
+			name:          "operator unmanaged",
+			cr:            csoclients.GetCR(withState(opv1.Unmanaged), withConditions(staleCondition, starterCondition, unrelatedCondition)),
+			ccdState:      opv1.Managed,
+			platform:      cfgv1.VSpherePlatformType,
+			expectRemoved: true,
+			expectedCondition: &opv1.OperatorCondition{
+				Type:    "TestProblemDetectorActive",
+				Status:  opv1.ConditionFalse,
+				Reason:  "OperatorNotManaged",
+				Message: "The storage operator is Unmanaged",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				CoreObjects:     getDetectorObjects(),
+				OperatorObjects: []runtime.Object{test.cr, getClusterCSIDriver(test.ccdState)},
+				ConfigObjects:   []runtime.Object{getInfrastructure(test.platform)},
+				DynamicObjects:  []runtime.Object{getExistingPrometheusRule()},
+			})
+			recorder := events.NewInMemoryRecorder("test")
+			starter := newTestStarter(clients, getStarterTestConfig(), recorder)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			startTestInformers(ctx, clients)
+
+			if err := starter.sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if starter.cancel != nil {
+				t.Errorf("expected the detector not to run")
+			}
+
+			_, err := clients.KubeClient.AppsV1().Deployments(csoclients.OperatorNamespace).Get(ctx, testDeploymentName, metav1.GetOptions{})
+			checkRemoved(t, "Deployment", err, test.expectRemoved)
+			_, err = clients.KubeClient.CoreV1().ServiceAccounts(csoclients.OperatorNamespace).Get(ctx, testServiceAccountName, metav1.GetOptions{})
+			checkRemoved(t, "ServiceAccount", err, test.expectRemoved)
+			_, err = clients.DynamicClient.Resource(prometheusRuleGVR).Namespace(csoclients.OperatorNamespace).Get(ctx, "vsphere-problem-detector", metav1.GetOptions{})
+			checkRemoved(t, "PrometheusRule", err, test.expectRemoved)
+			// Shared assets are never removed
+			_, err = clients.KubeClient.CoreV1().ConfigMaps(csoclients.OperatorNamespace).Get(ctx, "trusted-ca-bundle", metav1.GetOptions{})
+			checkRemoved(t, "ConfigMap", err, false)
+
+			cr, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cnd := v1helpers.FindOperatorCondition(cr.Status.Conditions, "TestProblemDetectorActive")
+			if test.expectedCondition == nil {
+				if cnd != nil {
+					t.Errorf("expected no Active condition, got %+v", cnd)
+				}
+				return
+			}
+			if cnd == nil {
+				t.Fatalf("expected Active condition, got none")
+			}
+			if cnd.Status != test.expectedCondition.Status || cnd.Reason != test.expectedCondition.Reason || cnd.Message != test.expectedCondition.Message {
+				t.Errorf("expected condition %+v, got %+v", test.expectedCondition, cnd)
+			}
+			if v1helpers.FindOperatorCondition(cr.Status.Conditions, staleCondition) != nil {
+				t.Errorf("expected condition %s to be removed", staleCondition)
+			}
+			for _, cndType := range []string{starterCondition, unrelatedCondition} {
+				if v1helpers.FindOperatorCondition(cr.Status.Conditions, cndType) == nil {
+					t.Errorf("expected condition %s to be kept", cndType)
+				}
+			}
+		})
+	}
+}
+
+func TestStarterStopsRunningDetector(t *testing.T) {
+	clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+		CoreObjects:     getDetectorObjects(),
+		OperatorObjects: []runtime.Object{csoclients.GetCR(), getClusterCSIDriver(opv1.Managed)},
+		ConfigObjects:   []runtime.Object{getInfrastructure(cfgv1.VSpherePlatformType)},
+	})
+	recorder := events.NewInMemoryRecorder("test")
+	// Controllers of the detector get their own recorder, the in-memory recorder is not thread safe.
+	starter := newTestStarter(clients, getStarterTestConfig(), events.NewInMemoryRecorder("detector"))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	startTestInformers(ctx, clients)
+
+	if err := starter.sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if starter.cancel == nil {
+		t.Fatalf("expected the detector to run")
+	}
+	cr, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v1helpers.IsOperatorConditionTrue(cr.Status.Conditions, "TestProblemDetectorActive") {
+		t.Errorf("expected TestProblemDetectorActive condition to be True")
+	}
+
+	// Remove the driver
+	if _, err := clients.OperatorClientSet.OperatorV1().ClusterCSIDrivers().Update(ctx, getClusterCSIDriver(opv1.Removed), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		ccd, err := starter.clusterCSIDriverLister.Get(getClusterCSIDriver(opv1.Removed).Name)
+		if err != nil {
+			return false, err
+		}
+		return ccd.Spec.ManagementState == opv1.Removed, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := starter.sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if starter.cancel != nil || starter.controller != nil {
+		t.Errorf("expected the detector to be stopped")
+	}
+	_, err = clients.KubeClient.AppsV1().Deployments(csoclients.OperatorNamespace).Get(ctx, testDeploymentName, metav1.GetOptions{})
+	checkRemoved(t, "Deployment", err, true)
+}
+
+func checkRemoved(t *testing.T, kind string, err error, expectRemoved bool) {
+	t.Helper()
+	if expectRemoved {
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected %s to be removed, got: %v", kind, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("expected %s to exist, got: %v", kind, err)
+	}
+}
@@ -0,0 +1,77 @@
+package problemdetector
+
+import (
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// ProblemDetectorConfig is configuration of a platform problem detector, i.e. a
+// Deployment that periodically checks permissions, quotas and configuration
+// of the platform and reports problems as metrics and alerts.
+type ProblemDetectorConfig struct {
+	// Short name of the detector, used to prefix conditions and controller names,
+	// e.g. VSphereProblemDetector.
+	ConditionPrefix string
+	// Platform where the detector should run.
+	Platform configv1.PlatformType
+	// StaticAssets is list of bindata assets to create when starting the detector.
+	// They are deleted when the detector stops.
+	StaticAssets []string
+	// SharedStaticAssets is list of bindata assets that are created when starting
+	// the detector, but are shared with other components and never deleted.
+	SharedStaticAssets []string
+	// DeploymentAsset is name of the bindata asset with Deployment of the
+	// detector. ImageReplacer is run on it and ${LOG_LEVEL} is replaced by
+	// the log level of the operator.
+	DeploymentAsset string
+	// ImageReplacer is a replacer that replaces the detector image names in
+	// the Deployment.
+	ImageReplacer *strings.Replacer
+	// Secrets in the operator namespace that restart the detector when they
+	// change, e.g. cloud credentials or serving certificate.
+	SecretNames []string
+	// ExtraDeploymentHooks returns platform specific hooks of the detector Deployment.
+	ExtraDeploymentHooks func(clients *csoclients.Clients) []deploymentcontroller.DeploymentHookFunc
+	// ServiceMonitorAsset is name of the bindata asset with the ServiceMonitor
+	// of the detector.
+	ServiceMonitorAsset string
+	// PrometheusRule with alerts of the detector.
+	PrometheusRule prometheusrules.RuleAsset
+	// ClusterCSIDriverName is name of ClusterCSIDriver of the platform CSI driver.
+	// The detector is stopped and removed when the ClusterCSIDriver is Removed.
+	// Optional.
+	ClusterCSIDriverName string
+	// ParseAlertsConfig parses user configuration of the detector alerts from
+	// ConfigMaps in the operator namespace.
+	ParseAlertsConfig func(lister corelisters.ConfigMapLister) (*AlertsConfig, error)
+	// CheckResults configures reading of the detector check results into
+	// conditions. Optional.
+	CheckResults *CheckResultsConfig
+}
+
+// CheckResultsConfig describes metrics with results of the detector checks.
+// Each metric is a gauge with "check" label and, for node checks, "node"
+// label. Non-zero value means the check failed.
+type CheckResultsConfig struct {
+	// MetricsURL is URL of the detector metrics endpoint.
+	MetricsURL string
+	// ClusterCheckMetric is name of the metric with results of cluster checks.
+	ClusterCheckMetric string
+	// NodeCheckMetric is name of the metric with results of node checks.
+	NodeCheckMetric string
+	// Remediation returns a hint how to fix a failing check. Optional.
+	Remediation func(check string) string
+}
+
+// AlertsConfig is user configuration of alerts of a problem detector.
+type AlertsConfig struct {
+	// Disabled removes all alerts of the detector.
+	Disabled bool
+	// Overrides of the detector alerts.
+	Overrides *prometheusrules.Overrides
+}
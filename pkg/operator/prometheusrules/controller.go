@@ -0,0 +1,80 @@
+package prometheusrules
+
+import (
+	"context"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/client-go/dynamic"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// This Controller renders a PrometheusRule from an asset, applies Overrides
+// from a ConfigMap in the operator namespace and creates / updates the
+// PrometheusRule. CSO owns the PrometheusRule, any manual change of it is
+// overwritten.
+// It produces following Conditions:
+// <name>Degraded - error parsing the ConfigMap or applying the PrometheusRule.
+type Controller struct {
+	name            string
+	asset           RuleAsset
+	configMapName   string
+	operatorClient  v1helpers.OperatorClient
+	dynamicClient   dynamic.Interface
+	configMapLister corelisters.ConfigMapLister
+	eventRecorder   events.Recorder
+}
+
+func NewController(
+	name string,
+	asset RuleAsset,
+	configMapName string,
+	clients *csoclients.Clients,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		name:            name,
+		asset:           asset,
+		configMapName:   configMapName,
+		operatorClient:  clients.OperatorClient,
+		dynamicClient:   clients.DynamicClient,
+		configMapLister: clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		eventRecorder:   eventRecorder.WithComponentSuffix(name),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(name, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+		clients.MonitoringInformer.Monitoring().V1().PrometheusRules().Informer(),
+	).ResyncEvery(resyncInterval).ToController(name, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("%s sync started", c.name)
+	defer klog.V(4).Infof("%s sync finished", c.name)
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	overrides, err := ParseOverridesConfigMap(c.configMapLister, c.configMapName)
+	if err != nil {
+		return err
+	}
+	rule, err := c.asset.Render(overrides)
+	if err != nil {
+		return err
+	}
+	_, _, err = resourceapply.ApplyPrometheusRule(ctx, c.dynamicClient, c.eventRecorder, rule)
+	return err
+}
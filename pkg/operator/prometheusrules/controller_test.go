@@ -0,0 +1,140 @@
+package prometheusrules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var prometheusRuleGVR = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"}
+
+func getOverridesConfigMap(config string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: storageRulesConfigMapName, Namespace: csoclients.OperatorNamespace},
+		Data:       map[string]string{configKey: config},
+	}
+}
+
+// getModifiedPrometheusRule returns the PrometheusRule as if it was edited by a user.
+func getModifiedPrometheusRule() *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{}
+	rule.SetAPIVersion("monitoring.coreos.com/v1")
+	rule.SetKind("PrometheusRule")
+	rule.SetName("prometheus")
+	rule.SetNamespace(csoclients.OperatorNamespace)
+	unstructured.SetNestedSlice(rule.Object, []interface{}{}, "spec", "groups")
+	return rule
+}
+
+// cvoAnnotations are the annotations of the PrometheusRule that was installed
+// by the cluster-version-operator from manifests/ in previous releases.
+var cvoAnnotations = map[string]string{
+	"include.release.openshift.io/hypershift":                     "true",
+	"include.release.openshift.io/ibm-cloud-managed":              "true",
+	"include.release.openshift.io/self-managed-high-availability": "true",
+	"include.release.openshift.io/single-node-developer":          "true",
+	"capability.openshift.io/name":                                "Storage",
+}
+
+// getCVOManagedPrometheusRule returns the PrometheusRule as left behind by
+// the cluster-version-operator after upgrade.
+func getCVOManagedPrometheusRule() *unstructured.Unstructured {
+	rule := getModifiedPrometheusRule()
+	rule.SetAnnotations(cvoAnnotations)
+	return rule
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name                   string
+		configMap              *v1.ConfigMap
+		initialRules           []runtime.Object
+		expectErr              bool
+		expectedFillingUpRules int
+		expectedAnnotations    map[string]string
+	}{
+		{
+			name:                   "rule is created",
+			expectedFillingUpRules: 2,
+		},
+		{
+			name:                   "modified rule is overwritten",
+			initialRules:           []runtime.Object{getModifiedPrometheusRule()},
+			expectedFillingUpRules: 2,
+		},
+		{
+			name:                   "rule installed by CVO is overwritten",
+			initialRules:           []runtime.Object{getCVOManagedPrometheusRule()},
+			expectedFillingUpRules: 2,
+			expectedAnnotations:    cvoAnnotations,
+		},
+		{
+			name: "overrides are applied",
+			configMap: getOverridesConfigMap(`
+alerts:
+  KubePersistentVolumeFillingUp/warning:
+    disabled: true
+`),
+			expectedFillingUpRules: 1,
+		},
+		{
+			name:      "invalid ConfigMap",
+			configMap: getOverridesConfigMap("alerts: {foo: {unknown: true}}"),
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+				DynamicObjects:  test.initialRules,
+			}
+			if test.configMap != nil {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, test.configMap)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			recorder := events.NewInMemoryRecorder("test")
+			ctrl := NewStorageRulesController(clients, 0, recorder)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).WaitForCacheSync(ctx.Done())
+
+			err := ctrl.Sync(ctx, factory.NewSyncContext("test", recorder))
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rule, err := clients.DynamicClient.Resource(prometheusRuleGVR).Namespace(csoclients.OperatorNamespace).Get(ctx, "prometheus", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expected PrometheusRule to exist: %v", err)
+			}
+			if rules := findAlerts(t, rule, "KubePersistentVolumeFillingUp"); len(rules) != test.expectedFillingUpRules {
+				t.Errorf("expected %d KubePersistentVolumeFillingUp rules, got %d", test.expectedFillingUpRules, len(rules))
+			}
+			annotations := rule.GetAnnotations()
+			for key, value := range test.expectedAnnotations {
+				if annotations[key] != value {
+					t.Errorf("expected annotation %s=%q, got %q", key, value, annotations[key])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package prometheusrules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Overrides are user changes of alerting rules rendered from an asset.
+// Example:
+//
+//	alerts:
+//	  # Applies to all rules of the alert
+//	  PodStartupStorageOperationsFailing:
+//	    disabled: true
+//	  # Applies only to rules of the alert with severity "warning" in the asset
+//	  KubePersistentVolumeFillingUp/warning:
+//	    for: 2h
+//	    severity: info
+//	thresholds:
+//	  PV_FILLING_UP_CRITICAL_THRESHOLD: 0.05
+type Overrides struct {
+	// Alerts are keyed by alert name, optionally followed by "/<severity>"
+	// to select only rules with the given severity in the asset.
+	Alerts map[string]AlertOverride `yaml:"alerts,omitempty"`
+	// Thresholds replace ${NAME} placeholders in rule expressions.
+	Thresholds map[string]float64 `yaml:"thresholds,omitempty"`
+}
+
+type AlertOverride struct {
+	Disabled bool   `yaml:"disabled,omitempty"`
+	For      string `yaml:"for,omitempty"`
+	Severity string `yaml:"severity,omitempty"`
+}
+
+const (
+	configKey = "config.yaml"
+
+	severityLabel = "severity"
+)
+
+var (
+	validSeverities = sets.New[string]("critical", "warning", "info")
+	// The same pattern as in PrometheusRule CRD.
+	durationRegexp = regexp.MustCompile(`^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`)
+)
+
+// ParseOverridesConfigMap parses Overrides from ConfigMap configMapName in the operator namespace.
+// Missing ConfigMap means no overrides.
+func ParseOverridesConfigMap(lister listerv1.ConfigMapLister, configMapName string) (*Overrides, error) {
+	cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(configMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("Using default alerts, %s does not exist", configMapName)
+			return &Overrides{}, nil
+		}
+		return nil, err
+	}
+
+	data, found := cm.Data[configKey]
+	if !found {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: expected key %s", configMapName, configKey)
+	}
+
+	overrides := &Overrides{}
+	if err := yaml.UnmarshalStrict([]byte(data), overrides); err != nil {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: %s", configMapName, err)
+	}
+	if err := overrides.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ConfigMap %s: %s", configMapName, err)
+	}
+	klog.V(4).Infof("Parsed ConfigMap %s: %+v", configMapName, overrides)
+	return overrides, nil
+}
+
+// Validate checks values of the overrides. Alert names and threshold names
+// are checked when the overrides are applied to a PrometheusRule.
+func (o *Overrides) Validate() error {
+	var errs []string
+	for _, key := range sets.List(sets.KeySet(o.Alerts)) {
+		override := o.Alerts[key]
+		if _, severity := splitAlertKey(key); severity != "" && !validSeverities.Has(severity) {
+			errs = append(errs, fmt.Sprintf("alert %s: invalid severity %q in the name", key, severity))
+		}
+		if override.For != "" && !durationRegexp.MatchString(override.For) {
+			errs = append(errs, fmt.Sprintf("alert %s: invalid duration %q", key, override.For))
+		}
+		if override.Severity != "" && !validSeverities.Has(override.Severity) {
+			errs = append(errs, fmt.Sprintf("alert %s: invalid severity %q", key, override.Severity))
+		}
+	}
+	for _, name := range sets.List(sets.KeySet(o.Thresholds)) {
+		if o.Thresholds[name] < 0 {
+			errs = append(errs, fmt.Sprintf("threshold %s: must not be negative", name))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// thresholdReplacer returns replacer of ${NAME} placeholders, using the
+// overrides when set and defaults otherwise.
+func (o *Overrides) thresholdReplacer(defaults map[string]float64) (*strings.Replacer, error) {
+	for _, name := range sets.List(sets.KeySet(o.Thresholds)) {
+		if _, found := defaults[name]; !found {
+			return nil, fmt.Errorf("unknown threshold %s", name)
+		}
+	}
+
+	var pairs []string
+	for _, name := range sets.List(sets.KeySet(defaults)) {
+		value := defaults[name]
+		if override, found := o.Thresholds[name]; found {
+			value = override
+		}
+		pairs = append(pairs, "${"+name+"}", strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	return strings.NewReplacer(pairs...), nil
+}
+
+// splitAlertKey splits "<alert>/<severity>" key of Overrides.Alerts.
+func splitAlertKey(key string) (string, string) {
+	alert, severity, _ := strings.Cut(key, "/")
+	return alert, severity
+}
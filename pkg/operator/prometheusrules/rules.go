@@ -0,0 +1,141 @@
+package prometheusrules
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	promscheme "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/scheme"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	genericScheme = runtime.NewScheme()
+	genericCodecs = serializer.NewCodecFactory(genericScheme)
+	genericCodec  = genericCodecs.UniversalDeserializer()
+
+	placeholderRegexp = regexp.MustCompile(`\$\{[A-Za-z0-9_]+\}`)
+)
+
+func init() {
+	if err := promscheme.AddToScheme(genericScheme); err != nil {
+		panic(err)
+	}
+}
+
+// RuleAsset is a PrometheusRule asset that can be tuned by Overrides.
+type RuleAsset struct {
+	// File is name of the asset.
+	File string
+	// Thresholds are default values of ${NAME} placeholders in the asset.
+	Thresholds map[string]float64
+}
+
+// Render reads the asset and returns the PrometheusRule with applied overrides.
+// Overrides of alerts or thresholds that do not exist in the asset are reported
+// as an error, they are most probably typos.
+func (a RuleAsset) Render(overrides *Overrides) (*unstructured.Unstructured, error) {
+	ruleBytes, err := assets.ReadFile(a.File)
+	if err != nil {
+		return nil, err
+	}
+
+	replacer, err := overrides.thresholdReplacer(a.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	ruleBytes = []byte(replacer.Replace(string(ruleBytes)))
+	if placeholder := placeholderRegexp.Find(ruleBytes); placeholder != nil {
+		return nil, fmt.Errorf("%s: missing value of %s", a.File, placeholder)
+	}
+
+	rule, err := parsePrometheusRule(ruleBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.File, err)
+	}
+	if err := applyAlertOverrides(rule, overrides.Alerts); err != nil {
+		return nil, err
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(rule)
+	if err != nil {
+		return nil, err
+	}
+	// Not set in the assets, it's added by the conversion.
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+func parsePrometheusRule(ruleBytes []byte) (*promv1.PrometheusRule, error) {
+	requiredObj, gvk, err := genericCodec.Decode(ruleBytes, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode PrometheusRule: %v", err)
+	}
+
+	rule, ok := requiredObj.(*promv1.PrometheusRule)
+	if !ok {
+		return nil, fmt.Errorf("invalid prometheusrule: %+v", requiredObj)
+	}
+	rule.SetGroupVersionKind(*gvk)
+	return rule, nil
+}
+
+// applyAlertOverrides changes alerting rules in place. An override of
+// "<alert>/<severity>" is applied after the override of "<alert>", so it wins.
+func applyAlertOverrides(rule *promv1.PrometheusRule, alerts map[string]AlertOverride) error {
+	used := sets.New[string]()
+	var groups []promv1.RuleGroup
+	for _, group := range rule.Spec.Groups {
+		var rules []promv1.Rule
+		for _, r := range group.Rules {
+			if r.Alert == "" {
+				// Recording rule
+				rules = append(rules, r)
+				continue
+			}
+
+			disabled := false
+			for _, key := range []string{r.Alert, r.Alert + "/" + r.Labels[severityLabel]} {
+				override, found := alerts[key]
+				if !found {
+					continue
+				}
+				used.Insert(key)
+				if override.Disabled {
+					disabled = true
+				}
+				if override.For != "" {
+					duration := promv1.Duration(override.For)
+					r.For = &duration
+				}
+				if override.Severity != "" {
+					labels := make(map[string]string, len(r.Labels)+1)
+					for k, v := range r.Labels {
+						labels[k] = v
+					}
+					labels[severityLabel] = override.Severity
+					r.Labels = labels
+				}
+			}
+			if !disabled {
+				rules = append(rules, r)
+			}
+		}
+		if len(rules) == 0 {
+			// All alerts of the group are disabled
+			continue
+		}
+		group.Rules = rules
+		groups = append(groups, group)
+	}
+
+	if unknown := sets.KeySet(alerts).Difference(used); unknown.Len() > 0 {
+		return fmt.Errorf("PrometheusRule %s does not have alerts %v", rule.Name, sets.List(unknown))
+	}
+	rule.Spec.Groups = groups
+	return nil
+}
@@ -0,0 +1,181 @@
+package prometheusrules
+
+import (
+	"strings"
+	"testing"
+
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// findAlerts returns all rules of the alert in the PrometheusRule.
+func findAlerts(t *testing.T, obj *unstructured.Unstructured, alert string) []promv1.Rule {
+	rule := &promv1.PrometheusRule{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, rule); err != nil {
+		t.Fatalf("failed to convert PrometheusRule: %v", err)
+	}
+	var rules []promv1.Rule
+	for _, group := range rule.Spec.Groups {
+		for _, r := range group.Rules {
+			if r.Alert == alert {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name          string
+		overrides     *Overrides
+		expectedError string
+		check         func(t *testing.T, obj *unstructured.Unstructured)
+	}{
+		{
+			name:      "no overrides",
+			overrides: &Overrides{},
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				rules := findAlerts(t, obj, "KubePersistentVolumeFillingUp")
+				if len(rules) != 2 {
+					t.Fatalf("expected 2 KubePersistentVolumeFillingUp rules, got %d", len(rules))
+				}
+				if !strings.Contains(rules[0].Expr.String(), ") < 0.03\n") {
+					t.Errorf("expected default critical threshold, got %s", rules[0].Expr.String())
+				}
+				if !strings.Contains(rules[1].Expr.String(), ") < 0.15\n") {
+					t.Errorf("expected default warning threshold, got %s", rules[1].Expr.String())
+				}
+				if len(findAlerts(t, obj, "PodStartupStorageOperationsFailing")) != 1 {
+					t.Errorf("expected PodStartupStorageOperationsFailing alert")
+				}
+			},
+		},
+		{
+			name: "threshold override",
+			overrides: &Overrides{
+				Thresholds: map[string]float64{"PV_FILLING_UP_CRITICAL_THRESHOLD": 0.05},
+			},
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				rules := findAlerts(t, obj, "KubePersistentVolumeFillingUp")
+				if !strings.Contains(rules[0].Expr.String(), ") < 0.05\n") {
+					t.Errorf("expected overridden critical threshold, got %s", rules[0].Expr.String())
+				}
+				if !strings.Contains(rules[1].Expr.String(), ") < 0.15\n") {
+					t.Errorf("expected default warning threshold, got %s", rules[1].Expr.String())
+				}
+			},
+		},
+		{
+			name: "disabled alert",
+			overrides: &Overrides{
+				Alerts: map[string]AlertOverride{"PodStartupStorageOperationsFailing": {Disabled: true}},
+			},
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				if len(findAlerts(t, obj, "PodStartupStorageOperationsFailing")) != 0 {
+					t.Errorf("expected PodStartupStorageOperationsFailing to be removed")
+				}
+				groups, _, _ := unstructured.NestedSlice(obj.Object, "spec", "groups")
+				for _, group := range groups {
+					if group.(map[string]interface{})["name"] == "storage-operations.rules" {
+						t.Errorf("expected empty group storage-operations.rules to be removed")
+					}
+				}
+			},
+		},
+		{
+			name: "for and severity of a single severity",
+			overrides: &Overrides{
+				Alerts: map[string]AlertOverride{
+					"KubePersistentVolumeFillingUp":         {For: "5m"},
+					"KubePersistentVolumeFillingUp/warning": {For: "2h", Severity: "info"},
+				},
+			},
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				rules := findAlerts(t, obj, "KubePersistentVolumeFillingUp")
+				if *rules[0].For != "5m" || rules[0].Labels[severityLabel] != "critical" {
+					t.Errorf("unexpected critical rule: for %s, severity %s", *rules[0].For, rules[0].Labels[severityLabel])
+				}
+				if *rules[1].For != "2h" || rules[1].Labels[severityLabel] != "info" {
+					t.Errorf("unexpected warning rule: for %s, severity %s", *rules[1].For, rules[1].Labels[severityLabel])
+				}
+			},
+		},
+		{
+			name: "unknown alert",
+			overrides: &Overrides{
+				Alerts: map[string]AlertOverride{"KubePersistentVolumeFillingUp/info": {Disabled: true}},
+			},
+			expectedError: "PrometheusRule prometheus does not have alerts [KubePersistentVolumeFillingUp/info]",
+		},
+		{
+			name: "unknown threshold",
+			overrides: &Overrides{
+				Thresholds: map[string]float64{"FOO": 1},
+			},
+			expectedError: "unknown threshold FOO",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj, err := storageRuleAsset.Render(test.overrides)
+			if test.expectedError != "" {
+				if err == nil || err.Error() != test.expectedError {
+					t.Fatalf("expected error %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if obj.GetName() != "prometheus" || obj.GetKind() != "PrometheusRule" {
+				t.Errorf("unexpected object %s %s", obj.GetKind(), obj.GetName())
+			}
+			test.check(t, obj)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		overrides     Overrides
+		expectedError string
+	}{
+		{
+			name: "valid",
+			overrides: Overrides{
+				Alerts: map[string]AlertOverride{
+					"KubePersistentVolumeFillingUp/warning": {For: "1h30m", Severity: "info"},
+				},
+				Thresholds: map[string]float64{"PV_FILLING_UP_CRITICAL_THRESHOLD": 0.1},
+			},
+		},
+		{
+			name: "invalid values",
+			overrides: Overrides{
+				Alerts: map[string]AlertOverride{
+					"A/error": {},
+					"B":       {For: "1 hour", Severity: "page"},
+				},
+				Thresholds: map[string]float64{"PV_FILLING_UP_CRITICAL_THRESHOLD": -1},
+			},
+			expectedError: `alert A/error: invalid severity "error" in the name; alert B: invalid duration "1 hour"; alert B: invalid severity "page"; threshold PV_FILLING_UP_CRITICAL_THRESHOLD: must not be negative`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.overrides.Validate()
+			var errString string
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("expected error %q, got %q", test.expectedError, errString)
+			}
+		})
+	}
+}
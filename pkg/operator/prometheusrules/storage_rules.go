@@ -0,0 +1,75 @@
+package prometheusrules
+
+import (
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	storageRulesControllerName = "StoragePrometheusRulesController"
+	// ConfigMap in the operator namespace with Overrides of the cluster-wide storage alerts.
+	storageRulesConfigMapName = "cluster-storage-operator-alerts"
+
+	// PVCPendingThresholdSeconds is the threshold of PersistentVolumeClaimPending alert.
+	PVCPendingThresholdSeconds = "PVC_PENDING_THRESHOLD_SECONDS"
+)
+
+// Cluster-wide storage alerts, such as KubePersistentVolumeFillingUp or PodStartupStorageOperationsFailing.
+var storageRuleAsset = RuleAsset{
+	File: "prometheusrules/12_prometheusrules.yaml",
+	Thresholds: map[string]float64{
+		// Ratio of free space of a PV
+		"PV_FILLING_UP_CRITICAL_THRESHOLD": 0.03,
+		"PV_FILLING_UP_WARNING_THRESHOLD":  0.15,
+		// Ratio of free inodes of a PV
+		"PV_INODES_FILLING_UP_CRITICAL_THRESHOLD": 0.03,
+		"PV_INODES_FILLING_UP_WARNING_THRESHOLD":  0.15,
+		// Duration of Pending phase of a PVC
+		PVCPendingThresholdSeconds: 900,
+	},
+}
+
+// NewStorageRulesController returns a controller that manages the cluster-wide storage alerts.
+func NewStorageRulesController(
+	clients *csoclients.Clients,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	return NewController(storageRulesControllerName, storageRuleAsset, storageRulesConfigMapName, clients, resyncInterval, eventRecorder)
+}
+
+// StorageRulesThreshold returns the current value of a threshold of the cluster-wide
+// storage alerts. Invalid overrides are ignored here, StoragePrometheusRulesController
+// reports them.
+func StorageRulesThreshold(lister corelisters.ConfigMapLister, name string) float64 {
+	value := storageRuleAsset.Thresholds[name]
+	overrides, err := ParseOverridesConfigMap(lister, storageRulesConfigMapName)
+	if err != nil {
+		klog.V(2).Infof("Using default value of threshold %s: %s", name, err)
+		return value
+	}
+	if override, found := overrides.Thresholds[name]; found {
+		return override
+	}
+	return value
+}
+
+// StorageRulesAsset returns the asset with cluster-wide storage alerts.
+func StorageRulesAsset() RuleAsset {
+	return storageRuleAsset
+}
+
+// RenderStorageRules returns the PrometheusRule with cluster-wide storage alerts,
+// with overrides from the ConfigMap, as StoragePrometheusRulesController applies it.
+func RenderStorageRules(lister corelisters.ConfigMapLister) (*unstructured.Unstructured, error) {
+	overrides, err := ParseOverridesConfigMap(lister, storageRulesConfigMapName)
+	if err != nil {
+		return nil, err
+	}
+	return storageRuleAsset.Render(overrides)
+}
@@ -0,0 +1,66 @@
+package releasedpv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// CleanupConfig is configuration of cleanup of Released PVs.
+// Example:
+//
+//	enabled: true
+//	minAge: 72h
+type CleanupConfig struct {
+	// Enabled enables deletion of PVs with Delete reclaim policy that are
+	// Released longer than MinAge.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MinAge is the minimal time a PV must be Released before it's deleted.
+	MinAge time.Duration `yaml:"minAge,omitempty"`
+}
+
+var (
+	defaultCleanupConfig = CleanupConfig{
+		// The cleanup is opt-in
+		Enabled: false,
+		MinAge:  7 * 24 * time.Hour,
+	}
+)
+
+const (
+	cleanupConfigMapName = "released-pv-cleanup"
+	configKey            = "config.yaml"
+)
+
+func ParseCleanupConfigMap(lister listerv1.ConfigMapLister) (*CleanupConfig, error) {
+	cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(cleanupConfigMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Missing ConfigMap indicates default config
+			klog.V(4).Infof("Using default config, %s does not exist", cleanupConfigMapName)
+			return &defaultCleanupConfig, nil
+		}
+		return nil, err
+	}
+
+	data, found := cm.Data[configKey]
+	if !found {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: expected key %s", cleanupConfigMapName, configKey)
+	}
+
+	config := defaultCleanupConfig
+	err = yaml.UnmarshalStrict([]byte(data), &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: %s", cleanupConfigMapName, err)
+	}
+	if config.MinAge <= 0 {
+		return nil, fmt.Errorf("invalid format of ConfigMap %s: minAge must be positive, got %s", cleanupConfigMapName, config.MinAge)
+	}
+	klog.V(4).Infof("Parsed ConfigMap %s: %+v", cleanupConfigMapName, config)
+	return &config, nil
+}
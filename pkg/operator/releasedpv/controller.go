@@ -0,0 +1,259 @@
+package releasedpv
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	conditionsPrefix = "ReleasedPVController"
+
+	reportConfigMapName = "released-persistent-volumes"
+	reportKey           = "report.yaml"
+
+	// Max. number of PVs listed in the report, to keep the ConfigMap under its size limit.
+	maxReportedPVs = 1000
+
+	// Added by external-provisioner to PVs it provisioned. The provisioner deletes
+	// the volume in the storage backend before it removes the finalizer.
+	provisionerFinalizer = "external-provisioner.volume.kubernetes.io/finalizer"
+)
+
+var (
+	releasedPVBytes = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "cso",
+			Name:           "released_pv_bytes",
+			Help:           "Total capacity of Released PersistentVolumes of CSI drivers installed by the cluster-storage-operator.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "storageclass"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(releasedPVBytes)
+}
+
+// report is the content of the report ConfigMap.
+type report struct {
+	// Released and Failed PVs, keyed by CSI driver name.
+	Drivers map[string][]pvReport `yaml:"drivers,omitempty"`
+	// Number of PVs not listed in the report because of its size.
+	Omitted int `yaml:"omitted,omitempty"`
+}
+
+type pvReport struct {
+	Name          string `yaml:"name"`
+	StorageClass  string `yaml:"storageClass,omitempty"`
+	Phase         string `yaml:"phase"`
+	ReclaimPolicy string `yaml:"reclaimPolicy"`
+	Capacity      string `yaml:"capacity,omitempty"`
+	// Time of the last phase transition. It's not a duration, so the ConfigMap is
+	// not updated on each sync.
+	Since string `yaml:"since,omitempty"`
+}
+
+// This Controller lists Released and Failed PVs of CSI drivers installed by CSO
+// and publishes them in ConfigMap released-persistent-volumes and as
+// cso_released_pv_bytes metric.
+// When enabled in ConfigMap released-pv-cleanup, it deletes PVs with Delete
+// reclaim policy that are Released for a long time. The CSI driver then
+// deletes the volumes in the storage backend.
+// It produces following Conditions:
+// ReleasedPVControllerDegraded - failed to parse the config or publish the report.
+type Controller struct {
+	operatorClient  v1helpers.OperatorClient
+	kubeClient      kubernetes.Interface
+	pvLister        corelisters.PersistentVolumeLister
+	configMapLister corelisters.ConfigMapLister
+	driversGetter   csidriveroperator.RunningDriversGetter
+	eventRecorder   events.Recorder
+	now             func() time.Time
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	driversGetter csidriveroperator.RunningDriversGetter,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:  clients.OperatorClient,
+		kubeClient:      clients.KubeClient,
+		pvLister:        clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Lister(),
+		configMapLister: clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		driversGetter:   driversGetter,
+		eventRecorder:   eventRecorder.WithComponentSuffix(conditionsPrefix),
+		now:             time.Now,
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(conditionsPrefix, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+	).ResyncEvery(resyncInterval).ToController(conditionsPrefix, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("ReleasedPVController sync started")
+	defer klog.V(4).Infof("ReleasedPVController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	cleanupConfig, err := ParseCleanupConfigMap(c.configMapLister)
+	if err != nil {
+		return err
+	}
+
+	drivers, synced := c.driversGetter.RunningDrivers()
+	if !synced {
+		klog.V(4).Infof("Waiting for CSI driver operators to be evaluated")
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), 5*time.Second)
+		return nil
+	}
+	managedDrivers := sets.New[string]()
+	for _, cfg := range drivers {
+		managedDrivers.Insert(cfg.CSIDriverName)
+	}
+
+	pvs, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	sort.Slice(pvs, func(i, j int) bool {
+		return pvs[i].Name < pvs[j].Name
+	})
+
+	r := report{}
+	releasedPVBytes.Reset()
+	reported := 0
+	for _, pv := range pvs {
+		if pv.Spec.CSI == nil || !managedDrivers.Has(pv.Spec.CSI.Driver) {
+			continue
+		}
+		if pv.Status.Phase != v1.VolumeReleased && pv.Status.Phase != v1.VolumeFailed {
+			continue
+		}
+		if pv.DeletionTimestamp != nil {
+			continue
+		}
+
+		if cleanupConfig.Enabled && c.shouldDelete(pv, cleanupConfig.MinAge) {
+			deleted, err := c.deletePV(ctx, pv)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				continue
+			}
+		}
+
+		driver := pv.Spec.CSI.Driver
+		capacity := pv.Spec.Capacity[v1.ResourceStorage]
+		if pv.Status.Phase == v1.VolumeReleased {
+			releasedPVBytes.WithLabelValues(driver, pv.Spec.StorageClassName).Add(float64(capacity.Value()))
+		}
+
+		if reported == maxReportedPVs {
+			r.Omitted++
+			continue
+		}
+		reported++
+		pvr := pvReport{
+			Name:          pv.Name,
+			StorageClass:  pv.Spec.StorageClassName,
+			Phase:         string(pv.Status.Phase),
+			ReclaimPolicy: string(pv.Spec.PersistentVolumeReclaimPolicy),
+		}
+		if !capacity.IsZero() {
+			pvr.Capacity = capacity.String()
+		}
+		if pv.Status.LastPhaseTransitionTime != nil {
+			pvr.Since = pv.Status.LastPhaseTransitionTime.UTC().Format(time.RFC3339)
+		}
+		if r.Drivers == nil {
+			r.Drivers = map[string][]pvReport{}
+		}
+		r.Drivers[driver] = append(r.Drivers[driver], pvr)
+	}
+
+	return c.publishReport(ctx, &r)
+}
+
+// shouldDelete returns true if the PV is Released with Delete reclaim policy longer than minAge.
+func (c *Controller) shouldDelete(pv *v1.PersistentVolume, minAge time.Duration) bool {
+	if pv.Status.Phase != v1.VolumeReleased || pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
+		return false
+	}
+	if pv.Status.LastPhaseTransitionTime == nil {
+		// The age of the PV is not known.
+		return false
+	}
+	if !sets.New[string](pv.Finalizers...).Has(provisionerFinalizer) {
+		// Deleting the PV would leak the volume in the storage backend.
+		return false
+	}
+	return c.now().Sub(pv.Status.LastPhaseTransitionTime.Time) >= minAge
+}
+
+// deletePV deletes the PV and returns true if it was deleted.
+func (c *Controller) deletePV(ctx context.Context, pv *v1.PersistentVolume) (bool, error) {
+	klog.V(2).Infof("Deleting PV %s, it is Released since %s", pv.Name, pv.Status.LastPhaseTransitionTime)
+	err := c.kubeClient.CoreV1().PersistentVolumes().Delete(ctx, pv.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &pv.UID, ResourceVersion: &pv.ResourceVersion},
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsConflict(err) {
+			// The PV was deleted or updated in the meantime, it will be evaluated in the next sync.
+			return false, nil
+		}
+		return false, err
+	}
+	c.eventRecorder.Eventf("ReleasedPVDeleted", "Deleted PersistentVolume %s of CSI driver %s, it was Released since %s",
+		pv.Name, pv.Spec.CSI.Driver, pv.Status.LastPhaseTransitionTime.UTC().Format(time.RFC3339))
+	return true, nil
+}
+
+func (c *Controller) publishReport(ctx context.Context, r *report) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reportConfigMapName,
+			Namespace: csoclients.OperatorNamespace,
+		},
+		Data: map[string]string{
+			reportKey: string(data),
+		},
+	}
+	_, _, err = resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, cm)
+	return err
+}
@@ -0,0 +1,189 @@
+package releasedpv
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeDriversGetter struct {
+	drivers []csioperatorclient.CSIOperatorConfig
+}
+
+func (f *fakeDriversGetter) RunningDrivers() ([]csioperatorclient.CSIOperatorConfig, bool) {
+	return f.drivers, true
+}
+
+func getPV(name, driver string, phase v1.PersistentVolumePhase, policy v1.PersistentVolumeReclaimPolicy, age time.Duration) *v1.PersistentVolume {
+	since := metav1.NewTime(now.Add(-age))
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Finalizers: []string{provisionerFinalizer},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:                      v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			PersistentVolumeSource:        v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: driver}},
+			PersistentVolumeReclaimPolicy: policy,
+			StorageClassName:              "gp3-csi",
+		},
+		Status: v1.PersistentVolumeStatus{
+			Phase:                   phase,
+			LastPhaseTransitionTime: &since,
+		},
+	}
+}
+
+func getCleanupConfigMap(config string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cleanupConfigMapName, Namespace: csoclients.OperatorNamespace},
+		Data:       map[string]string{configKey: config},
+	}
+}
+
+func TestSync(t *testing.T) {
+	aws := csioperatorclient.CSIOperatorConfig{CSIDriverName: csioperatorclient.AWSEBSCSIDriverName}
+
+	pvs := []runtime.Object{
+		getPV("bound", aws.CSIDriverName, v1.VolumeBound, v1.PersistentVolumeReclaimDelete, 30*24*time.Hour),
+		getPV("other-driver", "other.csi.example.com", v1.VolumeReleased, v1.PersistentVolumeReclaimRetain, 30*24*time.Hour),
+		getPV("old-delete", aws.CSIDriverName, v1.VolumeReleased, v1.PersistentVolumeReclaimDelete, 30*24*time.Hour),
+		getPV("young-delete", aws.CSIDriverName, v1.VolumeReleased, v1.PersistentVolumeReclaimDelete, time.Hour),
+		getPV("old-retain", aws.CSIDriverName, v1.VolumeReleased, v1.PersistentVolumeReclaimRetain, 30*24*time.Hour),
+		getPV("failed", aws.CSIDriverName, v1.VolumeFailed, v1.PersistentVolumeReclaimDelete, 30*24*time.Hour),
+	}
+	oldDeleteReport := `
+  - name: old-delete
+    storageClass: gp3-csi
+    phase: Released
+    reclaimPolicy: Delete
+    capacity: 1Gi
+    since: "2023-12-02T12:00:00Z"`
+	restReport := `
+  - name: old-retain
+    storageClass: gp3-csi
+    phase: Released
+    reclaimPolicy: Retain
+    capacity: 1Gi
+    since: "2023-12-02T12:00:00Z"
+  - name: young-delete
+    storageClass: gp3-csi
+    phase: Released
+    reclaimPolicy: Delete
+    capacity: 1Gi
+    since: "2024-01-01T11:00:00Z"
+`
+
+	tests := []struct {
+		name           string
+		configMaps     []runtime.Object
+		expectedReport string
+		expectedBytes  string
+		expectDeleted  bool
+		expectError    bool
+	}{
+		{
+			name: "report without cleanup",
+			expectedReport: `drivers:
+  ebs.csi.aws.com:
+  - name: failed
+    storageClass: gp3-csi
+    phase: Failed
+    reclaimPolicy: Delete
+    capacity: 1Gi
+    since: "2023-12-02T12:00:00Z"` + oldDeleteReport + restReport,
+			expectedBytes: "3221225472",
+		},
+		{
+			name:       "report with cleanup",
+			configMaps: []runtime.Object{getCleanupConfigMap("enabled: true\nminAge: 72h\n")},
+			expectedReport: `drivers:
+  ebs.csi.aws.com:
+  - name: failed
+    storageClass: gp3-csi
+    phase: Failed
+    reclaimPolicy: Delete
+    capacity: 1Gi
+    since: "2023-12-02T12:00:00Z"` + restReport,
+			expectedBytes: "2147483648",
+			expectDeleted: true,
+		},
+		{
+			name:        "invalid config",
+			configMaps:  []runtime.Object{getCleanupConfigMap("enabled: true\nminAge: -1h\n")},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				CoreObjects:     append(append([]runtime.Object{}, pvs...), test.configMaps...),
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			})
+			recorder := events.NewInMemoryRecorder("operator")
+			c := &Controller{
+				operatorClient:  clients.OperatorClient,
+				kubeClient:      clients.KubeClient,
+				pvLister:        clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Lister(),
+				configMapLister: clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+				driversGetter:   &fakeDriversGetter{drivers: []csioperatorclient.CSIOperatorConfig{aws}},
+				eventRecorder:   recorder,
+				now:             func() time.Time { return now },
+			}
+			clients.OperatorClient.Informer()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).WaitForCacheSync(ctx.Done())
+
+			err := c.sync(ctx, factory.NewSyncContext("test", recorder))
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			cm, err := clients.KubeClient.CoreV1().ConfigMaps(csoclients.OperatorNamespace).Get(ctx, reportConfigMapName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get report ConfigMap: %v", err)
+			}
+			if cm.Data[reportKey] != test.expectedReport {
+				t.Errorf("unexpected report:\n%s\nexpected:\n%s", cm.Data[reportKey], test.expectedReport)
+			}
+
+			_, err = clients.KubeClient.CoreV1().PersistentVolumes().Get(ctx, "old-delete", metav1.GetOptions{})
+			if deleted := apierrors.IsNotFound(err); deleted != test.expectDeleted {
+				t.Errorf("expected PV old-delete deleted: %t, got %t", test.expectDeleted, deleted)
+			}
+
+			expectedMetric := `
+				# HELP cso_released_pv_bytes [ALPHA] Total capacity of Released PersistentVolumes of CSI drivers installed by the cluster-storage-operator.
+				# TYPE cso_released_pv_bytes gauge
+				cso_released_pv_bytes{driver="ebs.csi.aws.com",storageclass="gp3-csi"} ` + test.expectedBytes + "\n"
+			if err := testutil.CollectAndCompare(releasedPVBytes, strings.NewReader(expectedMetric), "cso_released_pv_bytes"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/defaultstorageclass"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+// renderDir is the output directory of objects of CSO controllers that are not
+// specific to a CSI driver operator.
+const renderDir = "StorageOperator"
+
+// RenderOptions are inputs of RunRender.
+type RenderOptions struct {
+	// InfrastructureFile is a YAML file with Infrastructure "cluster". Required.
+	InfrastructureFile string
+	// FeatureGateFile is a YAML file with FeatureGate "cluster" with populated
+	// status. All feature gates are disabled when empty.
+	FeatureGateFile string
+	// ReleaseVersion selects FeatureGate status of the version. Can be empty
+	// when the FeatureGate has status of a single version.
+	ReleaseVersion string
+	// StorageFile is a YAML file with Storage "cluster". A Managed Storage CR
+	// with default values is used when empty.
+	StorageFile string
+	// ImagesEnvFile is a file with KEY=VALUE lines, the same env. vars as
+	// the operator Deployment has. Optional.
+	ImagesEnvFile string
+	// ConfigMapsFile is a YAML file with a ConfigMap or a List of ConfigMaps
+	// in the operator namespace, e.g. default-storage-class-templates or
+	// cluster-storage-operator-alerts. Optional.
+	ConfigMapsFile string
+	// HyperShift renders the objects as CSO running in HyperShift would.
+	HyperShift bool
+	// ControlNamespace is the HyperShift control plane namespace.
+	ControlNamespace string
+	// OutputDir is a directory where the rendered objects are written. Required.
+	OutputDir string
+}
+
+// RunRender renders all objects that CSO would create in a cluster described
+// by the options, without an API server. A summary of rendered CSI driver
+// operators is written to out.
+// Objects that CSO creates or changes based on the state of a live cluster
+// are not rendered, see the render command help.
+func RunRender(opts RenderOptions, out io.Writer) error {
+	if opts.InfrastructureFile == "" || opts.OutputDir == "" {
+		return fmt.Errorf("infrastructure file and output directory are required")
+	}
+	if opts.HyperShift && opts.ControlNamespace == "" {
+		return fmt.Errorf("control plane namespace is required in HyperShift")
+	}
+
+	if opts.ImagesEnvFile != "" {
+		if err := loadEnvFile(opts.ImagesEnvFile); err != nil {
+			return err
+		}
+	}
+
+	infrastructure := &configv1.Infrastructure{}
+	if err := readYAMLFile(opts.InfrastructureFile, infrastructure); err != nil {
+		return err
+	}
+	featureGates, err := readFeatureGates(opts.FeatureGateFile, opts.ReleaseVersion)
+	if err != nil {
+		return err
+	}
+	storage := &operatorv1.Storage{
+		Spec: operatorv1.StorageSpec{
+			OperatorSpec: operatorv1.OperatorSpec{ManagementState: operatorv1.Managed},
+		},
+	}
+	if opts.StorageFile != "" {
+		if err := readYAMLFile(opts.StorageFile, storage); err != nil {
+			return err
+		}
+	}
+	opSpec := &storage.Spec.OperatorSpec
+	if opSpec.ManagementState != operatorv1.Managed {
+		fmt.Fprintf(out, "Storage CR is %s, nothing to render\n", opSpec.ManagementState)
+		return nil
+	}
+
+	configMapLister, err := readConfigMaps(opts.ConfigMapsFile)
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+	if err := renderCommonObjects(files, configMapLister, infrastructure, opts.HyperShift); err != nil {
+		return err
+	}
+	if !opts.HyperShift {
+		ssr := &StandaloneStarter{}
+		for _, cfg := range ssr.populateProblemDetectorConfigs() {
+			rendered, err := problemdetector.Render(cfg, opSpec, infrastructure)
+			if err != nil {
+				return fmt.Errorf("failed to render %s: %w", cfg.ConditionPrefix, err)
+			}
+			if rendered == nil {
+				continue
+			}
+			fmt.Fprintf(out, "%s: rendered\n", cfg.ConditionPrefix)
+			for name, data := range rendered {
+				files[name] = data
+			}
+		}
+	}
+
+	input := csidriveroperator.RenderInput{
+		Infrastructure: infrastructure,
+		FeatureGates:   featureGates,
+		OperatorSpec:   opSpec,
+	}
+	if opts.HyperShift {
+		input.ControlNamespace = opts.ControlNamespace
+	}
+	for _, cfg := range offlineDriverConfigs(opts.HyperShift, infrastructure) {
+		result, err := csidriveroperator.RenderDriverOperator(cfg, input)
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", cfg.ConditionPrefix, err)
+		}
+		if !result.Run {
+			fmt.Fprintf(out, "%s: skipped (%s)\n", cfg.ConditionPrefix, result.Reason)
+			continue
+		}
+		fmt.Fprintf(out, "%s: rendered (%s)\n", cfg.ConditionPrefix, result.Reason)
+		for name, data := range result.Files {
+			files[name] = data
+		}
+	}
+
+	for _, name := range sets.List(sets.KeySet(files)) {
+		filePath := filepath.Join(opts.OutputDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, files[name], 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Written %d files to %s\n", len(files), opts.OutputDir)
+	return nil
+}
+
+// renderCommonObjects renders objects of CSO controllers that run regardless
+// of the platform.
+func renderCommonObjects(files map[string][]byte, configMapLister corelisters.ConfigMapLister, infrastructure *configv1.Infrastructure, hypershift bool) error {
+	if !hypershift {
+		// NetworkPolicies are not managed in HyperShift.
+		for _, asset := range networkPolicyAssets {
+			data, err := assets.ReadFile(asset)
+			if err != nil {
+				return err
+			}
+			files[path.Join(renderDir, path.Base(asset))] = data
+		}
+	}
+
+	rule, err := prometheusrules.RenderStorageRules(configMapLister)
+	if err != nil {
+		return fmt.Errorf("failed to render storage alerts: %w", err)
+	}
+	if err := addRenderedObject(files, path.Join(renderDir, path.Base(prometheusrules.StorageRulesAsset().File)), rule.Object); err != nil {
+		return err
+	}
+
+	sc, err := defaultstorageclass.Render(configMapLister, infrastructure)
+	if err != nil {
+		return fmt.Errorf("failed to render default StorageClass: %w", err)
+	}
+	if sc != nil {
+		sc.APIVersion = "storage.k8s.io/v1"
+		sc.Kind = "StorageClass"
+		if err := addRenderedObject(files, path.Join(renderDir, fmt.Sprintf("storage.k8s.io_v1_storageclass_%s.yaml", sc.Name)), sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addRenderedObject(files map[string][]byte, filePath string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filePath, err)
+	}
+	files[filePath] = data
+	return nil
+}
+
+// readConfigMaps returns a lister of ConfigMaps in a file with a ConfigMap or
+// a List of ConfigMaps. The lister is empty when fileName is empty.
+func readConfigMaps(fileName string) (corelisters.ConfigMapLister, error) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	lister := corelisters.NewConfigMapLister(indexer)
+	if fileName == "" {
+		return lister, nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	list := &struct {
+		metav1.TypeMeta
+		Items []corev1.ConfigMap `json:"items"`
+	}{}
+	if err := yaml.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	configMaps := list.Items
+	if list.Kind != "List" {
+		cm := corev1.ConfigMap{}
+		if err := yaml.Unmarshal(data, &cm); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+		}
+		configMaps = []corev1.ConfigMap{cm}
+	}
+	for i := range configMaps {
+		cm := &configMaps[i]
+		if cm.Namespace == "" {
+			cm.Namespace = csoclients.OperatorNamespace
+		}
+		if err := indexer.Add(cm); err != nil {
+			return nil, err
+		}
+	}
+	return lister, nil
+}
+
+// offlineDriverConfigs returns configs of all CSI driver operators that CSO
+// would manage. Some configs create extra controllers, they get fake clients
+// and they must not be started.
+func offlineDriverConfigs(hypershift bool, infrastructure *configv1.Infrastructure) []csioperatorclient.CSIOperatorConfig {
+	clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+		ConfigObjects: []runtime.Object{infrastructure},
+	})
+	recorder := events.NewInMemoryRecorder("offline")
+	if hypershift {
+		hsr := &HyperShiftStarter{}
+		hsr.eventRecorder = recorder
+		return hsr.populateConfigs(clients)
+	}
+	ssr := &StandaloneStarter{}
+	ssr.eventRecorder = recorder
+	return ssr.populateConfigs(clients)
+}
+
+func readYAMLFile(fileName string, obj interface{}) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+	return nil
+}
+
+// readFeatureGates returns feature gates of the given version from FeatureGate
+// in a file.
+func readFeatureGates(fileName, version string) (featuregates.FeatureGate, error) {
+	if fileName == "" {
+		return getFeatureGates(nil, version)
+	}
+	featureGate := &configv1.FeatureGate{}
+	if err := readYAMLFile(fileName, featureGate); err != nil {
+		return nil, err
+	}
+	return getFeatureGates(featureGate, version)
+}
+
+// getFeatureGates returns feature gates of the given version from FeatureGate
+// status. The version can be empty when the status has a single version.
+// All feature gates are disabled when featureGate is nil.
+func getFeatureGates(featureGate *configv1.FeatureGate, version string) (featuregates.FeatureGate, error) {
+	if featureGate == nil {
+		return featuregates.NewFeatureGate(nil, nil), nil
+	}
+	if version == "" {
+		if len(featureGate.Status.FeatureGates) != 1 {
+			return nil, fmt.Errorf("FeatureGate has status of %d versions, release version must be set", len(featureGate.Status.FeatureGates))
+		}
+		version = featureGate.Status.FeatureGates[0].Version
+	}
+	access, err := featuregates.NewHardcodedFeatureGateAccessFromFeatureGate(featureGate, version)
+	if err != nil {
+		return nil, err
+	}
+	return access.CurrentFeatureGates()
+}
+
+// loadEnvFile sets env. vars from a file with KEY=VALUE lines. Empty lines
+// and lines starting with # are ignored.
+func loadEnvFile(fileName string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", fileName, lineNr)
+		}
+		if err := os.Setenv(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
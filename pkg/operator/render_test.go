@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+)
+
+func TestRunRenderCommonObjects(t *testing.T) {
+	dir := t.TempDir()
+	infrastructureFile := filepath.Join(dir, "infrastructure.yaml")
+	writeFile(t, infrastructureFile, `apiVersion: config.openshift.io/v1
+kind: Infrastructure
+metadata:
+  name: cluster
+status:
+  platformStatus:
+    type: BareMetal
+`)
+	configMapsFile := filepath.Join(dir, "configmaps.yaml")
+	writeFile(t, configMapsFile, `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: default-storage-class-templates
+    namespace: openshift-cluster-storage-operator
+  data:
+    BareMetal: |
+      apiVersion: storage.k8s.io/v1
+      kind: StorageClass
+      metadata:
+        name: lvms
+      provisioner: topolvm.io
+`)
+
+	tests := []struct {
+		name          string
+		hypershift    bool
+		expectedFiles []string
+		missingFiles  []string
+	}{
+		{
+			name: "standalone",
+			expectedFiles: []string{
+				"StorageOperator/01_operator_default_deny.yaml",
+				"StorageOperator/11_csi_drivers_allow_metrics.yaml",
+				"StorageOperator/12_prometheusrules.yaml",
+				"StorageOperator/storage.k8s.io_v1_storageclass_lvms.yaml",
+			},
+		},
+		{
+			name:       "hypershift",
+			hypershift: true,
+			expectedFiles: []string{
+				"StorageOperator/12_prometheusrules.yaml",
+				"StorageOperator/storage.k8s.io_v1_storageclass_lvms.yaml",
+			},
+			missingFiles: []string{
+				"StorageOperator/01_operator_default_deny.yaml",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			opts := RenderOptions{
+				InfrastructureFile: infrastructureFile,
+				ConfigMapsFile:     configMapsFile,
+				HyperShift:         test.hypershift,
+				ControlNamespace:   "clusters-test",
+				OutputDir:          outputDir,
+			}
+			if err := RunRender(opts, io.Discard); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, name := range test.expectedFiles {
+				if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+					t.Errorf("expected file %s: %v", name, err)
+				}
+			}
+			for _, name := range test.missingFiles {
+				if _, err := os.Stat(filepath.Join(outputDir, name)); err == nil {
+					t.Errorf("unexpected file %s", name)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(outputDir, "StorageOperator/storage.k8s.io_v1_storageclass_lvms.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sc := resourceread.ReadStorageClassV1OrDie(data)
+			if sc.Provisioner != "topolvm.io" || sc.Annotations["storageclass.kubernetes.io/is-default-class"] != "true" {
+				t.Errorf("expected default StorageClass of topolvm.io, got %+v", sc)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, fileName, content string) {
+	t.Helper()
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,229 @@
+package selinuxreadiness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "SELinuxMountReadinessController"
+
+	// Not ending with Available / Degraded / Progressing / Upgradeable, SELinux
+	// context mismatches are caused by user workloads and they should not affect
+	// the ClusterOperator on their own.
+	readinessConditionType = "SELinuxMountReadiness"
+	// Rolls up into ClusterOperator Upgradeable, only when SELinuxMount feature
+	// gate is going to be enabled by the upgrade.
+	upgradeableConditionType = "SELinuxMountReadinessUpgradeable"
+
+	// SELinuxMount feature gate, it makes kubelet mount all volumes with
+	// "-o context" and Pods that share a volume with different SELinux contexts
+	// will fail to start.
+	selinuxMountFeatureGate = configv1.FeatureGateName("SELinuxMount")
+
+	// Volume plugins with SELinux context mismatches in the last day, as recorded
+	// by storage-selinux.rules. Both metrics are counters summed from all kubelets,
+	// they keep mismatches that are long gone until the kubelets restart.
+	volumePluginsQuery = `increase(cluster:volume_manager_selinux_volume_context_mismatch_errors_total[24h]) > 0 or increase(cluster:volume_manager_selinux_volume_context_mismatch_warnings_total[24h]) > 0`
+	// Namespaces of Pods that use the same volume with different SELinux contexts,
+	// as reported by SELinuxWarningController in kube-controller-manager.
+	namespacesQuery = `count by (pod1_namespace, pod2_namespace) (selinux_warning_controller_selinux_volume_conflict)`
+
+	volumePluginLabel  = "volume_plugin"
+	pod1NamespaceLabel = "pod1_namespace"
+	pod2NamespaceLabel = "pod2_namespace"
+)
+
+// This Controller evaluates whether the cluster is ready for SELinuxMount
+// feature gate by querying the in-cluster Prometheus for SELinux context
+// mismatches reported by kubelets and kube-controller-manager.
+// It produces following Conditions:
+// SELinuxMountReadiness - false when some volumes are used with different SELinux contexts,
+// unknown when Prometheus cannot be queried. The reason tells if Prometheus is
+// unreachable (PrometheusUnreachable), denies the query (PrometheusAccessDenied)
+// or the query failed (PrometheusQueryFailed).
+// SELinuxMountReadinessUpgradeable - false when there are SELinux context
+// mismatches and the upgrade enables SELinuxMount feature gate.
+type Controller struct {
+	operatorClient    v1helpers.OperatorClient
+	featureGateLister configlisters.FeatureGateLister
+	// Version of the cluster, as used in FeatureGate status.
+	currentVersion string
+	// prometheus is created on the first sync, it's not available in all clusters.
+	prometheus    *prometheusClient
+	newPrometheus func() (*prometheusClient, error)
+	eventRecorder events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	currentVersion string,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:    clients.OperatorClient,
+		featureGateLister: clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		currentVersion:    currentVersion,
+		newPrometheus:     newPrometheusClient,
+		eventRecorder:     eventRecorder.WithComponentSuffix(controllerName),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
+	).ResyncEvery(resyncInterval).ToController(controllerName, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("SELinuxMountReadinessController sync started")
+	defer klog.V(4).Infof("SELinuxMountReadinessController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	readinessCnd := operatorapi.OperatorCondition{
+		Type:   readinessConditionType,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	upgradeableCnd := operatorapi.OperatorCondition{
+		Type:   upgradeableConditionType,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+
+	volumePlugins, namespaces, err := c.getMismatches(ctx)
+	if err != nil {
+		// Monitoring is an optional capability, do not block upgrade nor degrade
+		// the operator when it's not available.
+		klog.V(2).Infof("Failed to evaluate SELinux context mismatches: %s", err)
+		readinessCnd.Status = operatorapi.ConditionUnknown
+		readinessCnd.Reason = "PrometheusQueryFailed"
+		var qErr *queryError
+		if errors.As(err, &qErr) {
+			readinessCnd.Reason = qErr.reason
+		}
+		readinessCnd.Message = fmt.Sprintf("Failed to query Prometheus: %s", err)
+		_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient,
+			v1helpers.UpdateConditionFn(readinessCnd),
+			v1helpers.UpdateConditionFn(upgradeableCnd))
+		return err
+	}
+
+	if len(volumePlugins) > 0 || len(namespaces) > 0 {
+		readinessCnd.Status = operatorapi.ConditionFalse
+		readinessCnd.Reason = "SELinuxContextMismatch"
+		readinessCnd.Message = mismatchMessage(volumePlugins, namespaces)
+
+		flips, err := c.featureGateFlips()
+		if err != nil {
+			return err
+		}
+		if flips {
+			upgradeableCnd.Status = operatorapi.ConditionFalse
+			upgradeableCnd.Reason = "SELinuxContextMismatch"
+			upgradeableCnd.Message = fmt.Sprintf("Feature gate %s will be enabled after upgrade and Pods with conflicting SELinux contexts may fail to start. %s", selinuxMountFeatureGate, readinessCnd.Message)
+		}
+	}
+
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient,
+		v1helpers.UpdateConditionFn(readinessCnd),
+		v1helpers.UpdateConditionFn(upgradeableCnd))
+	return err
+}
+
+// getMismatches returns volume plugins and namespaces with SELinux context mismatches.
+func (c *Controller) getMismatches(ctx context.Context) ([]string, []string, error) {
+	if c.prometheus == nil {
+		prometheus, err := c.newPrometheus()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.prometheus = prometheus
+	}
+
+	samples, err := c.prometheus.query(ctx, volumePluginsQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	volumePlugins := sets.New[string]()
+	for _, s := range samples {
+		if plugin := s.labels[volumePluginLabel]; plugin != "" {
+			volumePlugins.Insert(plugin)
+		}
+	}
+
+	samples, err = c.prometheus.query(ctx, namespacesQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespaces := sets.New[string]()
+	for _, s := range samples {
+		for _, label := range []string{pod1NamespaceLabel, pod2NamespaceLabel} {
+			if ns := s.labels[label]; ns != "" {
+				namespaces.Insert(ns)
+			}
+		}
+	}
+	return sets.List(volumePlugins), sets.List(namespaces), nil
+}
+
+// featureGateFlips returns true when SELinuxMount feature gate is disabled in
+// the current version and enabled in another version listed in FeatureGate status,
+// i.e. it's going to be enabled by an upgrade.
+func (c *Controller) featureGateFlips() (bool, error) {
+	featureGate, err := c.featureGateLister.Get("cluster")
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	enabledInCurrent := false
+	enabledInOther := false
+	for _, details := range featureGate.Status.FeatureGates {
+		enabled := false
+		for _, attr := range details.Enabled {
+			if attr.Name == selinuxMountFeatureGate {
+				enabled = true
+			}
+		}
+		if details.Version == c.currentVersion {
+			enabledInCurrent = enabled
+		} else if enabled {
+			enabledInOther = true
+		}
+	}
+	return !enabledInCurrent && enabledInOther, nil
+}
+
+func mismatchMessage(volumePlugins, namespaces []string) string {
+	var msgs []string
+	if len(volumePlugins) > 0 {
+		msgs = append(msgs, fmt.Sprintf("volume plugins with SELinux context mismatches: %s", strings.Join(volumePlugins, ", ")))
+	}
+	if len(namespaces) > 0 {
+		msgs = append(msgs, fmt.Sprintf("namespaces with Pods that share volumes with different SELinux contexts: %s", strings.Join(namespaces, ", ")))
+	}
+	return "Found " + strings.Join(msgs, "; ")
+}
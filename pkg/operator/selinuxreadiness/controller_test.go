@@ -0,0 +1,212 @@
+package selinuxreadiness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const currentVersion = "4.16.0"
+
+const emptyResponse = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+// fakePrometheus returns a Prometheus stand-in that responds to known queries.
+func fakePrometheus(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+		response, found := responses[r.URL.Query().Get("query")]
+		if !found {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"unexpected query"}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func getFeatureGate(enabledInVersions ...string) *configv1.FeatureGate {
+	fg := &configv1.FeatureGate{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	for _, version := range []string{currentVersion, "4.17.0"} {
+		details := configv1.FeatureGateDetails{Version: version}
+		attrs := []configv1.FeatureGateAttributes{{Name: selinuxMountFeatureGate}}
+		enabled := false
+		for _, v := range enabledInVersions {
+			if v == version {
+				enabled = true
+			}
+		}
+		if enabled {
+			details.Enabled = attrs
+		} else {
+			details.Disabled = attrs
+		}
+		fg.Status.FeatureGates = append(fg.Status.FeatureGates, details)
+	}
+	return fg
+}
+
+func TestSync(t *testing.T) {
+	mismatches := map[string]string{
+		// Only recent mismatches count, the counters are never reset while kubelets run.
+		"increase(cluster:volume_manager_selinux_volume_context_mismatch_errors_total[24h]) > 0 or increase(cluster:volume_manager_selinux_volume_context_mismatch_warnings_total[24h]) > 0": `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"volume_plugin":"kubernetes.io/csi/ebs.csi.aws.com"},"value":[1700000000,"3"]},
+			{"metric":{"volume_plugin":"kubernetes.io/nfs"},"value":[1700000000,"1"]}]}}`,
+		namespacesQuery: `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"pod1_namespace":"ns2","pod2_namespace":"ns1"},"value":[1700000000,"1"]},
+			{"metric":{"pod1_namespace":"ns1","pod2_namespace":"ns1"},"value":[1700000000,"2"]}]}}`,
+	}
+	mismatchMessage := "Found volume plugins with SELinux context mismatches: kubernetes.io/csi/ebs.csi.aws.com, kubernetes.io/nfs; " +
+		"namespaces with Pods that share volumes with different SELinux contexts: ns1, ns2"
+
+	tests := []struct {
+		name                      string
+		responses                 map[string]string
+		featureGate               *configv1.FeatureGate
+		unreachable               bool
+		expectedReadiness         opv1.ConditionStatus
+		expectedReadinessReason   string
+		expectedReadinessMessage  string
+		expectedUpgradeable       opv1.ConditionStatus
+		expectedUpgradeableReason string
+	}{
+		{
+			name: "no mismatches",
+			responses: map[string]string{
+				volumePluginsQuery: emptyResponse,
+				namespacesQuery:    emptyResponse,
+			},
+			featureGate:               getFeatureGate("4.17.0"),
+			expectedReadiness:         opv1.ConditionTrue,
+			expectedUpgradeable:       opv1.ConditionTrue,
+			expectedUpgradeableReason: "AsExpected",
+		},
+		{
+			name:                      "mismatches, feature gate does not change",
+			responses:                 mismatches,
+			featureGate:               getFeatureGate(),
+			expectedReadiness:         opv1.ConditionFalse,
+			expectedReadinessMessage:  mismatchMessage,
+			expectedUpgradeable:       opv1.ConditionTrue,
+			expectedUpgradeableReason: "AsExpected",
+		},
+		{
+			name:                      "mismatches, feature gate already enabled",
+			responses:                 mismatches,
+			featureGate:               getFeatureGate(currentVersion, "4.17.0"),
+			expectedReadiness:         opv1.ConditionFalse,
+			expectedReadinessMessage:  mismatchMessage,
+			expectedUpgradeable:       opv1.ConditionTrue,
+			expectedUpgradeableReason: "AsExpected",
+		},
+		{
+			name:                      "mismatches, feature gate is going to be enabled",
+			responses:                 mismatches,
+			featureGate:               getFeatureGate("4.17.0"),
+			expectedReadiness:         opv1.ConditionFalse,
+			expectedReadinessMessage:  mismatchMessage,
+			expectedUpgradeable:       opv1.ConditionFalse,
+			expectedUpgradeableReason: "SELinuxContextMismatch",
+		},
+		{
+			name:                      "failed query",
+			responses:                 map[string]string{},
+			featureGate:               getFeatureGate("4.17.0"),
+			expectedReadiness:         opv1.ConditionUnknown,
+			expectedReadinessReason:   "PrometheusQueryFailed",
+			expectedReadinessMessage:  fmt.Sprintf("Failed to query Prometheus: query %q failed (HTTP 400): bad_data: unexpected query", volumePluginsQuery),
+			expectedUpgradeable:       opv1.ConditionTrue,
+			expectedUpgradeableReason: "AsExpected",
+		},
+		{
+			name:                      "unreachable Prometheus",
+			responses:                 map[string]string{},
+			unreachable:               true,
+			featureGate:               getFeatureGate("4.17.0"),
+			expectedReadiness:         opv1.ConditionUnknown,
+			expectedReadinessReason:   "PrometheusUnreachable",
+			expectedUpgradeable:       opv1.ConditionTrue,
+			expectedUpgradeableReason: "AsExpected",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakePrometheus(t, test.responses)
+			defer server.Close()
+			expectedReadinessMessage := test.expectedReadinessMessage
+			if test.unreachable {
+				server.Close()
+				expectedReadinessMessage = fmt.Sprintf("Failed to query Prometheus: %s is unreachable: ", server.URL)
+			}
+
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+				ConfigObjects:   []runtime.Object{test.featureGate},
+			})
+			recorder := events.NewInMemoryRecorder("operator")
+			c := &Controller{
+				operatorClient:    clients.OperatorClient,
+				featureGateLister: clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+				currentVersion:    currentVersion,
+				prometheus:        &prometheusClient{url: server.URL, httpClient: server.Client()},
+				eventRecorder:     recorder,
+			}
+			clients.OperatorClient.Informer()
+			clients.ConfigInformers.Config().V1().FeatureGates().Informer()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			if err := c.sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, readinessConditionType)
+			if cnd == nil {
+				t.Fatalf("expected %s condition", readinessConditionType)
+			}
+			if cnd.Status != test.expectedReadiness {
+				t.Errorf("expected %s condition status %s, got %s", readinessConditionType, test.expectedReadiness, cnd.Status)
+			}
+			if test.expectedReadinessReason != "" && cnd.Reason != test.expectedReadinessReason {
+				t.Errorf("expected %s condition reason %s, got %s", readinessConditionType, test.expectedReadinessReason, cnd.Reason)
+			}
+			// The message of an unreachable Prometheus ends with a connection error.
+			if (test.unreachable && !strings.HasPrefix(cnd.Message, expectedReadinessMessage)) ||
+				(!test.unreachable && cnd.Message != expectedReadinessMessage) {
+				t.Errorf("expected %s condition message %q, got %q", readinessConditionType, expectedReadinessMessage, cnd.Message)
+			}
+
+			cnd = v1helpers.FindOperatorCondition(storage.Status.Conditions, upgradeableConditionType)
+			if cnd == nil {
+				t.Fatalf("expected %s condition", upgradeableConditionType)
+			}
+			if cnd.Status != test.expectedUpgradeable {
+				t.Errorf("expected %s condition status %s, got %s", upgradeableConditionType, test.expectedUpgradeable, cnd.Status)
+			}
+			if cnd.Reason != test.expectedUpgradeableReason {
+				t.Errorf("expected %s condition reason %s, got %s", upgradeableConditionType, test.expectedUpgradeableReason, cnd.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package selinuxreadiness
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// thanos-querier of the in-cluster monitoring stack.
+	defaultPrometheusURL = "https://thanos-querier.openshift-monitoring.svc:9091"
+	// Token and service CA bundle of the operator ServiceAccount.
+	tokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+	queryTimeout = 30 * time.Second
+)
+
+// sample is a single sample of an instant Prometheus query.
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+// queryResponse is the response of Prometheus /api/v1/query.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			// [ <unix time>, "<sample value>" ]
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// queryError is a failed Prometheus query with a reason for conditions.
+type queryError struct {
+	reason string
+	err    error
+}
+
+func (e *queryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *queryError) Unwrap() error {
+	return e.err
+}
+
+// prometheusClient runs instant queries against Prometheus HTTP API.
+type prometheusClient struct {
+	url        string
+	httpClient *http.Client
+	// tokenFile with a bearer token for Prometheus. The token is read on each query,
+	// because it is rotated by kubelet.
+	tokenFile string
+}
+
+// newPrometheusClient returns a client of the in-cluster thanos-querier,
+// authenticated as the operator ServiceAccount.
+func newPrometheusClient() (*prometheusClient, error) {
+	caData, err := os.ReadFile(serviceCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in %s", serviceCAFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &prometheusClient{
+		url:        defaultPrometheusURL,
+		httpClient: &http.Client{Transport: transport, Timeout: queryTimeout},
+		tokenFile:  tokenFile,
+	}, nil
+}
+
+// query runs an instant query and returns the resulting vector.
+func (p *prometheusClient) query(ctx context.Context, query string) ([]sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.tokenFile != "" {
+		token, err := os.ReadFile(p.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ServiceAccount token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		// Most probably blocked by a NetworkPolicy or the monitoring stack is down.
+		return nil, &queryError{reason: "PrometheusUnreachable", err: fmt.Errorf("%s is unreachable: %w", p.url, err)}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, &queryError{reason: "PrometheusAccessDenied", err: fmt.Errorf("query %q was denied (HTTP %d): %s", query, resp.StatusCode, string(body))}
+	}
+	var result queryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response of query %q (HTTP %d): %w", query, resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query %q failed (HTTP %d): %s: %s", query, resp.StatusCode, result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("query %q returned unexpected result type %q", query, result.Data.ResultType)
+	}
+
+	samples := make([]sample, 0, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		if len(r.Value) != 2 {
+			return nil, fmt.Errorf("query %q returned malformed sample %v", query, r.Value)
+		}
+		str, ok := r.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("query %q returned malformed sample %v", query, r.Value)
+		}
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query %q returned malformed sample %v: %w", query, r.Value, err)
+		}
+		samples = append(samples, sample{labels: r.Metric, value: value})
+	}
+	return samples, nil
+}
@@ -4,14 +4,7 @@ import (
 	"context"
 	"time"
 
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/component-base/metrics"
-	"k8s.io/component-base/metrics/legacyregistry"
-
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
-	"k8s.io/klog/v2"
-
-	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 )
 
 const (
@@ -19,49 +12,22 @@ const (
 )
 
 const (
-	operatorNamespace      = "openshift-cluster-storage-operator"
-	clusterOperatorName    = "storage"
-	defaultScAnnotationKey = "storageclass.kubernetes.io/is-default-class"
+	operatorNamespace   = "openshift-cluster-storage-operator"
+	clusterOperatorName = "storage"
 )
 
-func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext, guestKubeConfig *string) error {
+// RunOperator starts CSO. In dry-run mode, CSO sends all its changes to the
+// API server in dry-run and reports them in events and in a ConfigMap.
+func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext, guestKubeConfig *string, dryRun bool) error {
 	isHyperShift := false
 	if guestKubeConfig != nil && *guestKubeConfig != "" {
 		isHyperShift = true
 	}
 
-	starter := NewStandaloneStarter(controllerConfig)
+	starter := NewStandaloneStarter(controllerConfig, dryRun)
 
 	if isHyperShift {
-		starter = NewHyperShiftStarter(controllerConfig, *guestKubeConfig)
+		starter = NewHyperShiftStarter(controllerConfig, *guestKubeConfig, dryRun)
 	}
 	return starter.StartOperator(ctx)
 }
-
-func countStorageClasses(clients *csoclients.Clients) {
-	klog.Infof("Registering default StorageClass count metric for controller")
-	legacyregistry.RawMustRegister(metrics.NewGaugeFunc(
-		&metrics.GaugeOpts{
-			Name:           "default_storage_class_count",
-			Help:           "Number of default storage classes currently configured.",
-			StabilityLevel: metrics.ALPHA,
-		},
-		func() float64 {
-			scLister := clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister()
-			existingSCs, err := scLister.List(labels.Everything())
-			if err != nil {
-				klog.Fatalf("Failed to get existing storage classes: %s", err)
-			}
-			defaultSCCount := 0
-			var defaultSCNames []string
-			for _, sc := range existingSCs {
-				if sc.Annotations[defaultScAnnotationKey] == "true" {
-					defaultSCCount++
-					defaultSCNames = append(defaultSCNames, sc.Name)
-				}
-			}
-			klog.V(4).Infof("Current default StorageClass count: %v (%v)", defaultSCCount, defaultSCNames)
-			return float64(defaultSCCount)
-		},
-	))
-}
@@ -0,0 +1,289 @@
+package upgradereadiness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	conditionsPrefix = "UpgradeReadinessController"
+
+	// Max. number of PVs listed in the condition message.
+	maxReportedPVs = 10
+)
+
+// inTreePlugin is an in-tree volume plugin that is removed from Kubernetes.
+type inTreePlugin struct {
+	// Name of the in-tree volume plugin.
+	name string
+	// Name of the CSI driver that handles volumes of the plugin after CSI migration.
+	// Empty when the plugin was removed without CSI migration.
+	csiDriverName string
+	// Minor version of Kubernetes 1.x that removed the plugin.
+	removedIn uint
+	// isUsedBy returns true if the PV uses the in-tree plugin.
+	isUsedBy func(pv *v1.PersistentVolume) bool
+}
+
+// inTreePlugins lists in-tree volume plugins removed from Kubernetes. Plugins
+// that are deprecated, but still present in Kubernetes (e.g. portworx-volume)
+// are not listed, their PVs work after upgrade.
+var inTreePlugins = []inTreePlugin{
+	{
+		name:          "kubernetes.io/aws-ebs",
+		csiDriverName: "ebs.csi.aws.com",
+		removedIn:     27,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.AWSElasticBlockStore != nil },
+	},
+	{
+		name:          "kubernetes.io/azure-disk",
+		csiDriverName: "disk.csi.azure.com",
+		removedIn:     27,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.AzureDisk != nil },
+	},
+	{
+		name:          "kubernetes.io/azure-file",
+		csiDriverName: "file.csi.azure.com",
+		removedIn:     30,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.AzureFile != nil },
+	},
+	{
+		name:          "kubernetes.io/cinder",
+		csiDriverName: "cinder.csi.openstack.org",
+		removedIn:     26,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.Cinder != nil },
+	},
+	{
+		name:          "kubernetes.io/gce-pd",
+		csiDriverName: "pd.csi.storage.gke.io",
+		removedIn:     28,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.GCEPersistentDisk != nil },
+	},
+	{
+		name:          "kubernetes.io/vsphere-volume",
+		csiDriverName: "csi.vsphere.vmware.com",
+		removedIn:     30,
+		isUsedBy:      func(pv *v1.PersistentVolume) bool { return pv.Spec.VsphereVolume != nil },
+	},
+	{
+		name:      "kubernetes.io/scaleio",
+		removedIn: 22,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.ScaleIO != nil },
+	},
+	{
+		name:      "kubernetes.io/flocker",
+		removedIn: 25,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.Flocker != nil },
+	},
+	{
+		name:      "kubernetes.io/quobyte",
+		removedIn: 25,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.Quobyte != nil },
+	},
+	{
+		name:      "kubernetes.io/storageos",
+		removedIn: 25,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.StorageOS != nil },
+	},
+	{
+		name:      "kubernetes.io/glusterfs",
+		removedIn: 26,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.Glusterfs != nil },
+	},
+	{
+		name:      "kubernetes.io/cephfs",
+		removedIn: 31,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.CephFS != nil },
+	},
+	{
+		name:      "kubernetes.io/rbd",
+		removedIn: 31,
+		isUsedBy:  func(pv *v1.PersistentVolume) bool { return pv.Spec.RBD != nil },
+	},
+}
+
+// This Controller scans bound PersistentVolumes for volume sources of in-tree
+// volume plugins removed from Kubernetes in the next minor version after the
+// version of the API server. Such PVs keep working only when
+// the CSI driver of the plugin is installed and CSI migration of the plugin
+// is enabled on all nodes. Plugins removed without CSI migration always block
+// the upgrade to the version that removes them.
+// It produces following Conditions:
+// UpgradeReadinessControllerUpgradeable - false when some PVs would stop working
+// after upgrade.
+type Controller struct {
+	operatorClient  v1helpers.OperatorClient
+	pvLister        corelisters.PersistentVolumeLister
+	csiDriverLister storagelisters.CSIDriverLister
+	csiNodeLister   storagelisters.CSINodeLister
+	versionGetter   discovery.ServerVersionInterface
+	eventRecorder   events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:  clients.OperatorClient,
+		pvLister:        clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Lister(),
+		csiDriverLister: clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Lister(),
+		csiNodeLister:   clients.KubeInformers.InformersFor("").Storage().V1().CSINodes().Lister(),
+		versionGetter:   clients.KubeClient.Discovery(),
+		eventRecorder:   eventRecorder.WithComponentSuffix(conditionsPrefix),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(conditionsPrefix, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().CSINodes().Informer(),
+	).ResyncEvery(resyncInterval).ToController(conditionsPrefix, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("UpgradeReadinessController sync started")
+	defer klog.V(4).Infof("UpgradeReadinessController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	// Upgradeable=False blocks only the next minor upgrade.
+	targetMinor, err := c.nextMinorVersion()
+	if err != nil {
+		return err
+	}
+
+	pvs, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	sort.Slice(pvs, func(i, j int) bool {
+		return pvs[i].Name < pvs[j].Name
+	})
+
+	// Problems of in-tree plugins, evaluated only for plugins that are actually used.
+	problems := map[string]string{}
+	var blockingPVs []string
+	for _, pv := range pvs {
+		if pv.Status.Phase != v1.VolumeBound {
+			continue
+		}
+		for _, plugin := range inTreePlugins {
+			// PVs of plugins removed in older versions are already broken, the upgrade does not change them.
+			if plugin.removedIn != targetMinor || !plugin.isUsedBy(pv) {
+				continue
+			}
+			problem, found := problems[plugin.name]
+			if !found {
+				problem, err = c.checkPlugin(plugin)
+				if err != nil {
+					return err
+				}
+				problems[plugin.name] = problem
+			}
+			if problem != "" {
+				blockingPVs = append(blockingPVs, fmt.Sprintf("%s (%s)", pv.Name, plugin.name))
+			}
+		}
+	}
+
+	upgradeableCnd := operatorapi.OperatorCondition{
+		Type:   conditionsPrefix + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if len(blockingPVs) > 0 {
+		upgradeableCnd.Status = operatorapi.ConditionFalse
+		upgradeableCnd.Reason = "InTreeVolumesNotMigrated"
+		upgradeableCnd.Message = upgradeableMessage(blockingPVs, problems, targetMinor)
+	}
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(upgradeableCnd))
+	return err
+}
+
+// nextMinorVersion returns minor version of Kubernetes 1.x after the version
+// of the API server.
+func (c *Controller) nextMinorVersion() (uint, error) {
+	info, err := c.versionGetter.ServerVersion()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get Kubernetes version: %w", err)
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse Kubernetes version %q: %w", info.GitVersion, err)
+	}
+	return v.Minor() + 1, nil
+}
+
+// checkPlugin returns an empty string when volumes of the in-tree plugin will
+// work after upgrade, or a reason why they will not.
+func (c *Controller) checkPlugin(plugin inTreePlugin) (string, error) {
+	if plugin.csiDriverName == "" {
+		return fmt.Sprintf("in-tree volume plugin %s is removed without CSI migration", plugin.name), nil
+	}
+
+	_, err := c.csiDriverLister.Get(plugin.csiDriverName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("CSI driver %s is not installed", plugin.csiDriverName), nil
+		}
+		return "", err
+	}
+
+	csiNodes, err := c.csiNodeLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	var notMigratedNodes []string
+	for _, csiNode := range csiNodes {
+		migrated := sets.New[string](strings.Split(csiNode.Annotations[v1.MigratedPluginsAnnotationKey], ",")...)
+		if !migrated.Has(plugin.name) {
+			notMigratedNodes = append(notMigratedNodes, csiNode.Name)
+		}
+	}
+	if len(notMigratedNodes) > 0 {
+		sort.Strings(notMigratedNodes)
+		return fmt.Sprintf("CSI migration of %s is not enabled on nodes %s", plugin.name, strings.Join(notMigratedNodes, ", ")), nil
+	}
+	return "", nil
+}
+
+// upgradeableMessage lists the PVs that block upgrade and the reasons why their
+// in-tree plugins will not work.
+func upgradeableMessage(blockingPVs []string, problems map[string]string, targetMinor uint) string {
+	pvs := strings.Join(blockingPVs, ", ")
+	if len(blockingPVs) > maxReportedPVs {
+		pvs = fmt.Sprintf("%s and %d more", strings.Join(blockingPVs[:maxReportedPVs], ", "), len(blockingPVs)-maxReportedPVs)
+	}
+	var reasons []string
+	for _, plugin := range sets.List(sets.KeySet(problems)) {
+		if problems[plugin] != "" {
+			reasons = append(reasons, problems[plugin])
+		}
+	}
+	return fmt.Sprintf("PersistentVolumes %s use in-tree volume plugins that will not work after upgrade to Kubernetes 1.%d: %s", pvs, targetMinor, strings.Join(reasons, "; "))
+}
@@ -0,0 +1,196 @@
+package upgradereadiness
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+)
+
+var upgradeableConditionType = conditionsPrefix + opv1.OperatorStatusTypeUpgradeable
+
+func getPV(name string, phase v1.PersistentVolumePhase, source v1.PersistentVolumeSource) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1.PersistentVolumeSpec{PersistentVolumeSource: source},
+		Status:     v1.PersistentVolumeStatus{Phase: phase},
+	}
+}
+
+func ebsPV(name string) *v1.PersistentVolume {
+	return getPV(name, v1.VolumeBound, v1.PersistentVolumeSource{
+		AWSElasticBlockStore: &v1.AWSElasticBlockStoreVolumeSource{VolumeID: name},
+	})
+}
+
+func getCSIDriver(name string) *storagev1.CSIDriver {
+	return &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func getCSINode(name, migratedPlugins string) *storagev1.CSINode {
+	return &storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{v1.MigratedPluginsAnnotationKey: migratedPlugins},
+		},
+	}
+}
+
+func TestSync(t *testing.T) {
+	var manyPVs []runtime.Object
+	for i := 0; i < 12; i++ {
+		manyPVs = append(manyPVs, ebsPV(fmt.Sprintf("pv%02d", i)))
+	}
+
+	tests := []struct {
+		name            string
+		kubeVersion     string
+		objects         []runtime.Object
+		expectedStatus  opv1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name: "no in-tree PVs",
+			objects: []runtime.Object{
+				getPV("csi", v1.VolumeBound, v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com"}}),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name:        "migrated in-tree PVs",
+			kubeVersion: "v1.26.5",
+			objects: []runtime.Object{
+				ebsPV("pv1"),
+				getCSIDriver("ebs.csi.aws.com"),
+				getCSINode("node1", "kubernetes.io/aws-ebs,kubernetes.io/gce-pd"),
+				getCSINode("node2", "kubernetes.io/aws-ebs"),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name: "unbound in-tree PVs are ignored",
+			objects: []runtime.Object{
+				getPV("released", v1.VolumeReleased, v1.PersistentVolumeSource{Glusterfs: &v1.GlusterfsPersistentVolumeSource{}}),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name:        "missing CSI driver",
+			kubeVersion: "v1.26.5",
+			objects: []runtime.Object{
+				ebsPV("pv1"),
+				getCSINode("node1", "kubernetes.io/aws-ebs"),
+			},
+			expectedStatus:  opv1.ConditionFalse,
+			expectedMessage: "PersistentVolumes pv1 (kubernetes.io/aws-ebs) use in-tree volume plugins that will not work after upgrade to Kubernetes 1.27: CSI driver ebs.csi.aws.com is not installed",
+		},
+		{
+			name:        "migration not enabled and removed plugins",
+			kubeVersion: "v1.25.16",
+			objects: []runtime.Object{
+				getPV("pv1", v1.VolumeBound, v1.PersistentVolumeSource{Cinder: &v1.CinderPersistentVolumeSource{VolumeID: "pv1"}}),
+				getPV("pv2", v1.VolumeBound, v1.PersistentVolumeSource{Glusterfs: &v1.GlusterfsPersistentVolumeSource{}}),
+				getCSIDriver("cinder.csi.openstack.org"),
+				getCSINode("node1", "kubernetes.io/cinder"),
+				getCSINode("node2", ""),
+			},
+			expectedStatus: opv1.ConditionFalse,
+			expectedMessage: "PersistentVolumes pv1 (kubernetes.io/cinder), pv2 (kubernetes.io/glusterfs) use in-tree volume plugins that will not work after upgrade to Kubernetes 1.26: " +
+				"CSI migration of kubernetes.io/cinder is not enabled on nodes node2; in-tree volume plugin kubernetes.io/glusterfs is removed without CSI migration",
+		},
+		{
+			name: "plugins removed before the next version",
+			objects: []runtime.Object{
+				ebsPV("pv1"),
+				getPV("pv2", v1.VolumeBound, v1.PersistentVolumeSource{Glusterfs: &v1.GlusterfsPersistentVolumeSource{}}),
+				getPV("pv3", v1.VolumeBound, v1.PersistentVolumeSource{ScaleIO: &v1.ScaleIOPersistentVolumeSource{}}),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name:        "plugin removed after the next version",
+			kubeVersion: "v1.29.6",
+			objects: []runtime.Object{
+				getPV("pv1", v1.VolumeBound, v1.PersistentVolumeSource{CephFS: &v1.CephFSPersistentVolumeSource{}}),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name:        "plugin removed in the next version",
+			kubeVersion: "v1.30.4+6ba2c37",
+			objects: []runtime.Object{
+				getPV("pv1", v1.VolumeBound, v1.PersistentVolumeSource{CephFS: &v1.CephFSPersistentVolumeSource{}}),
+			},
+			expectedStatus:  opv1.ConditionFalse,
+			expectedMessage: "PersistentVolumes pv1 (kubernetes.io/cephfs) use in-tree volume plugins that will not work after upgrade to Kubernetes 1.31: in-tree volume plugin kubernetes.io/cephfs is removed without CSI migration",
+		},
+		{
+			name: "deprecated plugin that is not removed",
+			objects: []runtime.Object{
+				getPV("pv1", v1.VolumeBound, v1.PersistentVolumeSource{PortworxVolume: &v1.PortworxVolumeSource{}}),
+			},
+			expectedStatus: opv1.ConditionTrue,
+		},
+		{
+			name:           "too many PVs",
+			kubeVersion:    "v1.26.5",
+			objects:        manyPVs,
+			expectedStatus: opv1.ConditionFalse,
+			expectedMessage: "PersistentVolumes pv00 (kubernetes.io/aws-ebs), pv01 (kubernetes.io/aws-ebs), pv02 (kubernetes.io/aws-ebs), pv03 (kubernetes.io/aws-ebs), " +
+				"pv04 (kubernetes.io/aws-ebs), pv05 (kubernetes.io/aws-ebs), pv06 (kubernetes.io/aws-ebs), pv07 (kubernetes.io/aws-ebs), " +
+				"pv08 (kubernetes.io/aws-ebs), pv09 (kubernetes.io/aws-ebs) and 2 more use in-tree volume plugins that will not work after upgrade to Kubernetes 1.27: " +
+				"CSI driver ebs.csi.aws.com is not installed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{
+				CoreObjects:     test.objects,
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			})
+			kubeVersion := test.kubeVersion
+			if kubeVersion == "" {
+				kubeVersion = "v1.30.4"
+			}
+			clients.KubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: kubeVersion}
+			recorder := events.NewInMemoryRecorder("operator")
+			ctrl := NewController(clients, 0, recorder)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+
+			if err := ctrl.Sync(ctx, factory.NewSyncContext("test", recorder)); err != nil {
+				t.Fatalf("sync() returned unexpected error: %v", err)
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, upgradeableConditionType)
+			if cnd == nil {
+				t.Fatalf("expected %s condition", upgradeableConditionType)
+			}
+			if cnd.Status != test.expectedStatus {
+				t.Errorf("expected %s condition status %s, got %s", upgradeableConditionType, test.expectedStatus, cnd.Status)
+			}
+			if cnd.Message != test.expectedMessage {
+				t.Errorf("expected %s condition message %q, got %q", upgradeableConditionType, test.expectedMessage, cnd.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/assetvalidation"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Placeholder of the HyperShift control plane namespace in assets.
+const controlPlaneNamespacePlaceholder = "${CONTROLPLANE_NAMESPACE}"
+
+var (
+	clusterCSIDriverKind    = schema.GroupVersionKind{Group: "operator.openshift.io", Version: "v1", Kind: "ClusterCSIDriver"}
+	deploymentKind          = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	serviceMonitorKind      = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+	prometheusRuleKind      = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+	volumeSnapshotClassKind = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotClass"}
+	networkPolicyKind       = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}
+)
+
+// RunVerifyAssets validates the embedded assets and all their references in
+// CSO code. Found problems are written to out.
+func RunVerifyAssets(out io.Writer) error {
+	errs := assetvalidation.Validate(assets.FS(), assetReferences())
+	for _, err := range errs {
+		fmt.Fprintln(out, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("found %d problem(s) in assets", len(errs))
+	}
+	fmt.Fprintln(out, "All assets are valid")
+	return nil
+}
+
+// assetReferences returns all assets referenced by CSO code.
+func assetReferences() []assetvalidation.Reference {
+	var refs []assetvalidation.Reference
+	add := func(owner, asset string, kind schema.GroupVersionKind, namespace string) {
+		if asset != "" {
+			refs = append(refs, assetvalidation.Reference{Owner: owner, Asset: asset, Kind: kind, Namespace: namespace})
+		}
+	}
+
+	for _, hypershift := range []bool{false, true} {
+		for _, cfg := range offlineDriverConfigs(hypershift, &configv1.Infrastructure{}) {
+			owner := cfg.ConditionPrefix
+			deploymentNamespace := csoclients.CSIOperatorNamespace
+			if hypershift {
+				owner += " (HyperShift)"
+				deploymentNamespace = controlPlaneNamespacePlaceholder
+			}
+			for _, asset := range cfg.StaticAssets {
+				add(owner, asset, schema.GroupVersionKind{}, "")
+			}
+			for _, asset := range cfg.MgmtStaticAssets {
+				add(owner, asset, schema.GroupVersionKind{}, controlPlaneNamespacePlaceholder)
+			}
+			add(owner, cfg.CRAsset, clusterCSIDriverKind, "")
+			add(owner, cfg.DeploymentAsset, deploymentKind, deploymentNamespace)
+			add(owner, cfg.ServiceMonitorAsset, serviceMonitorKind, csoclients.CSIOperatorNamespace)
+			add(owner, cfg.MgmtServiceMonitorAsset, serviceMonitorKind, controlPlaneNamespacePlaceholder)
+			add(owner, cfg.VolumeSnapshotClassAsset, volumeSnapshotClassKind, "")
+		}
+	}
+
+	ssr := &StandaloneStarter{}
+	for _, cfg := range ssr.populateProblemDetectorConfigs() {
+		owner := cfg.ConditionPrefix
+		for _, asset := range append(append([]string{}, cfg.StaticAssets...), cfg.SharedStaticAssets...) {
+			add(owner, asset, schema.GroupVersionKind{}, "")
+		}
+		add(owner, cfg.DeploymentAsset, deploymentKind, csoclients.OperatorNamespace)
+		add(owner, cfg.ServiceMonitorAsset, serviceMonitorKind, csoclients.OperatorNamespace)
+		add(owner, cfg.PrometheusRule.File, prometheusRuleKind, csoclients.OperatorNamespace)
+	}
+
+	for _, asset := range networkPolicyAssets {
+		add("NetworkPolicies", asset, networkPolicyKind, "")
+	}
+	add("StorageRules", prometheusrules.StorageRulesAsset().File, prometheusRuleKind, csoclients.OperatorNamespace)
+	return refs
+}
@@ -0,0 +1,16 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/assetvalidation"
+)
+
+// TestAssets catches typos in asset names and stale generated assets before
+// they panic at runtime.
+func TestAssets(t *testing.T) {
+	for _, err := range assetvalidation.Validate(assets.FS(), assetReferences()) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,221 @@
+package volumeattachment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/operatormetrics"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	conditionsPrefix = "VolumeAttachmentController"
+	// Not ending with Available / Degraded / Progressing / Upgradeable, a stuck
+	// VolumeAttachment is reported by an alert and it should not affect the ClusterOperator.
+	stuckConditionType = conditionsPrefix + "Stuck"
+
+	// VolumeAttachments that did not attach / detach in this time are stuck.
+	stuckTimeout = 10 * time.Minute
+	// Max. number of VolumeAttachments listed in the condition message.
+	maxReportedAttachments = 10
+
+	reasonAttaching   = "Attaching"
+	reasonDetaching   = "Detaching"
+	reasonAttachError = "AttachError"
+	reasonDetachError = "DetachError"
+)
+
+var (
+	stuckVolumeAttachments = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "cso",
+			Name:           "volume_attachments_stuck",
+			Help:           "Number of VolumeAttachments of CSI drivers installed by the cluster-storage-operator that are stuck attaching or detaching.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "node", "reason"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(stuckVolumeAttachments)
+}
+
+type stuckAttachment struct {
+	name   string
+	driver string
+	node   string
+	reason string
+	since  time.Time
+}
+
+// This Controller finds VolumeAttachments of CSI drivers started by CSO that
+// are stuck: they did not attach or detach in stuckTimeout or the CSI driver
+// reported an error. Stuck VolumeAttachments are reported as
+// cso_volume_attachments_stuck metric, which feeds VolumeAttachmentStuck alert,
+// and as events when they get stuck.
+// It produces following Conditions:
+// VolumeAttachmentControllerStuck - some VolumeAttachments are stuck.
+type Controller struct {
+	operatorClient   v1helpers.OperatorClient
+	vaLister         storagelisters.VolumeAttachmentLister
+	driversGetter    csidriveroperator.RunningDriversGetter
+	eventRecorder    events.Recorder
+	now              func() time.Time
+	reportedStuckVAs sets.Set[string]
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	driversGetter csidriveroperator.RunningDriversGetter,
+	resyncInterval time.Duration,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:   clients.OperatorClient,
+		vaLister:         clients.KubeInformers.InformersFor("").Storage().V1().VolumeAttachments().Lister(),
+		driversGetter:    driversGetter,
+		eventRecorder:    eventRecorder.WithComponentSuffix(conditionsPrefix),
+		now:              time.Now,
+		reportedStuckVAs: sets.New[string](),
+	}
+	return factory.New().WithSync(operatormetrics.InstrumentSync(conditionsPrefix, c.sync)).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().VolumeAttachments().Informer(),
+	).ResyncEvery(resyncInterval).ToController(conditionsPrefix, c.eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("VolumeAttachmentController sync started")
+	defer klog.V(4).Infof("VolumeAttachmentController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	drivers, synced := c.driversGetter.RunningDrivers()
+	if !synced {
+		klog.V(4).Infof("Waiting for CSI driver operators to be evaluated")
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), 5*time.Second)
+		return nil
+	}
+	managedDrivers := sets.New[string]()
+	for _, cfg := range drivers {
+		managedDrivers.Insert(cfg.CSIDriverName)
+	}
+
+	vas, err := c.vaLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var stuck []stuckAttachment
+	for _, va := range vas {
+		if !managedDrivers.Has(va.Spec.Attacher) {
+			continue
+		}
+		if s := c.isStuck(va); s != nil {
+			stuck = append(stuck, *s)
+		}
+	}
+	sort.Slice(stuck, func(i, j int) bool {
+		return stuck[i].name < stuck[j].name
+	})
+
+	c.reportEvents(stuck)
+
+	stuckVolumeAttachments.Reset()
+	for _, s := range stuck {
+		stuckVolumeAttachments.WithLabelValues(s.driver, s.node, s.reason).Inc()
+	}
+
+	stuckCnd := operatorapi.OperatorCondition{
+		Type:   stuckConditionType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if len(stuck) > 0 {
+		stuckCnd.Status = operatorapi.ConditionTrue
+		stuckCnd.Reason = "VolumeAttachmentsStuck"
+		stuckCnd.Message = c.stuckMessage(stuck)
+	}
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(stuckCnd))
+	return err
+}
+
+// isStuck returns non-nil stuckAttachment if the VolumeAttachment is stuck.
+func (c *Controller) isStuck(va *storagev1.VolumeAttachment) *stuckAttachment {
+	s := &stuckAttachment{
+		name:   va.Name,
+		driver: va.Spec.Attacher,
+		node:   va.Spec.NodeName,
+	}
+	switch {
+	case va.Status.DetachError != nil:
+		s.reason = reasonDetachError
+		s.since = va.Status.DetachError.Time.Time
+	case va.Status.AttachError != nil && va.DeletionTimestamp == nil:
+		s.reason = reasonAttachError
+		s.since = va.Status.AttachError.Time.Time
+	case va.DeletionTimestamp != nil:
+		s.reason = reasonDetaching
+		s.since = va.DeletionTimestamp.Time
+		if c.now().Sub(s.since) < stuckTimeout {
+			return nil
+		}
+	case !va.Status.Attached:
+		s.reason = reasonAttaching
+		s.since = va.CreationTimestamp.Time
+		if c.now().Sub(s.since) < stuckTimeout {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return s
+}
+
+// reportEvents emits an event for each VolumeAttachment that got stuck since the last sync.
+func (c *Controller) reportEvents(stuck []stuckAttachment) {
+	current := sets.New[string]()
+	for _, s := range stuck {
+		key := s.name + "/" + s.reason
+		current.Insert(key)
+		if c.reportedStuckVAs.Has(key) {
+			continue
+		}
+		c.eventRecorder.Warningf("VolumeAttachmentStuck", "VolumeAttachment %s of CSI driver %s on node %s is stuck: %s", s.name, s.driver, s.node, s.reason)
+	}
+	c.reportedStuckVAs = current
+}
+
+// stuckMessage lists the stuck VolumeAttachments. It does not contain any durations,
+// so the condition is not updated on each sync.
+func (c *Controller) stuckMessage(stuck []stuckAttachment) string {
+	var msgs []string
+	for i, s := range stuck {
+		if i == maxReportedAttachments {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(stuck)-maxReportedAttachments))
+			break
+		}
+		msgs = append(msgs, fmt.Sprintf("VolumeAttachment %s of CSI driver %s on node %s: %s since %s", s.name, s.driver, s.node, s.reason, s.since.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,179 @@
+package volumeattachment
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	opv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeDriversGetter struct {
+	drivers []csioperatorclient.CSIOperatorConfig
+}
+
+func (f *fakeDriversGetter) RunningDrivers() ([]csioperatorclient.CSIOperatorConfig, bool) {
+	return f.drivers, true
+}
+
+type vaModifier func(va *storagev1.VolumeAttachment)
+
+func getVolumeAttachment(name, driver string, age time.Duration, modifiers ...vaModifier) *storagev1.VolumeAttachment {
+	va := &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: driver,
+			NodeName: "node1",
+		},
+	}
+	for _, modifier := range modifiers {
+		modifier(va)
+	}
+	return va
+}
+
+func attached(va *storagev1.VolumeAttachment) {
+	va.Status.Attached = true
+}
+
+func deletedAgo(age time.Duration) vaModifier {
+	return func(va *storagev1.VolumeAttachment) {
+		ts := metav1.NewTime(now.Add(-age))
+		va.DeletionTimestamp = &ts
+		va.Finalizers = []string{"external-attacher/ebs-csi-aws-com"}
+	}
+}
+
+func withDetachError(va *storagev1.VolumeAttachment) {
+	va.Status.DetachError = &storagev1.VolumeError{Time: metav1.NewTime(now.Add(-time.Minute)), Message: "mock error"}
+}
+
+func withAttachError(va *storagev1.VolumeAttachment) {
+	va.Status.AttachError = &storagev1.VolumeError{Time: metav1.NewTime(now.Add(-time.Minute)), Message: "mock error"}
+}
+
+func TestSync(t *testing.T) {
+	aws := csioperatorclient.CSIOperatorConfig{CSIDriverName: csioperatorclient.AWSEBSCSIDriverName}
+
+	tests := []struct {
+		name               string
+		vas                []*storagev1.VolumeAttachment
+		expectedStuck      opv1.ConditionStatus
+		expectedMessage    string
+		expectedEvents     int
+		expectedMetricText string
+	}{
+		{
+			name: "no stuck VolumeAttachments",
+			vas: []*storagev1.VolumeAttachment{
+				getVolumeAttachment("attached", aws.CSIDriverName, time.Hour, attached),
+				getVolumeAttachment("attaching", aws.CSIDriverName, time.Minute),
+				getVolumeAttachment("detaching", aws.CSIDriverName, time.Hour, attached, deletedAgo(time.Minute)),
+				getVolumeAttachment("other-driver", "other.csi.example.com", time.Hour, withAttachError),
+			},
+			expectedStuck: opv1.ConditionFalse,
+		},
+		{
+			name: "stuck VolumeAttachments",
+			vas: []*storagev1.VolumeAttachment{
+				getVolumeAttachment("va1", aws.CSIDriverName, time.Hour),
+				getVolumeAttachment("va2", aws.CSIDriverName, time.Hour, attached, deletedAgo(time.Hour)),
+				getVolumeAttachment("va3", aws.CSIDriverName, time.Minute, withAttachError),
+				getVolumeAttachment("va4", aws.CSIDriverName, time.Hour, attached, deletedAgo(time.Minute), withDetachError),
+				getVolumeAttachment("va5", aws.CSIDriverName, time.Hour),
+			},
+			expectedStuck: opv1.ConditionTrue,
+			expectedMessage: "VolumeAttachment va1 of CSI driver ebs.csi.aws.com on node node1: Attaching since 2024-01-01T11:00:00Z; " +
+				"VolumeAttachment va2 of CSI driver ebs.csi.aws.com on node node1: Detaching since 2024-01-01T11:00:00Z; " +
+				"VolumeAttachment va3 of CSI driver ebs.csi.aws.com on node node1: AttachError since 2024-01-01T11:59:00Z; " +
+				"VolumeAttachment va4 of CSI driver ebs.csi.aws.com on node node1: DetachError since 2024-01-01T11:59:00Z; " +
+				"VolumeAttachment va5 of CSI driver ebs.csi.aws.com on node node1: Attaching since 2024-01-01T11:00:00Z",
+			expectedEvents: 5,
+			expectedMetricText: `
+				# HELP cso_volume_attachments_stuck [ALPHA] Number of VolumeAttachments of CSI drivers installed by the cluster-storage-operator that are stuck attaching or detaching.
+				# TYPE cso_volume_attachments_stuck gauge
+				cso_volume_attachments_stuck{driver="ebs.csi.aws.com",node="node1",reason="AttachError"} 1
+				cso_volume_attachments_stuck{driver="ebs.csi.aws.com",node="node1",reason="Attaching"} 2
+				cso_volume_attachments_stuck{driver="ebs.csi.aws.com",node="node1",reason="DetachError"} 1
+				cso_volume_attachments_stuck{driver="ebs.csi.aws.com",node="node1",reason="Detaching"} 1
+			`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{
+				OperatorObjects: []runtime.Object{csoclients.GetCR()},
+			}
+			for _, va := range test.vas {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, va)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			recorder := events.NewInMemoryRecorder("operator")
+			c := &Controller{
+				operatorClient:   clients.OperatorClient,
+				vaLister:         clients.KubeInformers.InformersFor("").Storage().V1().VolumeAttachments().Lister(),
+				driversGetter:    &fakeDriversGetter{drivers: []csioperatorclient.CSIOperatorConfig{aws}},
+				eventRecorder:    recorder,
+				now:              func() time.Time { return now },
+				reportedStuckVAs: sets.New[string](),
+			}
+			clients.OperatorClient.Informer()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			csoclients.StartInformers(clients, ctx.Done())
+			csoclients.WaitForSync(clients, ctx.Done())
+			stuckVolumeAttachments.Reset()
+
+			syncCtx := factory.NewSyncContext("test", recorder)
+			// The second sync must not emit the events again.
+			for i := 0; i < 2; i++ {
+				if err := c.sync(ctx, syncCtx); err != nil {
+					t.Fatalf("sync() returned unexpected error: %v", err)
+				}
+			}
+
+			if len(recorder.Events()) != test.expectedEvents {
+				t.Errorf("expected %d events, got %d: %+v", test.expectedEvents, len(recorder.Events()), recorder.Events())
+			}
+
+			if test.expectedMetricText != "" {
+				if err := testutil.CollectAndCompare(stuckVolumeAttachments, strings.NewReader(test.expectedMetricText), "cso_volume_attachments_stuck"); err != nil {
+					t.Error(err)
+				}
+			}
+
+			storage, err := clients.OperatorClientSet.OperatorV1().Storages().Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get Storage: %v", err)
+			}
+			cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, stuckConditionType)
+			if cnd == nil {
+				t.Fatalf("expected %s condition", stuckConditionType)
+			}
+			if cnd.Status != test.expectedStuck {
+				t.Errorf("expected %s condition status %s, got %s", stuckConditionType, test.expectedStuck, cnd.Status)
+			}
+			if cnd.Message != test.expectedMessage {
+				t.Errorf("expected %s condition message %q, got %q", stuckConditionType, test.expectedMessage, cnd.Message)
+			}
+		})
+	}
+}
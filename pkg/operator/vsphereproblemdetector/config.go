@@ -4,14 +4,30 @@ import (
 	"fmt"
 
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
 	"gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/api/errors"
 	listerv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 )
 
+// DetectorConfig is user configuration of vsphere-problem-detector.
+// Example:
+//
+//	version: v1
+//	alerts:
+//	  VSphereOpenshiftNodeHealthFail:
+//	    for: 30m
+//	    severity: info
+//
+// A config without version is the legacy config, which has only alertsDisabled.
 type DetectorConfig struct {
-	AlertsDisabled bool `yaml:"alertsDisabled,omitempty"`
+	Version        string `yaml:"version,omitempty"`
+	AlertsDisabled bool   `yaml:"alertsDisabled,omitempty"`
+	// Alerts are overrides of the detector alerts, keyed by alert name,
+	// optionally followed by "/<severity>".
+	Alerts map[string]prometheusrules.AlertOverride `yaml:"alerts,omitempty"`
 }
 
 var (
@@ -24,6 +40,8 @@ var (
 const (
 	detectorConfigMapName = "vsphere-problem-detector"
 	configKey             = "config.yaml"
+
+	configVersionV1 = "v1"
 )
 
 func ParseConfigMap(lister listerv1.ConfigMapLister) (*DetectorConfig, error) {
@@ -47,6 +65,41 @@ func ParseConfigMap(lister listerv1.ConfigMapLister) (*DetectorConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid format of ConfigMap %s: %s", detectorConfigMapName, err)
 	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ConfigMap %s: %s", detectorConfigMapName, err)
+	}
 	klog.V(4).Infof("Parsed ConfigMap %s: %+v", detectorConfigMapName, config)
 	return &config, nil
 }
+
+// Validate checks values of the config. Alert names are checked when the
+// overrides are applied to the PrometheusRule.
+func (c *DetectorConfig) Validate() error {
+	switch c.Version {
+	case "":
+		if len(c.Alerts) > 0 {
+			return fmt.Errorf("alerts require version %s", configVersionV1)
+		}
+		return nil
+	case configVersionV1:
+	default:
+		return fmt.Errorf("unsupported version %q", c.Version)
+	}
+
+	return c.alertOverrides().Validate()
+}
+
+func (c *DetectorConfig) alertOverrides() *prometheusrules.Overrides {
+	return &prometheusrules.Overrides{Alerts: c.Alerts}
+}
+
+func parseAlertsConfig(lister listerv1.ConfigMapLister) (*problemdetector.AlertsConfig, error) {
+	cfg, err := ParseConfigMap(lister)
+	if err != nil {
+		return nil, err
+	}
+	return &problemdetector.AlertsConfig{
+		Disabled:  cfg.AlertsDisabled,
+		Overrides: cfg.alertOverrides(),
+	}, nil
+}
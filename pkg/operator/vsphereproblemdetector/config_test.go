@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
@@ -67,6 +68,40 @@ func TestParseConfigMap(t *testing.T) {
 			expectedConfig: &DetectorConfig{AlertsDisabled: false},
 			expectError:    false,
 		},
+		{
+			name: "v1 config",
+			configMap: getCM(map[string]string{configKey: `version: v1
+alerts:
+  VSphereOpenshiftNodeHealthFail:
+    for: 30m
+    severity: info
+`}),
+			expectedConfig: &DetectorConfig{
+				Version: configVersionV1,
+				Alerts: map[string]prometheusrules.AlertOverride{
+					"VSphereOpenshiftNodeHealthFail": {For: "30m", Severity: "info"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:           "alerts without version",
+			configMap:      getCM(map[string]string{configKey: "alerts:\n  VSphereOpenshiftNodeHealthFail:\n    for: 30m\n"}),
+			expectedConfig: nil,
+			expectError:    true,
+		},
+		{
+			name:           "unsupported version",
+			configMap:      getCM(map[string]string{configKey: "version: v2"}),
+			expectedConfig: nil,
+			expectError:    true,
+		},
+		{
+			name:           "invalid alert severity",
+			configMap:      getCM(map[string]string{configKey: "version: v1\nalerts:\n  VSphereOpenshiftNodeHealthFail:\n    severity: fatal\n"}),
+			expectedConfig: nil,
+			expectError:    true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,131 @@
+package vsphereproblemdetector
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/prometheusrules"
+	"github.com/openshift/library-go/pkg/operator/deploymentcontroller"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcehash"
+	appsv1 "k8s.io/api/apps/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+)
+
+const (
+	infraConfigName                     = "cluster"
+	vSphereProblemDetectorOperatorImage = "VSPHERE_PROBLEM_DETECTOR_OPERATOR_IMAGE"
+	cloudCredSecretName                 = "vsphere-cloud-credentials"
+	metricsCertSecretName               = "vsphere-problem-detector-serving-cert"
+	cloudConfigNamespace                = "openshift-config"
+	// Check results of the detector. NetworkPolicies in assets/networkpolicies
+	// must allow CSO to reach it, see TestAssets in pkg/operator/networkpolicy.
+	metricsURL = "https://vsphere-problem-detector-metrics.openshift-cluster-storage-operator.svc:8444/metrics"
+)
+
+// checkRemediations are hints how to fix failing detector checks.
+var checkRemediations = map[string]string{
+	"CheckAccountPermissions":        "grant the vCenter account in secret kube-system/vsphere-creds the permissions required by OpenShift",
+	"CheckTaskPermissions":           "grant the vCenter account permissions to read vCenter tasks",
+	"CheckFolderPermissions":         "grant the vCenter account permissions on the VM folder from the cloud config",
+	"CheckDefaultDatastore":          "make sure the default datastore from the cloud config exists and its name is shorter than 63 characters",
+	"CheckStorageClasses":            "make sure datastores and storage policies used by StorageClasses exist",
+	"CheckPVs":                       "make sure datastores of vSphere PersistentVolumes exist",
+	"CheckZoneTags":                  "make sure all vSphere failure domains have region and zone tags attached",
+	"CheckNodeDiskUUID":              "set disk.EnableUUID=TRUE in advanced configuration of the node VMs",
+	"CheckNodeProviderID":            "make sure the node has spec.providerID set by the vSphere cloud provider",
+	"CheckComputeClusterPermissions": "grant the vCenter account permissions on the compute cluster of the node",
+	"CheckResourcePoolPermissions":   "grant the vCenter account permissions on the resource pool of the node",
+}
+
+// checkRemediation returns a hint how to fix a failing detector check.
+func checkRemediation(check string) string {
+	if hint, found := checkRemediations[check]; found {
+		return hint
+	}
+	return fmt.Sprintf("see events with reason VSphere%s in namespace %s", check, csoclients.OperatorNamespace)
+}
+
+// GetProblemDetectorConfig returns configuration of vsphere-problem-detector.
+func GetProblemDetectorConfig() problemdetector.ProblemDetectorConfig {
+	pairs := []string{
+		"${OPERATOR_IMAGE}", os.Getenv(vSphereProblemDetectorOperatorImage),
+	}
+
+	return problemdetector.ProblemDetectorConfig{
+		ConditionPrefix: "VSphereProblemDetector",
+		Platform:        configv1.VSpherePlatformType,
+		StaticAssets: []string{
+			"vsphere_problem_detector/01_sa.yaml",
+			"vsphere_problem_detector/02_role.yaml",
+			"vsphere_problem_detector/03_rolebinding.yaml",
+			"vsphere_problem_detector/04_clusterrole.yaml",
+			"vsphere_problem_detector/05_clusterrolebinding.yaml",
+			"vsphere_problem_detector/10_service.yaml",
+		},
+		SharedStaticAssets: []string{
+			// trusted-ca-bundle is used by vmware-vsphere-csi-driver-operator too
+			"vsphere_problem_detector/06_configmap.yaml",
+		},
+		DeploymentAsset: "vsphere_problem_detector/07_deployment.yaml",
+		ImageReplacer:   strings.NewReplacer(pairs...),
+		SecretNames: []string{
+			// Restart when credentials change to get a quick retest
+			cloudCredSecretName,
+			// Restart when serving-cert changes
+			metricsCertSecretName,
+		},
+		ExtraDeploymentHooks: func(clients *csoclients.Clients) []deploymentcontroller.DeploymentHookFunc {
+			return []deploymentcontroller.DeploymentHookFunc{
+				// Restart when cloud config changes to get a quick retest
+				withConfigMapHashAnnotationHook(
+					cloudConfigNamespace,
+					clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+					clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps(),
+				),
+			}
+		},
+		ServiceMonitorAsset: "vsphere_problem_detector/11_service_monitor.yaml",
+		PrometheusRule: prometheusrules.RuleAsset{
+			File: "vsphere_problem_detector/12_prometheusrules.yaml",
+		},
+		ClusterCSIDriverName: csioperatorclient.VMwareVSphereDriverName,
+		ParseAlertsConfig:    parseAlertsConfig,
+		CheckResults: &problemdetector.CheckResultsConfig{
+			MetricsURL:         metricsURL,
+			ClusterCheckMetric: "vsphere_cluster_check_errors",
+			NodeCheckMetric:    "vsphere_node_check_errors",
+			Remediation:        checkRemediation,
+		},
+	}
+}
+
+func withConfigMapHashAnnotationHook(namespace string, infraLister openshiftv1.InfrastructureLister, cmInformer coreinformers.ConfigMapInformer) deploymentcontroller.DeploymentHookFunc {
+	return func(opSpec *operatorapi.OperatorSpec, deployment *appsv1.Deployment) error {
+		// Find cloud-config ConfigMap name from Infrastructure
+		infra, err := infraLister.Get(infraConfigName)
+		if err != nil {
+			return err
+		}
+		cloudConfigName := infra.Spec.CloudConfig.Name
+
+		// Compute ConfigMap hash
+		inputHashes, err := resourcehash.MultipleObjectHashStringMapForObjectReferenceFromLister(
+			cmInformer.Lister(),
+			nil,
+			resourcehash.NewObjectRef().ForConfigMap().InNamespace(namespace).Named(cloudConfigName),
+		)
+		if err != nil {
+			return fmt.Errorf("invalid dependency reference: %w", err)
+		}
+
+		// Add the hash to Deployment annotations
+		return problemdetector.AddObjectHash(deployment, inputHashes)
+	}
+}